@@ -0,0 +1,172 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	alertRulesName          = "genjobs-alerts"
+	defaultAlertStaleWindow = "24h"
+	// alertFailureWindow is the window failed job state transitions are counted over.
+	alertFailureWindow = "1h"
+)
+
+// prometheusRule is the PrometheusRule manifest of the Prometheus operator.
+type prometheusRule struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   map[string]string  `json:"metadata"`
+	Spec       prometheusRuleSpec `json:"spec"`
+}
+
+// prometheusRuleSpec is the specification of the PrometheusRule manifest.
+type prometheusRuleSpec struct {
+	Groups []ruleGroup `json:"groups"`
+}
+
+// ruleGroup is a group of Prometheus rules.
+type ruleGroup struct {
+	Name  string      `json:"name"`
+	Rules []alertRule `json:"rules"`
+}
+
+// alertRule is a Prometheus alerting rule.
+type alertRule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// nameMatcher returns a regular expression matching exactly the names.
+func nameMatcher(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	sort.Strings(quoted)
+
+	// PromQL string literals require escaping backslashes.
+	return strings.Replace(strings.Join(quoted, "|"), `\`, `\\`, -1)
+}
+
+// buildAlertRules builds the alerts on failed postsubmits and periodics and on stale periodics.
+// The alerts are keyed off the private job names genjobs sets, as exported by Prow in the prowjob_state_transitions metric.
+func buildAlertRules(o options, postsubmits map[string][]config.Postsubmit, periodics []config.Periodic) prometheusRule {
+	var watched, periodicNames []string
+
+	for _, post := range postsubmits {
+		for _, job := range post {
+			watched = append(watched, job.Name)
+		}
+	}
+	for _, job := range periodics {
+		watched = append(watched, job.Name)
+		periodicNames = append(periodicNames, job.Name)
+	}
+
+	var labels map[string]string
+	if len(o.AlertLabels) > 0 {
+		labels = o.AlertLabels
+	}
+
+	var rules []alertRule
+
+	if len(watched) > 0 {
+		rules = append(rules, alertRule{
+			Alert: "GenjobsJobFailed",
+			Expr: fmt.Sprintf(`sum by (job_name, type, org, repo) (increase(prowjob_state_transitions{type=~"postsubmit|periodic",job_name=~"%s",state=~"failure|error"}[%s])) > 0`,
+				nameMatcher(watched), alertFailureWindow),
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary": "Prow job {{ $labels.job_name }} failed.",
+			},
+		})
+	}
+
+	if len(periodicNames) > 0 {
+		stale, err := time.ParseDuration(o.AlertStaleWindow)
+		if err != nil {
+			stale, _ = time.ParseDuration(defaultAlertStaleWindow)
+		}
+
+		window := promDuration(stale)
+		rules = append(rules, alertRule{
+			Alert: "GenjobsPeriodicStale",
+			Expr: fmt.Sprintf(`sum by (job_name) (increase(prowjob_state_transitions{type="periodic",job_name=~"%s",state="success"}[%s])) == 0`,
+				nameMatcher(periodicNames), window),
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary": fmt.Sprintf("Prow periodic {{ $labels.job_name }} has not succeeded in %s.", window),
+			},
+		})
+	}
+
+	return prometheusRule{
+		APIVersion: "monitoring.coreos.com/v1",
+		Kind:       "PrometheusRule",
+		Metadata:   map[string]string{"name": alertRulesName},
+		Spec:       prometheusRuleSpec{Groups: []ruleGroup{{Name: alertRulesName, Rules: rules}}},
+	}
+}
+
+// promDuration formats a duration as a Prometheus duration (e.g. 1d, 12h, 90m).
+func promDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+// writeAlertRules writes the PrometheusRule manifest alerting on the generated postsubmits and periodics.
+func writeAlertRules(o options, postsubmits map[string][]config.Postsubmit, periodics []config.Periodic) {
+	if len(postsubmits) == 0 && len(periodics) == 0 {
+		return
+	}
+
+	if o.Verbose {
+		fmt.Printf("write alert rules for %d periodics to path %v\n", len(periodics), o.AlertRules)
+	}
+
+	if o.DryRun {
+		return
+	}
+
+	b, err := yaml.Marshal(buildAlertRules(o, postsubmits, periodics))
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to marshal alert rules: %v.", err))
+		return
+	}
+
+	writeConfigFile(o, o.AlertRules, append([]byte(outHeader(o, nil)), b...))
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+const (
+	bootstrapScenarioArg = "--scenario="
+	bootstrapArgSep      = "--"
+	bootstrapScenarioDir = "/workspace/scenarios"
+	executeScenario      = "execute"
+	defaultBaseRef       = "master"
+)
+
+// bootstrapArgs are the parsed arguments of a legacy bootstrap job.
+type bootstrapArgs struct {
+	repos    []string
+	timeout  time.Duration
+	scenario string
+	args     []string
+}
+
+// isBootstrapJob returns whether the job is a legacy undecorated bootstrap job running a scenario.
+func isBootstrapJob(job *config.JobBase, utility *config.UtilityConfig) bool {
+	if utility.Decorate != nil && *utility.Decorate {
+		return false
+	}
+
+	if job.Spec == nil || len(job.Spec.Containers) == 0 {
+		return false
+	}
+
+	for _, arg := range job.Spec.Containers[0].Args {
+		if arg == bootstrapArgSep {
+			break
+		}
+		if strings.HasPrefix(arg, bootstrapScenarioArg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseBootstrapArgs parses the arguments of a bootstrap container, ignoring those made obsolete by the pod utilities.
+func parseBootstrapArgs(args []string) bootstrapArgs {
+	var b bootstrapArgs
+
+	for i, arg := range args {
+		if arg == bootstrapArgSep {
+			b.args = args[i+1:]
+			break
+		}
+
+		name, value := arg, ""
+		if j := strings.Index(arg, "="); j >= 0 {
+			name, value = arg[:j], arg[j+1:]
+		}
+
+		switch name {
+		case "--repo":
+			b.repos = append(b.repos, value)
+		case "--timeout":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				b.timeout = time.Duration(minutes) * time.Minute
+			}
+		case "--scenario":
+			b.scenario = value
+		}
+	}
+
+	return b
+}
+
+// parseBootstrapRepo parses a bootstrap repository in the form [github.com/]org/repo[=ref].
+func parseBootstrapRepo(s string) (prowjob.Refs, bool) {
+	repo, ref := s, ""
+	if i := strings.Index(s, "="); i >= 0 {
+		repo, ref = s[:i], s[i+1:]
+	}
+
+	repo = strings.TrimPrefix(repo, gitHost+"/")
+	if strings.Count(repo, "/") != 1 || strings.HasPrefix(repo, "/") || strings.HasSuffix(repo, "/") {
+		return prowjob.Refs{}, false
+	}
+
+	// Refs of pull requests (e.g. master:abc123,1:def456) or variables resolve to the base branch.
+	if ref == "" || strings.Contains(ref, "$(") {
+		ref = defaultBaseRef
+	}
+	if i := strings.IndexAny(ref, ":,"); i >= 0 {
+		ref = ref[:i]
+	}
+
+	org, name := path.Split(repo)
+
+	return prowjob.Refs{Org: strings.TrimSuffix(org, "/"), Repo: name, BaseRef: ref}, true
+}
+
+// migrateBootstrap converts a legacy bootstrap job to a decorated pod-utilities job based on provided inputs.
+// The repositories other than the one of the job are converted to extra refs, the timeout to a decoration timeout
+// and the scenario to the container command.
+func migrateBootstrap(o options, job *config.JobBase, utility *config.UtilityConfig, orgrepo string) {
+	if !o.MigrateBootstrap || !isBootstrapJob(job, utility) {
+		return
+	}
+
+	container := &job.Spec.Containers[0]
+	b := parseBootstrapArgs(container.Args)
+
+	for _, r := range b.repos {
+		ref, ok := parseBootstrapRepo(r)
+		if !ok {
+			if o.Verbose {
+				fmt.Printf("skip bootstrap repository %v of job %v: unable to resolve org/repo\n", r, job.Name)
+			}
+			continue
+		}
+
+		if orgrepo != "" && (ref.Org+"/"+ref.Repo == orgrepo || convertOrgRepoStr(o, ref.Org+"/"+ref.Repo) == orgrepo) {
+			continue
+		}
+
+		utility.ExtraRefs = append(utility.ExtraRefs, ref)
+	}
+
+	decorate := true
+	utility.Decorate = &decorate
+
+	if b.timeout > 0 {
+		if utility.DecorationConfig == nil {
+			utility.DecorationConfig = &prowjob.DecorationConfig{}
+		}
+		utility.DecorationConfig.Timeout = &prowjob.Duration{Duration: b.timeout}
+	}
+
+	if b.scenario == executeScenario {
+		if len(b.args) > 0 {
+			container.Command = b.args[:1]
+			container.Args = b.args[1:]
+		} else {
+			container.Command = nil
+			container.Args = nil
+		}
+	} else {
+		container.Command = []string{path.Join(bootstrapScenarioDir, b.scenario+".py")}
+		container.Args = b.args
+	}
+
+	if o.Verbose {
+		fmt.Printf("migrate bootstrap job %v to pod utilities\n", job.Name)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// branchProtectionFragment is the Prow config fragment containing the generated branch protection configuration.
+type branchProtectionFragment struct {
+	BranchProtection config.BranchProtection `json:"branch-protection"`
+}
+
+// buildBranchProtection builds the branch protection of the org/repos with presubmits, requiring
+// the contexts of their non-optional presubmits.
+func buildBranchProtection(presubmits map[string][]config.Presubmit) config.BranchProtection {
+	bp := config.BranchProtection{Orgs: map[string]config.Org{}}
+
+	protect := true
+	for orgrepo, policy := range contextPolicies(presubmits) {
+		org, repo := util.SplitOrgRepo(orgrepo)
+
+		orgPolicy, exists := bp.Orgs[org]
+		if !exists {
+			orgPolicy = config.Org{Repos: map[string]config.Repo{}}
+		}
+
+		repoPolicy := config.Repo{Policy: config.Policy{Protect: &protect}}
+		if len(policy.RequiredContexts) > 0 {
+			repoPolicy.RequiredStatusChecks = &config.ContextPolicy{Contexts: policy.RequiredContexts}
+		}
+
+		if len(policy.Branches) > 0 {
+			repoPolicy.Branches = map[string]config.Branch{}
+			for branch, p := range policy.Branches {
+				repoPolicy.Branches[branch] = config.Branch{Policy: config.Policy{
+					Protect:              &protect,
+					RequiredStatusChecks: &config.ContextPolicy{Contexts: p.RequiredContexts},
+				}}
+			}
+		}
+
+		orgPolicy.Repos[repo] = repoPolicy
+		bp.Orgs[org] = orgPolicy
+	}
+
+	return bp
+}
+
+// writeBranchProtectionConfig writes the branch protection configuration fragment for the org/repos with presubmits.
+func writeBranchProtectionConfig(o options, presubmits map[string][]config.Presubmit) {
+	if len(presubmits) == 0 {
+		return
+	}
+
+	if o.Verbose {
+		fmt.Printf("write branch protection configuration for %d repositories to path %v\n", len(presubmits), o.BranchProtectionConfig)
+	}
+
+	if o.DryRun {
+		return
+	}
+
+	b, err := yaml.Marshal(branchProtectionFragment{BranchProtection: buildBranchProtection(presubmits)})
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to marshal branch protection configuration: %v.", err))
+		return
+	}
+
+	writeConfigFile(o, o.BranchProtectionConfig, append([]byte(outHeader(o, nil)), b...))
+}
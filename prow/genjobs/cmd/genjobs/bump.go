@@ -0,0 +1,332 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+
+	"istio.io/test-infra/prow/genjobs/pkg/git"
+	"istio.io/test-infra/prow/genjobs/pkg/github"
+	"istio.io/test-infra/prow/genjobs/pkg/registry"
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	bumpCommand     = "bump"
+	bumpCommitTitle = "Bump private image(s)"
+	// imageRe matches the image field of a container in a job config.
+	imageRe = `(?m)^(\s*(?:-\s+)?image:\s*["']?)([^\s"'#]+)`
+)
+
+// bumpOptions are the command-line flags for the bump subcommand.
+type bumpOptions struct {
+	Images            []string
+	TagFilter         string
+	PinDigest         bool
+	RegistryTokenPath string
+	PRRepo            string
+	BaseBranch        string
+	PushBranch        string
+	GitHubTokenPath   string
+}
+
+func init() {
+	commands[bumpCommand] = command{
+		flags:      addBumpFlags,
+		run:        runBump,
+		standalone: true,
+	}
+}
+
+// addBumpFlags registers the command-line flags for the bump subcommand.
+func addBumpFlags(o *options) {
+	flag.StringSliceVar(&o.bump.Images, "images", []string{}, "Image name prefix(es) to bump (e.g. gcr.io/istio-private-testing/).")
+	flag.StringVar(&o.bump.TagFilter, "tag-filter", "", "Regex of the tag(s) to bump to (default tags sharing the non-numeric prefix of the current tag).")
+	flag.BoolVar(&o.bump.PinDigest, "pin-digest", false, "Pin bumped image(s) to the digest of the new tag.")
+	flag.StringVar(&o.bump.RegistryTokenPath, "registry-token-path", "", "Path to file containing an access token for the image registry.")
+	flag.StringVar(&o.bump.PRRepo, "pr-repo", "", "Private org/repo to open a pull request against with the bumped image(s).")
+	flag.StringVar(&o.bump.BaseBranch, "base-branch", "master", "Base branch of the pull request.")
+	flag.StringVar(&o.bump.PushBranch, "push-branch", "genjobs-bump", "Branch to push the bumped image(s) to.")
+	flag.StringVar(&o.bump.GitHubTokenPath, "github-token-path", "", "Path to file containing a GitHub token used to push and open pull requests.")
+}
+
+// validateBumpOpts validates the command-line flags for the bump subcommand.
+func validateBumpOpts(b bumpOptions) error {
+	if len(b.Images) == 0 {
+		return &util.ExitError{Message: "--images option is required.", Code: 1}
+	}
+	if b.PRRepo != "" && strings.Count(b.PRRepo, "/") != 1 {
+		return &util.ExitError{Message: fmt.Sprintf("--pr-repo option must be an org/repo: %v.", b.PRRepo), Code: 1}
+	}
+	if b.PRRepo != "" && b.GitHubTokenPath == "" {
+		return &util.ExitError{Message: "--github-token-path option is required with --pr-repo.", Code: 1}
+	}
+	return nil
+}
+
+// imageBumper resolves the newest image for a reference, caching registry lookups.
+type imageBumper struct {
+	opts   bumpOptions
+	client *registry.Client
+	tags   map[string][]string
+	bumps  map[string]string
+}
+
+// bump returns the bumped image for an image reference, or an empty string if it is up to date.
+func (b *imageBumper) bump(image string) (string, error) {
+	if bumped, ok := b.bumps[image]; ok {
+		return bumped, nil
+	}
+
+	bumped, err := b.resolve(image)
+	if err != nil {
+		return "", err
+	}
+
+	b.bumps[image] = bumped
+
+	return bumped, nil
+}
+
+// resolve looks up the newest tag of an image reference in the registry.
+// Tags are compared lexically, which orders date-stamped tags (e.g. master-2019-11-14T12-01-13) chronologically.
+func (b *imageBumper) resolve(image string) (string, error) {
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Tag == "" {
+		return "", nil
+	}
+
+	filter := b.opts.TagFilter
+	if filter == "" {
+		filter = `^` + util.MustCompile(`^\D*`).FindString(ref.Tag)
+	}
+
+	tags, ok := b.tags[ref.Name()]
+	if !ok {
+		if tags, err = b.client.Tags(ref); err != nil {
+			return "", err
+		}
+		b.tags[ref.Name()] = tags
+	}
+
+	newest := ref.Tag
+	for _, tag := range tags {
+		if tag > newest && util.MustCompile(filter).MatchString(tag) {
+			newest = tag
+		}
+	}
+
+	if newest == ref.Tag && (!b.opts.PinDigest || ref.Digest != "") {
+		return "", nil
+	}
+
+	// The original name is kept verbatim so that implicit registries are preserved.
+	name := image[:strings.LastIndex(strings.SplitN(image, "@", 2)[0], ":")]
+	bumped := name + ":" + newest
+
+	if b.opts.PinDigest {
+		digest, err := b.client.Digest(ref, newest)
+		if err != nil {
+			return "", err
+		}
+		bumped += "@" + digest
+	}
+
+	if bumped == image {
+		return "", nil
+	}
+
+	return bumped, nil
+}
+
+// matches checks if an image reference has one of the image prefixes to bump.
+func (b *imageBumper) matches(image string) bool {
+	for _, prefix := range b.opts.Images {
+		if strings.HasPrefix(image, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bumpFile rewrites the images of a job config file, returning the applied bumps keyed by old image.
+func (b *imageBumper) bumpFile(p string, dryRun bool) (map[string]string, error) {
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[string]string{}
+	var bumpErr error
+
+	out := util.MustCompile(imageRe).ReplaceAllStringFunc(string(d), func(line string) string {
+		m := util.MustCompile(imageRe).FindStringSubmatch(line)
+		image := m[2]
+
+		if bumpErr != nil || !b.matches(image) {
+			return line
+		}
+
+		bumped, err := b.bump(image)
+		if err != nil {
+			bumpErr = err
+			return line
+		}
+		if bumped == "" {
+			return line
+		}
+
+		applied[image] = bumped
+
+		return m[1] + bumped
+	})
+
+	if bumpErr != nil {
+		return nil, bumpErr
+	}
+
+	if len(applied) > 0 && !dryRun {
+		if err := ioutil.WriteFile(p, []byte(out), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return applied, nil
+}
+
+// runBump bumps the private images of the generated job config(s) to their newest tags.
+func runBump(o options) error {
+	if err := validateBumpOpts(o.bump); err != nil {
+		return err
+	}
+
+	client, err := registry.NewClient(o.bump.RegistryTokenPath)
+	if err != nil {
+		return err
+	}
+
+	b := &imageBumper{
+		opts:   o.bump,
+		client: client,
+		tags:   map[string][]string{},
+		bumps:  map[string]string{},
+	}
+
+	var paths []string
+	if err := filepath.Walk(o.Output, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && util.HasExtension(p, yamlExt) {
+			paths = append(paths, p)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	bumped := map[string]string{}
+	files := 0
+
+	for _, p := range paths {
+		applied, err := b.bumpFile(p, o.DryRun)
+		if err != nil {
+			return fmt.Errorf("unable to bump images in %v: %v", p, err)
+		}
+		if len(applied) == 0 {
+			continue
+		}
+
+		files++
+		for old, image := range applied {
+			bumped[old] = image
+		}
+		if o.Verbose {
+			fmt.Printf("bump %d image(s) in path %v\n", len(applied), p)
+		}
+	}
+
+	if len(bumped) == 0 {
+		fmt.Println("no image(s) to bump")
+		return nil
+	}
+
+	olds := make([]string, 0, len(bumped))
+	for old := range bumped {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+
+	var summary strings.Builder
+	for _, old := range olds {
+		fmt.Fprintf(&summary, "- %s -> %s\n", old, bumped[old])
+	}
+
+	fmt.Printf("bumped %d image(s) in %d file(s):\n%s", len(bumped), files, summary.String())
+
+	if o.DryRun || o.bump.PRRepo == "" {
+		return nil
+	}
+
+	return openBumpPullRequest(o, summary.String())
+}
+
+// openBumpPullRequest commits the bumped image(s) and opens a pull request.
+func openBumpPullRequest(o options, summary string) error {
+	dir := o.Output
+	if util.IsFile(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	repo, err := git.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%s\n\n%s", bumpCommitTitle, summary)
+	if err := repo.CommitAll(o.bump.PushBranch, gitOpsAuthor, gitOpsEmail, message); err != nil {
+		return err
+	}
+	if err := repo.ForcePush(o.bump.PushBranch); err != nil {
+		return err
+	}
+
+	org, name := util.SplitOrgRepo(o.bump.PRRepo)
+	client, err := github.NewClient(o.bump.GitHubTokenPath, "", org, name)
+	if err != nil {
+		return err
+	}
+
+	num, err := client.EnsurePullRequest(o.bump.PushBranch, o.bump.BaseBranch, bumpCommitTitle, message)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("opened pull request %v/pull/%d\n", o.bump.PRRepo, num)
+
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/test-infra/prow/config"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	cacheVolumeName       = "genjobs-cache"
+	defaultCacheMountPath = "/cache"
+
+	remoteCacheVolumeName  = "genjobs-remote-cache"
+	remoteCacheMountPath   = "/etc/genjobs-remote-cache"
+	remoteCacheEndpointEnv = "REMOTE_CACHE_ENDPOINT"
+	remoteCacheCredsEnv    = "REMOTE_CACHE_CREDENTIALS"
+	defaultRemoteCacheKey  = "credentials.json"
+)
+
+// defaultRemoteCacheLabels are the job labels indicating bazel or go builds.
+var defaultRemoteCacheLabels = []string{"preset-bazel-build", "preset-go-build"}
+
+// parseCacheVolume parses a cache volume in the form emptyDir[:sizeLimit] or pvc:claimName.
+func parseCacheVolume(s string) (v1.VolumeSource, error) {
+	kind, arg := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		kind, arg = s[:i], s[i+1:]
+	}
+
+	switch kind {
+	case "emptyDir":
+		source := &v1.EmptyDirVolumeSource{}
+		if arg != "" {
+			size, err := resource.ParseQuantity(arg)
+			if err != nil {
+				return v1.VolumeSource{}, err
+			}
+			source.SizeLimit = &size
+		}
+		return v1.VolumeSource{EmptyDir: source}, nil
+	case "pvc":
+		if arg == "" {
+			return v1.VolumeSource{}, fmt.Errorf("missing claim name")
+		}
+		return v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: arg}}, nil
+	}
+
+	return v1.VolumeSource{}, fmt.Errorf("unknown volume type %q", kind)
+}
+
+// parseRemoteCacheSecret parses a remote cache secret in the form name[:key].
+func parseRemoteCacheSecret(s string) (string, string) {
+	if i := strings.Index(s, ":"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+
+	return s, defaultRemoteCacheKey
+}
+
+// matchesAny returns whether the name matches any of the patterns, or true if there are none.
+func matchesAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if util.MustCompile(pattern).MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateCacheVolume injects the cache volume, mount and env into the jobs Spec based on provided inputs.
+func updateCacheVolume(o options, job *config.JobBase) {
+	if o.CacheVolume == "" || job.Spec == nil || !matchesAny(o.CacheJobs, job.Name) {
+		return
+	}
+
+	source, err := parseCacheVolume(o.CacheVolume)
+	if err != nil {
+		return
+	}
+
+	mountPath := o.CacheMountPath
+	if mountPath == "" {
+		mountPath = defaultCacheMountPath
+	}
+
+	hasVolume := false
+	for _, volume := range job.Spec.Volumes {
+		if volume.Name == cacheVolumeName {
+			hasVolume = true
+			break
+		}
+	}
+	if !hasVolume {
+		job.Spec.Volumes = append(job.Spec.Volumes, v1.Volume{Name: cacheVolumeName, VolumeSource: source})
+	}
+
+	for i := range job.Spec.Containers {
+		container := &job.Spec.Containers[i]
+
+		hasMount := false
+		for _, mount := range container.VolumeMounts {
+			if mount.Name == cacheVolumeName {
+				hasMount = true
+				break
+			}
+		}
+		if !hasMount {
+			container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: cacheVolumeName, MountPath: mountPath})
+		}
+
+		for _, envK := range util.SortedKeys(o.CacheEnv) {
+			setEnv(container, envK, path.Join(mountPath, o.CacheEnv[envK]))
+		}
+	}
+}
+
+// updateRemoteCache injects the remote cache endpoint and credentials into the jobs Spec based on provided inputs.
+func updateRemoteCache(o options, job *config.JobBase) {
+	if o.RemoteCache == "" || job.Spec == nil {
+		return
+	}
+
+	matched := false
+	for _, label := range o.RemoteCacheLabels {
+		if job.Labels[label] == "true" {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return
+	}
+
+	var name, key string
+	if o.RemoteCacheSecret != "" {
+		name, key = parseRemoteCacheSecret(o.RemoteCacheSecret)
+
+		hasVolume := false
+		for _, volume := range job.Spec.Volumes {
+			if volume.Name == remoteCacheVolumeName {
+				hasVolume = true
+				break
+			}
+		}
+		if !hasVolume {
+			job.Spec.Volumes = append(job.Spec.Volumes, v1.Volume{
+				Name:         remoteCacheVolumeName,
+				VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: name}},
+			})
+		}
+	}
+
+	for i := range job.Spec.Containers {
+		container := &job.Spec.Containers[i]
+
+		setEnv(container, remoteCacheEndpointEnv, o.RemoteCache)
+
+		if name == "" {
+			continue
+		}
+
+		hasMount := false
+		for _, mount := range container.VolumeMounts {
+			if mount.Name == remoteCacheVolumeName {
+				hasMount = true
+				break
+			}
+		}
+		if !hasMount {
+			container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: remoteCacheVolumeName, MountPath: remoteCacheMountPath, ReadOnly: true})
+		}
+
+		setEnv(container, remoteCacheCredsEnv, path.Join(remoteCacheMountPath, key))
+	}
+}
+
+// setEnv sets the value of the env in the container, adding it if missing.
+func setEnv(container *v1.Container, name, value string) {
+	for i := range container.Env {
+		if container.Env[i].Name == name {
+			container.Env[i].Value = value
+			return
+		}
+	}
+
+	container.Env = append(container.Env, v1.EnvVar{Name: name, Value: value})
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// configFlag is the flag of the options config file, which cannot be set from the file itself.
+const configFlag = "config"
+
+// applyConfigFile sets the flags not set on the command line from a yaml file of options keyed by flag name.
+func applyConfigFile(o *options, path string) error {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("--config option unable to read file %v: %v.", path, err), Code: 1}
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(d, &values); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("--config option unable to parse file %v: %v.", path, err), Code: 1}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// Extra refs have no flag equivalent.
+		if key == "extra-refs" {
+			var refs struct {
+				ExtraRefs []prowjob.Refs `json:"extra-refs"`
+			}
+			if err := yaml.Unmarshal(d, &refs); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("--config option invalid value for extra-refs: %v.", err), Code: 1}
+			}
+			o.ExtraRefs = refs.ExtraRefs
+			continue
+		}
+
+		f := flag.Lookup(key)
+		if f == nil || key == configFlag {
+			return &util.ExitError{Message: fmt.Sprintf("--config option unknown option: %v.", key), Code: 1}
+		}
+
+		if f.Changed {
+			continue
+		}
+
+		value, err := flagValue(values[key])
+		if err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--config option invalid value for %v: %v.", key, err), Code: 1}
+		}
+
+		if err := flag.Set(key, value); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--config option invalid value for %v: %v.", key, err), Code: 1}
+		}
+	}
+
+	return nil
+}
+
+// flagValue returns the command-line representation of a config file value.
+// Lists are joined and maps are joined as key=value pairs, both as comma-separated values.
+func flagValue(v interface{}) (string, error) {
+	var fields []string
+
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			s, err := scalarValue(item)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, s)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s, err := scalarValue(t[key])
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, key+"="+s)
+		}
+	default:
+		return scalarValue(v)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(fields); err != nil {
+		return "", err
+	}
+	w.Flush()
+
+	return strings.TrimSuffix(buf.String(), "\n"), w.Error()
+}
+
+// scalarValue returns the command-line representation of a scalar config file value.
+func scalarValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	}
+
+	return "", fmt.Errorf("unsupported value %v", v)
+}
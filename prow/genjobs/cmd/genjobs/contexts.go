@@ -0,0 +1,105 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const jsonExt = ".json$"
+
+// requiredContexts are the required status contexts of an org/repo.
+type requiredContexts struct {
+	Org  string `json:"org"`
+	Repo string `json:"repo"`
+	// Contexts are required on all branches.
+	Contexts []string `json:"contexts"`
+	// Branches are the contexts required on each branch, including the contexts required on all branches.
+	Branches map[string][]string `json:"branches,omitempty"`
+}
+
+// contextsExport is the machine-readable export of the required status contexts.
+type contextsExport struct {
+	Repos []requiredContexts `json:"repos"`
+}
+
+// buildContextsExport builds the required status contexts of the org/repos from their non-optional presubmits.
+func buildContextsExport(presubmits map[string][]config.Presubmit) contextsExport {
+	policies := contextPolicies(presubmits)
+
+	export := contextsExport{Repos: []requiredContexts{}}
+
+	for _, orgrepo := range sortedOrgRepos(presubmits) {
+		policy := policies[orgrepo]
+		org, repo := util.SplitOrgRepo(orgrepo)
+
+		rc := requiredContexts{
+			Org:      org,
+			Repo:     repo,
+			Contexts: append([]string{}, policy.RequiredContexts...),
+		}
+
+		if len(policy.Branches) > 0 {
+			rc.Branches = map[string][]string{}
+			for branch, p := range policy.Branches {
+				rc.Branches[branch] = sets.NewString(p.RequiredContexts...).Insert(policy.RequiredContexts...).List()
+			}
+		}
+
+		export.Repos = append(export.Repos, rc)
+	}
+
+	return export
+}
+
+// writeContextsExport writes the required status contexts of the org/repos with presubmits as JSON or YAML.
+func writeContextsExport(o options, presubmits map[string][]config.Presubmit) {
+	if o.Verbose {
+		fmt.Printf("write required contexts for %d repositories to path %v\n", len(presubmits), o.ContextsOutput)
+	}
+
+	if o.DryRun {
+		return
+	}
+
+	export := buildContextsExport(presubmits)
+
+	var (
+		b   []byte
+		err error
+	)
+	if util.HasExtension(o.ContextsOutput, jsonExt) {
+		b, err = json.MarshalIndent(export, "", "  ")
+		b = append(b, '\n')
+	} else {
+		b, err = yaml.Marshal(export)
+		b = append([]byte(outHeader(o, nil)), b...)
+	}
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to marshal required contexts: %v.", err))
+		return
+	}
+
+	writeConfigFile(o, o.ContextsOutput, b)
+}
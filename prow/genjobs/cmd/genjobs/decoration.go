@@ -0,0 +1,486 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// updateUtilityConfig updates the jobs UtilityConfig fields based on provided inputs.
+func updateUtilityConfig(o options, job *config.UtilityConfig) {
+	renameSSHKeySecrets(o.SecretNames, job.DecorationConfig)
+
+	if o.ForceDecorate && (job.Decorate == nil || !*job.Decorate) {
+		decorate := true
+		job.Decorate = &decorate
+		if job.DecorationConfig == nil {
+			job.DecorationConfig = &prowjob.DecorationConfig{}
+		}
+	}
+
+	if o.SkipSubmodules {
+		job.SkipSubmodules = true
+	}
+
+	if o.CloneDepth > 0 {
+		job.CloneDepth = o.CloneDepth
+	}
+
+	if !hasDecorationOptions(o) {
+		return
+	}
+
+	if job.DecorationConfig == nil {
+		job.DecorationConfig = &prowjob.DecorationConfig{}
+	}
+
+	updateGCSConfiguration(o, job.DecorationConfig)
+	updateGCSCredentialsSecret(o, job.DecorationConfig)
+	updateSSHKeySecrets(o, job.DecorationConfig)
+	updateSSHHostFingerprints(o, job.DecorationConfig)
+	updateOauthTokenSecret(o, job.DecorationConfig)
+	updateGitHubApp(o, job.DecorationConfig)
+	updateDecorationResources(o, job.DecorationConfig)
+	updateUtilityImages(o, job.DecorationConfig)
+
+	if o.SkipCloning {
+		skipCloning := true
+		job.DecorationConfig.SkipCloning = &skipCloning
+	}
+}
+
+// hasDecorationOptions returns whether any of the provided inputs update the jobs DecorationConfig.
+func hasDecorationOptions(o options) bool {
+	return o.Bucket != "" ||
+		o.GCSCredentialsSecret != "" ||
+		o.DefaultServiceAccount != "" ||
+		o.SSHKeySecret != "" ||
+		len(o.SSHHostFingerprints) > 0 ||
+		o.OauthTokenSecret != "" ||
+		o.GitHubAppID != "" ||
+		o.SkipCloning ||
+		o.CensorSecrets ||
+		len(o.DecorationResources) > 0 ||
+		len(o.UtilityImages) > 0
+}
+
+// parseSecretKey parses a secret key of the form name:key.
+func parseSecretKey(s string) (string, string, bool) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// parseOauthTokenSecret parses an oauth token secret of the form name:key.
+func parseOauthTokenSecret(s string) (*prowjob.OauthTokenSecret, bool) {
+	name, key, ok := parseSecretKey(s)
+	if !ok {
+		return nil, false
+	}
+
+	return &prowjob.OauthTokenSecret{Name: name, Key: key}, true
+}
+
+// updateOauthTokenSecret updates the jobs OauthTokenSecret field based on provided inputs.
+// The ssh key secrets are dropped since the repositories are cloned over https with the token.
+func updateOauthTokenSecret(o options, job *prowjob.DecorationConfig) {
+	if o.OauthTokenSecret == "" {
+		return
+	}
+
+	secret, ok := parseOauthTokenSecret(o.OauthTokenSecret)
+	if !ok {
+		return
+	}
+
+	job.OauthTokenSecret = secret
+	job.SSHKeySecrets = nil
+}
+
+// updateGitHubApp drops the jobs ssh key secrets when cloning with a GitHub App.
+// The GitHub App fields themselves are unsupported by the vendored Prow API and recorded by updateDecorationExtras.
+func updateGitHubApp(o options, job *prowjob.DecorationConfig) {
+	if o.GitHubAppID == "" {
+		return
+	}
+
+	job.SSHKeySecrets = nil
+}
+
+// updateUtilityImages updates the jobs UtilityImages fields based on provided inputs.
+func updateUtilityImages(o options, job *prowjob.DecorationConfig) {
+	if len(o.UtilityImages) == 0 {
+		return
+	}
+
+	images := &prowjob.UtilityImages{}
+	if job.UtilityImages != nil {
+		*images = *job.UtilityImages
+	}
+
+	for utility, image := range o.UtilityImages {
+		switch utility {
+		case "clonerefs":
+			images.CloneRefs = image
+		case "initupload":
+			images.InitUpload = image
+		case "entrypoint":
+			images.Entrypoint = image
+		case "sidecar":
+			images.Sidecar = image
+		}
+	}
+
+	job.UtilityImages = images
+}
+
+// updateGCSConfiguration updates the jobs GCSConfiguration fields based on provided inputs.
+func updateGCSConfiguration(o options, job *prowjob.DecorationConfig) {
+	if o.Bucket == "" {
+		return
+	}
+
+	if job.GCSConfiguration == nil {
+		job.GCSConfiguration = &prowjob.GCSConfiguration{
+			Bucket: o.Bucket,
+		}
+	} else {
+		job.GCSConfiguration.Bucket = o.Bucket
+	}
+}
+
+// updateGCSCredentialsSecret updates the jobs GCSCredentialsSecret field based on provided inputs.
+func updateGCSCredentialsSecret(o options, job *prowjob.DecorationConfig) {
+	if o.GCSCredentialsSecret == "" {
+		return
+	}
+
+	job.GCSCredentialsSecret = o.GCSCredentialsSecret
+}
+
+// updateSSHKeySecrets updates the jobs SSHKeySecrets fields based on provided inputs.
+func updateSSHKeySecrets(o options, job *prowjob.DecorationConfig) {
+	if o.SSHKeySecret == "" {
+		return
+	}
+
+	if job.SSHKeySecrets == nil {
+		job.SSHKeySecrets = []string{o.SSHKeySecret}
+	} else {
+		job.SSHKeySecrets = append(job.SSHKeySecrets, o.SSHKeySecret)
+	}
+}
+
+// renameSSHKeySecrets renames the public ssh key secrets to their private equivalents.
+func renameSSHKeySecrets(secrets map[string]string, job *prowjob.DecorationConfig) {
+	if len(secrets) == 0 || job == nil || len(job.SSHKeySecrets) == 0 {
+		return
+	}
+
+	renamed := make([]string, 0, len(job.SSHKeySecrets))
+	for _, name := range job.SSHKeySecrets {
+		if private, ok := secrets[name]; ok {
+			name = private
+		}
+		renamed = append(renamed, name)
+	}
+
+	job.SSHKeySecrets = renamed
+}
+
+// updateSSHHostFingerprints updates the jobs SSHHostFingerprints fields based on provided inputs.
+func updateSSHHostFingerprints(o options, job *prowjob.DecorationConfig) {
+	if len(o.SSHHostFingerprints) == 0 {
+		return
+	}
+
+	fingerprints := sets.NewString(job.SSHHostFingerprints...)
+	for _, fingerprint := range o.SSHHostFingerprints {
+		if !fingerprints.Has(fingerprint) {
+			job.SSHHostFingerprints = append(job.SSHHostFingerprints, fingerprint)
+			fingerprints.Insert(fingerprint)
+		}
+	}
+}
+
+// parseDecorationResource parses a decoration resource key in the form container.(requests|limits).resource.
+func parseDecorationResource(key string) (container string, kind string, name string, ok bool) {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return "", "", "", false
+	}
+
+	switch parts[0] {
+	case "clonerefs", "initupload", "place_entrypoint", "sidecar":
+	default:
+		return "", "", "", false
+	}
+
+	switch parts[1] {
+	case "requests", "limits":
+	default:
+		return "", "", "", false
+	}
+
+	return parts[0], parts[1], parts[2], true
+}
+
+// updateDecorationResources updates the jobs decoration container Resources fields based on provided inputs.
+func updateDecorationResources(o options, job *prowjob.DecorationConfig) {
+	if len(o.DecorationResources) == 0 {
+		return
+	}
+
+	if job.Resources == nil {
+		job.Resources = &prowjob.Resources{}
+	}
+
+	for _, k := range util.SortedKeys(o.DecorationResources) {
+		container, kind, name, ok := parseDecorationResource(k)
+		if !ok {
+			continue
+		}
+
+		quantity, err := resource.ParseQuantity(o.DecorationResources[k])
+		if err != nil {
+			continue
+		}
+
+		var requirements **v1.ResourceRequirements
+		switch container {
+		case "clonerefs":
+			requirements = &job.Resources.CloneRefs
+		case "initupload":
+			requirements = &job.Resources.InitUpload
+		case "place_entrypoint":
+			requirements = &job.Resources.PlaceEntrypoint
+		case "sidecar":
+			requirements = &job.Resources.Sidecar
+		}
+
+		if *requirements == nil {
+			*requirements = &v1.ResourceRequirements{}
+		}
+
+		list := &(*requirements).Requests
+		if kind == "limits" {
+			list = &(*requirements).Limits
+		}
+
+		if *list == nil {
+			*list = v1.ResourceList{}
+		}
+
+		(*list)[v1.ResourceName(name)] = quantity
+	}
+}
+
+// updateExtraRefs updates the jobs ExtraRefs fields based on provided inputs to work with private repositories.
+func updateExtraRefs(o options, job *config.UtilityConfig) {
+	for i, ref := range job.ExtraRefs {
+		org, repo := ref.Org, ref.Repo
+
+		if o.Refs || validateOrgRepo(o, org, repo) {
+			host := mapGitHost(o, org, repo)
+
+			if o.Reverse {
+				job.ExtraRefs[i].CloneURI = ""
+			}
+
+			// Try to transform known ref org mappings first.
+			if newOrg, ok := o.RefOrgMap[org]; ok {
+				org = newOrg
+				job.ExtraRefs[i].CloneURI = fmt.Sprintf("https://%s/%s", org, repo)
+				// Then try to transform general org mappings.
+			} else if newOrg, newRepo, ok := mapOrgRepo(o, org, repo); ok {
+				org, repo = newOrg, newRepo
+				if host != gitHost {
+					job.ExtraRefs[i].CloneURI = fmt.Sprintf("https://%s/%s/%s.git", host, org, repo)
+				}
+			}
+			job.ExtraRefs[i].Org = org
+			job.ExtraRefs[i].Repo = repo
+			job.ExtraRefs[i].PathAlias = mapPathAlias(o, ref.PathAlias, host, org+"/"+repo)
+			if o.SSHClone {
+				job.ExtraRefs[i].CloneURI = fmt.Sprintf("git@%s:%s/%s.git", host, org, repo)
+			}
+			if baseRef, ok := mapBaseRef(o, org, repo); ok {
+				job.ExtraRefs[i].BaseRef = baseRef
+			} else if o.RefBranchOut != "" {
+				job.ExtraRefs[i].BaseRef = o.RefBranchOut
+			} else {
+				job.ExtraRefs[i].BaseRef = mapBranch(o, job.ExtraRefs[i].BaseRef)
+			}
+		}
+	}
+	if len(o.ExtraRefs) > 0 {
+		job.ExtraRefs = append(job.ExtraRefs, o.ExtraRefs...)
+	}
+}
+
+// mapBaseRef returns the base ref of a private org/repo or its org, if any.
+func mapBaseRef(o options, org string, repo string) (string, bool) {
+	if baseRef, ok := o.BaseRefMap[org+"/"+repo]; ok {
+		return baseRef, true
+	}
+	baseRef, ok := o.BaseRefMap[org]
+
+	return baseRef, ok
+}
+
+// updatePathAlias updates the jobs PathAlias fields based on provided inputs.
+func updatePathAlias(o options, job *config.UtilityConfig, orgrepo string, host string) {
+	job.PathAlias = mapPathAlias(o, job.PathAlias, host, orgrepo)
+}
+
+// mapPathAlias translates the path alias of a mapped repo. The longest matching --path-alias-map entry is
+// applied first, otherwise the path alias is handled according to --path-alias-mode.
+func mapPathAlias(o options, alias string, host string, orgrepo string) string {
+	if alias == "" {
+		return alias
+	}
+
+	match := ""
+	for from := range o.PathAliasMap {
+		if (alias == from || strings.HasPrefix(alias, from+"/")) && len(from) > len(match) {
+			match = from
+		}
+	}
+	if match != "" {
+		return o.PathAliasMap[match] + strings.TrimPrefix(alias, match)
+	}
+
+	switch o.PathAliasMode {
+	case pathAliasClear:
+		return ""
+	case pathAliasRepo:
+		if host == "" {
+			host = gitHost
+		}
+		return host + "/" + orgrepo
+	}
+
+	return alias
+}
+
+// decorationExtras are the decoration config fields of jobs keyed by job.
+// The vendored Prow API does not support them (e.g. github_app_id), so they are patched into the rendered job config.
+type decorationExtras map[string]map[string]interface{}
+
+// decorationExtraFields are the decoration config fields unsupported by the vendored Prow API.
+var decorationExtraFields = []string{"github_app_id", "github_app_private_key_secret", "default_service_account_name", "censor_secrets"}
+
+// decorationFields returns the decoration config fields unsupported by the vendored Prow API to set on the jobs based on provided inputs.
+func decorationFields(o options) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	if o.GitHubAppID != "" {
+		fields["github_app_id"] = o.GitHubAppID
+	}
+	if name, key, ok := parseSecretKey(o.GitHubAppPrivateKey); ok {
+		fields["github_app_private_key_secret"] = map[string]interface{}{"name": name, "key": key}
+	}
+	if o.DefaultServiceAccount != "" {
+		fields["default_service_account_name"] = o.DefaultServiceAccount
+	}
+	if o.CensorSecrets {
+		fields["censor_secrets"] = true
+	}
+
+	return fields
+}
+
+// updateDecorationExtras records the decoration config fields to set on a job based on provided inputs.
+func updateDecorationExtras(o options, extras decorationExtras, key string, job *prowjob.DecorationConfig) {
+	if job == nil {
+		return
+	}
+
+	if fields := decorationFields(o); len(fields) > 0 {
+		extras[key] = fields
+	}
+}
+
+// decorationConfig returns the raw decoration config of a job, if any.
+func decorationConfig(job map[string]interface{}) map[string]interface{} {
+	decoration, _ := job["decoration_config"].(map[string]interface{})
+	return decoration
+}
+
+// readDecorationExtras reads the decoration config fields unsupported by the vendored Prow API from a job config file.
+func readDecorationExtras(p string) decorationExtras {
+	extras := decorationExtras{}
+
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return extras
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(d, &raw); err != nil {
+		return extras
+	}
+
+	walkJobs(raw, func(key string, job map[string]interface{}) {
+		decoration := decorationConfig(job)
+		fields := map[string]interface{}{}
+		for _, k := range decorationExtraFields {
+			if v, ok := decoration[k]; ok {
+				fields[k] = v
+			}
+		}
+		if len(fields) > 0 {
+			extras[key] = fields
+		}
+	})
+
+	return extras
+}
+
+// patchDecorationExtras sets the decoration config fields of the jobs in a rendered job config.
+func patchDecorationExtras(b []byte, extras decorationExtras) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	walkJobs(raw, func(key string, job map[string]interface{}) {
+		decoration := decorationConfig(job)
+		if decoration == nil {
+			return
+		}
+		for k, v := range extras[key] {
+			decoration[k] = v
+		}
+	})
+
+	return yaml.Marshal(raw)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	flag "github.com/spf13/pflag"
+
+	"istio.io/test-infra/prow/genjobs/pkg/git"
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	driftCommand = "drift"
+	// driftExitCode is the exit code when stale generated file(s) are detected.
+	driftExitCode = 2
+)
+
+// driftOptions are the command-line flags for the drift subcommand.
+type driftOptions struct {
+	SourceRef string
+}
+
+func init() {
+	commands[driftCommand] = command{
+		flags: addDriftFlags,
+		run:   runDrift,
+	}
+}
+
+// addDriftFlags registers the command-line flags for the drift subcommand.
+func addDriftFlags(o *options) {
+	flag.StringVar(&o.drift.SourceRef, "source-ref", "HEAD", "Revision of the input source tree to compare the recorded provenance against.")
+}
+
+// runDrift reports the generated file(s) that are stale relative to the input source tree at a revision.
+// Staleness is derived from the source SHA recorded in each output file with --source-sha.
+func runDrift(o options) error {
+	o, cleanup, err := resolveInputs(o)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	dir := o.Input
+	if util.IsFile(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	repo, err := git.Open(dir)
+	if err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("-i, --input option is not in a git repository: %v.", o.Input), Code: 1}
+	}
+
+	paths := collectInputFiles(o)
+	inputs := map[string][]string{}
+
+	for i, res := range transformFiles(o, paths, combinePresets(o, o.Presets)) {
+		if res == nil {
+			continue
+		}
+		for outPath, jobs := range res.outputs(o) {
+			if !jobs.empty() {
+				inputs[outPath] = append(inputs[outPath], paths[i])
+			}
+		}
+	}
+
+	outPaths := make([]string, 0, len(inputs))
+	for outPath := range inputs {
+		outPaths = append(outPaths, outPath)
+	}
+	sort.Strings(outPaths)
+
+	stale := 0
+
+	for _, outPath := range outPaths {
+		p := outPath
+		if paths := splitPaths(outPath); !util.Exists(p) && len(paths) > 0 {
+			p = paths[0]
+		}
+
+		if !util.Exists(p) {
+			fmt.Printf("missing %v\n", outPath)
+			stale++
+			continue
+		}
+
+		sha, err := readProvenance(p)
+		if err != nil {
+			util.PrintErr(fmt.Sprintf("unable to read provenance of %v: %v.", p, err))
+			stale++
+			continue
+		}
+		if sha == "" {
+			fmt.Printf("unknown %v: no source SHA recorded\n", outPath)
+			stale++
+			continue
+		}
+
+		changed, err := repo.ChangedFiles(sha, o.drift.SourceRef, inputs[outPath]...)
+		if err != nil {
+			util.PrintErr(fmt.Sprintf("unable to compare %v against %v: %v.", outPath, o.drift.SourceRef, err))
+			stale++
+			continue
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		fmt.Printf("stale %v: %d input file(s) changed since %v\n", outPath, len(changed), sha)
+		if o.Verbose {
+			for _, c := range changed {
+				fmt.Printf("  %v\n", c)
+			}
+		}
+		stale++
+	}
+
+	if stale > 0 {
+		return &util.ExitError{Message: fmt.Sprintf("%d generated file(s) are stale relative to %v.", stale, o.drift.SourceRef), Code: driftExitCode}
+	}
+
+	fmt.Printf("no drift detected for %d generated file(s) at %v\n", len(outPaths), o.drift.SourceRef)
+
+	return nil
+}
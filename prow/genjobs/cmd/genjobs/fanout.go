@@ -0,0 +1,134 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// parseFanout parses a fan-out target of the form public-org:private-org.
+func parseFanout(s string) (org string, privateOrg string, ok bool) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// fanoutModifier returns the modifier of the fan-out target, defaulting to the target name.
+func fanoutModifier(o options, target string) string {
+	if modifier, ok := o.FanoutModifiers[target]; ok {
+		return modifier
+	}
+
+	return target
+}
+
+// validateFanout validates the fan-out targets of the options and makes their outputs absolute.
+func validateFanout(o *options) error {
+	if len(o.Fanout) > 0 && o.Reverse {
+		return &util.ExitError{Message: "--fanout option cannot be used with --reverse.", Code: 1}
+	}
+
+	modifiers := map[string]string{o.Modifier: ""}
+	outputs := make(map[string]string, len(o.FanoutOutputs))
+
+	for target, s := range o.Fanout {
+		org, privateOrg, ok := parseFanout(s)
+		if !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout option invalid: %v=%v.", target, s), Code: 1}
+		}
+		if _, ok := o.OrgMap[org]; !ok || isRepoMapping(org) || isRegexMapping(org) {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout option org is not mapped: %v.", org), Code: 1}
+		}
+		if _, to := splitGitHost(privateOrg); isRepoMapping(to) {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout option must map an org to an org: %v=%v.", target, s), Code: 1}
+		}
+
+		modifier := fanoutModifier(*o, target)
+		if other, ok := modifiers[modifier]; ok {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout-modifiers option must be distinct per target: %v=%v conflicts with %q.", target, modifier, other), Code: 1}
+		}
+		modifiers[modifier] = target
+
+		out, ok := o.FanoutOutputs[target]
+		if !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout-outputs option missing for target: %v.", target), Code: 1}
+		}
+		abs, err := filepath.Abs(out)
+		if err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout-outputs option invalid: %v.", out), Code: 1}
+		}
+		outputs[target] = abs
+	}
+
+	for target := range o.FanoutModifiers {
+		if _, ok := o.Fanout[target]; !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout-modifiers option target is not defined: %v.", target), Code: 1}
+		}
+	}
+
+	for target := range o.FanoutOutputs {
+		if _, ok := o.Fanout[target]; !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--fanout-outputs option target is not defined: %v.", target), Code: 1}
+		}
+	}
+
+	o.FanoutOutputs = outputs
+
+	return nil
+}
+
+// expandFanout returns the options of each fan-out target of the options, generating a complete job set of the
+// public org for the private org of the target with the target modifier and output.
+func expandFanout(optsList []options) []options {
+	var expanded []options
+
+	for _, o := range optsList {
+		expanded = append(expanded, o)
+
+		targets := make([]string, 0, len(o.Fanout))
+		for target := range o.Fanout {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+
+		for _, target := range targets {
+			org, privateOrg, _ := parseFanout(o.Fanout[target])
+
+			fo := o
+			fo.OrgMap = map[string]string{org: privateOrg}
+			fo.Modifier = fanoutModifier(o, target)
+			fo.ModifierMap = nil
+			fo.Output = o.FanoutOutputs[target]
+			fo.Tenants = nil
+			fo.Fanout = nil
+			fo.StrictMapping = false
+			fo.CopyUnmapped = false
+
+			expanded = append(expanded, fo)
+		}
+	}
+
+	return expanded
+}
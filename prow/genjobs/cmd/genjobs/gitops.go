@@ -0,0 +1,350 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/git"
+	"istio.io/test-infra/prow/genjobs/pkg/github"
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	gitOpsCommand     = "gitops"
+	gitOpsAuthor      = "genjobs"
+	gitOpsEmail       = "genjobs@istio.io"
+	gitOpsCommitTitle = "Sync generated private job(s)"
+	// gitOpsPRTemplate is the default template of the pull request body.
+	gitOpsPRTemplate = `Generated from {{.SourceRepo}}@{{.SourceSHA}}.
+{{if .Added}}
+Added job(s):
+{{range .Added}}- {{.}}
+{{end}}{{end}}{{if .Removed}}
+Removed job(s):
+{{range .Removed}}- {{.}}
+{{end}}{{end}}{{if .Changed}}
+Changed job(s):
+{{range .Changed}}- {{.}}
+{{end}}{{end}}`
+)
+
+// gitOpsOptions are the command-line flags for the gitops subcommand.
+type gitOpsOptions struct {
+	SourceRepo      string
+	SourceBranch    string
+	SourcePath      string
+	ConfigRepo      string
+	ConfigBranch    string
+	ConfigPath      string
+	PushBranch      string
+	GitHubTokenPath string
+	GitHubEndpoint  string
+	WorkDir         string
+	PRTemplate      string
+	SyncInterval    time.Duration
+	Once            bool
+	Semantic        bool
+}
+
+// syncSummary is the data available to the pull request body template.
+type syncSummary struct {
+	SourceRepo string
+	SourceSHA  string
+	ConfigRepo string
+	Added      []string
+	Removed    []string
+	Changed    []string
+}
+
+func init() {
+	commands[gitOpsCommand] = command{
+		flags: addGitOpsFlags,
+		run:   runGitOps,
+	}
+}
+
+// addGitOpsFlags registers the command-line flags for the gitops subcommand.
+func addGitOpsFlags(o *options) {
+	flag.StringVar(&o.gitOps.SourceRepo, "source-repo", "", "Public org/repo containing the source job(s).")
+	flag.StringVar(&o.gitOps.SourceBranch, "source-branch", "master", "Branch of the public repo to generate job(s) from.")
+	flag.StringVar(&o.gitOps.SourcePath, "source-path", ".", "Path within the public repo containing the source job(s).")
+	flag.StringVar(&o.gitOps.ConfigRepo, "config-repo", "", "Private org/repo to write generated job(s) to.")
+	flag.StringVar(&o.gitOps.ConfigBranch, "config-branch", "master", "Base branch of the private repo.")
+	flag.StringVar(&o.gitOps.ConfigPath, "config-path", ".", "Path within the private repo to write generated job(s) to.")
+	flag.StringVar(&o.gitOps.PushBranch, "push-branch", "genjobs-sync", "Branch of the private repo to push drift to.")
+	flag.StringVar(&o.gitOps.GitHubTokenPath, "github-token-path", "", "Path to file containing a GitHub token used to push and open pull requests.")
+	flag.StringVar(&o.gitOps.GitHubEndpoint, "github-endpoint", "", "GitHub API endpoint to open pull requests with (e.g. of GitHub Enterprise). Uses the public GitHub API if unset.")
+	flag.StringVar(&o.gitOps.PRTemplate, "pr-template", "", "Path to file containing a Go template of the pull request body summarizing the job changes.")
+	flag.StringVar(&o.gitOps.WorkDir, "work-dir", "", "Directory to clone repositories into (default a temporary directory).")
+	flag.DurationVar(&o.gitOps.SyncInterval, "sync-interval", time.Hour, "Interval between synchronizations.")
+	flag.BoolVar(&o.gitOps.Once, "once", false, "Synchronize once and exit.")
+	flag.BoolVar(&o.gitOps.Semantic, "semantic", false, "Only push drift when the generated job(s) changed semantically rather than in formatting.")
+}
+
+// validateGitOpsOpts validates the command-line flags for the gitops subcommand.
+func validateGitOpsOpts(g gitOpsOptions) error {
+	if g.SourceRepo == "" || strings.Count(g.SourceRepo, "/") != 1 {
+		return &util.ExitError{Message: fmt.Sprintf("--source-repo option must be an org/repo: %v.", g.SourceRepo), Code: 1}
+	}
+	if g.ConfigRepo == "" || strings.Count(g.ConfigRepo, "/") != 1 {
+		return &util.ExitError{Message: fmt.Sprintf("--config-repo option must be an org/repo: %v.", g.ConfigRepo), Code: 1}
+	}
+	if g.PRTemplate != "" && !util.IsFile(g.PRTemplate) {
+		return &util.ExitError{Message: fmt.Sprintf("--pr-template option path does not exist: %v.", g.PRTemplate), Code: 1}
+	}
+	if g.SyncInterval <= 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--sync-interval option must be positive: %v.", g.SyncInterval), Code: 1}
+	}
+	return nil
+}
+
+// runGitOps periodically regenerates the private job(s) and pushes a pull request when drift is detected.
+func runGitOps(o options) error {
+	if err := validateGitOpsOpts(o.gitOps); err != nil {
+		return err
+	}
+
+	if o.gitOps.WorkDir == "" {
+		dir, err := ioutil.TempDir("", gitOpsCommand)
+		if err != nil {
+			return fmt.Errorf("unable to create work directory: %v", err)
+		}
+		o.gitOps.WorkDir = dir
+	}
+
+	for {
+		if err := syncGitOps(o); err != nil {
+			if o.gitOps.Once {
+				return err
+			}
+			util.PrintErr(fmt.Sprintf("unable to synchronize %v: %v.", o.gitOps.ConfigRepo, err))
+		}
+
+		if o.gitOps.Once {
+			return nil
+		}
+
+		time.Sleep(o.gitOps.SyncInterval)
+	}
+}
+
+// syncGitOps clones the public and private repos, regenerates the job(s), and pushes any drift.
+func syncGitOps(o options) error {
+	g := o.gitOps
+
+	var token string
+	if g.GitHubTokenPath != "" {
+		t, err := github.ReadToken(g.GitHubTokenPath)
+		if err != nil {
+			return err
+		}
+		token = t
+	}
+
+	source, err := git.Clone(remoteURL(g.SourceRepo, token), g.SourceBranch, filepath.Join(g.WorkDir, "source"))
+	if err != nil {
+		return err
+	}
+	sourceSHA, err := source.HeadSHA()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := git.Clone(remoteURL(g.ConfigRepo, token), g.ConfigBranch, filepath.Join(g.WorkDir, "config"))
+	if err != nil {
+		return err
+	}
+
+	// Generated files are always rewritten from scratch to avoid appending to the existing job(s).
+	o.Input = filepath.Join(source.Dir, g.SourcePath)
+	o.inputs = nil
+	o.Output = filepath.Join(cfg.Dir, g.ConfigPath)
+	o.Clean = true
+	o.DryRun = false
+
+	before := snapshotJobs(o.Output)
+
+	if err := generateJobs(o); err != nil {
+		return err
+	}
+
+	drift, err := cfg.HasChanges()
+	if err != nil {
+		return err
+	}
+	if !drift {
+		fmt.Printf("no drift detected for %v at %v@%v\n", g.ConfigRepo, g.SourceRepo, sourceSHA)
+		return nil
+	}
+
+	summary := diffJobs(before, snapshotJobs(o.Output))
+	summary.SourceRepo, summary.SourceSHA, summary.ConfigRepo = g.SourceRepo, sourceSHA, g.ConfigRepo
+
+	if g.Semantic && len(summary.Added)+len(summary.Removed)+len(summary.Changed) == 0 {
+		fmt.Printf("no semantic drift detected for %v at %v@%v\n", g.ConfigRepo, g.SourceRepo, sourceSHA)
+		return nil
+	}
+
+	body, err := renderPRBody(g.PRTemplate, summary)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%s\n\n%s", gitOpsCommitTitle, body)
+	if err := cfg.CommitAll(g.PushBranch, gitOpsAuthor, gitOpsEmail, message); err != nil {
+		return err
+	}
+	if err := cfg.ForcePush(g.PushBranch); err != nil {
+		return err
+	}
+
+	fmt.Printf("pushed drift for %v to branch %v\n", g.ConfigRepo, g.PushBranch)
+
+	if g.GitHubTokenPath == "" {
+		return nil
+	}
+
+	org, repo := util.SplitOrgRepo(g.ConfigRepo)
+	client, err := github.NewClient(g.GitHubTokenPath, g.GitHubEndpoint, org, repo)
+	if err != nil {
+		return err
+	}
+
+	num, err := client.EnsurePullRequest(g.PushBranch, g.ConfigBranch, gitOpsCommitTitle, body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("opened pull request %v/pull/%d\n", g.ConfigRepo, num)
+
+	return nil
+}
+
+// snapshotJobs reads the job(s) of all job config files in a directory keyed by type, org/repo, and name.
+// The job(s) are normalized so that formatting differences are not considered changes.
+func snapshotJobs(dir string) map[string]string {
+	jobs := map[string]string{}
+
+	_ = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !util.HasExtension(p, yamlExt) {
+			return nil
+		}
+
+		c, err := config.ReadJobConfig(p)
+		if err != nil {
+			return nil
+		}
+
+		snapshotJobConfig(jobs, c)
+
+		return nil
+	})
+
+	return jobs
+}
+
+// snapshotJobConfig adds the rendered job(s) of a job config to a snapshot keyed by job type, org/repo and name.
+func snapshotJobConfig(jobs map[string]string, c config.JobConfig) {
+	add := func(key string, job interface{}) {
+		if b, err := yaml.Marshal(job); err == nil {
+			jobs[key] = string(b)
+		}
+	}
+
+	for orgrepo, pre := range c.PresubmitsStatic {
+		for _, job := range pre {
+			add(fmt.Sprintf("presubmit %s %s", orgrepo, job.Name), job)
+		}
+	}
+	for orgrepo, post := range c.PostsubmitsStatic {
+		for _, job := range post {
+			add(fmt.Sprintf("postsubmit %s %s", orgrepo, job.Name), job)
+		}
+	}
+	for _, job := range c.Periodics {
+		add(fmt.Sprintf("periodic %s", job.Name), job)
+	}
+}
+
+// diffJobs summarizes the job(s) added, removed, and changed between two snapshots.
+func diffJobs(before, after map[string]string) syncSummary {
+	var s syncSummary
+
+	for key, job := range after {
+		if old, ok := before[key]; !ok {
+			s.Added = append(s.Added, key)
+		} else if old != job {
+			s.Changed = append(s.Changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			s.Removed = append(s.Removed, key)
+		}
+	}
+
+	sort.Strings(s.Added)
+	sort.Strings(s.Removed)
+	sort.Strings(s.Changed)
+
+	return s
+}
+
+// renderPRBody renders the pull request body from a template file, or the default template when unset.
+func renderPRBody(path string, summary syncSummary) (string, error) {
+	text := gitOpsPRTemplate
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read pull request template %v: %v", path, err)
+		}
+		text = string(b)
+	}
+
+	tmpl, err := template.New("pr").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse pull request template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, summary); err != nil {
+		return "", fmt.Errorf("unable to render pull request template: %v", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// remoteURL returns the https clone URL of an org/repo, authenticated with a token when provided.
+func remoteURL(orgrepo, token string) string {
+	if token == "" {
+		return fmt.Sprintf("https://%s/%s.git", gitHost, orgrepo)
+	}
+	return fmt.Sprintf("https://x-access-token:%s@%s/%s.git", token, gitHost, orgrepo)
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/api"
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	serveCommand    = "serve"
+	defaultGRPCPort = 9090
+)
+
+// serveOptions are the command-line flags for the serve subcommand.
+type serveOptions struct {
+	GRPCPort int
+	UIPort   int
+	JobsDir  string
+}
+
+func init() {
+	commands[serveCommand] = command{
+		flags:      addServeFlags,
+		run:        runServe,
+		standalone: true,
+	}
+}
+
+// addServeFlags registers the command-line flags for the serve subcommand.
+func addServeFlags(o *options) {
+	flag.IntVar(&o.serve.GRPCPort, "grpc-port", defaultGRPCPort, "Port to serve the gRPC generation API on.")
+	flag.IntVar(&o.serve.UIPort, "ui-port", defaultUIPort, "Port to serve the web UI for previewing transformations on (0 to disable).")
+	flag.StringVar(&o.serve.JobsDir, "jobs-dir", "", "Directory containing public job(s) selectable in the web UI.")
+}
+
+// runServe serves the gRPC generation API, the web UI, and the health endpoints until terminated.
+func runServe(o options) error {
+	var u *ui
+	if o.serve.UIPort > 0 {
+		var err error
+		if u, err = newUI(o); err != nil {
+			return err
+		}
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", o.serve.GRPCPort))
+	if err != nil {
+		return fmt.Errorf("unable to listen on gRPC port %d: %v", o.serve.GRPCPort, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	api.RegisterGenJobsServer(grpcServer, &genJobsServer{})
+
+	grpcErrCh := make(chan error, 1)
+	go func() {
+		grpcErrCh <- grpcServer.Serve(lis)
+	}()
+
+	servers := []*http.Server{}
+	errChs := []<-chan error{}
+
+	var h health
+	server, errCh := startHealthServer(o.HealthPort, &h)
+	servers, errChs = append(servers, server), append(errChs, errCh)
+
+	if u != nil {
+		mux := http.NewServeMux()
+		u.register(mux)
+
+		server, errCh := startServer(o.serve.UIPort, mux)
+		servers, errChs = append(servers, server), append(errChs, errCh)
+	}
+
+	h.setReady()
+
+	// Any server error terminates all servers.
+	httpErrCh := make(chan error, 1)
+	for _, errCh := range errChs {
+		go func(errCh <-chan error) {
+			httpErrCh <- <-errCh
+		}(errCh)
+	}
+
+	sigCh, stop := terminationSignals()
+	defer stop()
+
+	select {
+	case err = <-grpcErrCh:
+		err = fmt.Errorf("unable to serve gRPC API: %v", err)
+	case err = <-httpErrCh:
+	case sig := <-sigCh:
+		util.PrintErr(fmt.Sprintf("received %v, shutting down.", sig))
+	}
+
+	grpcServer.GracefulStop()
+	for _, server := range servers {
+		_ = shutdownServer(server)
+	}
+
+	return err
+}
+
+// genJobsServer implements the gRPC generation API.
+type genJobsServer struct{}
+
+// GenerateJobs transforms the input job config files and streams each generated file.
+func (s *genJobsServer) GenerateJobs(req *api.GenerateJobsRequest, stream api.GenJobs_GenerateJobsServer) error {
+	o, err := optionsFromProto(req.GetTransform())
+	if err != nil {
+		return err
+	}
+
+	inputs, err := inputsFromProto(req.GetInputs())
+	if err != nil {
+		return err
+	}
+
+	outputs, err := generateFiles(o, inputs)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for _, p := range sortedPaths(outputs) {
+		if err := stream.Send(&api.GeneratedFile{
+			File: &api.File{Path: p, Content: outputs[p]},
+			Jobs: int32(countJobs(outputs[p])),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// VerifyJobs checks whether the provided outputs are up to date with the input job config files.
+func (s *genJobsServer) VerifyJobs(ctx context.Context, req *api.VerifyJobsRequest) (*api.VerifyJobsResponse, error) {
+	o, err := optionsFromProto(req.GetTransform())
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := inputsFromProto(req.GetInputs())
+	if err != nil {
+		return nil, err
+	}
+
+	generated, err := generateFiles(o, inputs)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	actual := filesFromProto(req.GetOutputs())
+	resp := &api.VerifyJobsResponse{}
+
+	for _, p := range sortedPaths(generated) {
+		content, exists := actual[p]
+		switch {
+		case !exists:
+			resp.Missing = append(resp.Missing, p)
+		case !bytes.Equal(content, generated[p]):
+			resp.Stale = append(resp.Stale, p)
+		}
+	}
+
+	resp.UpToDate = len(resp.Missing) == 0 && len(resp.Stale) == 0
+
+	return resp, nil
+}
+
+// ExplainJob shows how a single job is transformed.
+func (s *genJobsServer) ExplainJob(ctx context.Context, req *api.ExplainJobRequest) (*api.ExplainJobResponse, error) {
+	if req.GetJobName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "job name is required")
+	}
+
+	o, err := optionsFromProto(req.GetTransform())
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := inputsFromProto(req.GetInputs())
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := explainJob(o, inputs, req.GetJobName())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &api.ExplainJobResponse{
+		InputPath:  exp.InputPath,
+		OutputPath: exp.OutputPath,
+		Source:     exp.Source,
+		Generated:  exp.Generated,
+	}, nil
+}
+
+// optionsFromProto converts an API transform into generation options.
+func optionsFromProto(t *api.Transform) (options, error) {
+	var tr transform
+
+	if t.GetConfig() != "" {
+		if err := yaml.Unmarshal([]byte(t.GetConfig()), &tr); err != nil {
+			return options{}, status.Errorf(codes.InvalidArgument, "invalid transform config: %v", err)
+		}
+	}
+
+	applyDefaultTransforms(&tr, &transform{
+		OrgMap:       t.GetMapping(),
+		Modifier:     t.GetModifier(),
+		Bucket:       t.GetBucket(),
+		Cluster:      t.GetCluster(),
+		Channel:      t.GetChannel(),
+		SSHKeySecret: t.GetSshKeySecret(),
+		Labels:       t.GetLabels(),
+		Env:          t.GetEnv(),
+		Annotations:  t.GetAnnotations(),
+		Selector:     t.GetSelector(),
+		Branches:     t.GetBranches(),
+		BranchesOut:  t.GetBranchesOut(),
+		JobType:      t.GetJobType(),
+		Sort:         t.GetSort(),
+		Resolve:      t.GetResolve(),
+		Refs:         t.GetRefs(),
+		SSHClone:     t.GetSshClone(),
+	})
+
+	if len(tr.JobType) == 0 {
+		tr.JobType = defaultJobTypes
+	}
+
+	if len(tr.OrgMap) == 0 {
+		return options{}, status.Error(codes.InvalidArgument, "mapping is required")
+	}
+
+	if fields := serverPathFields(tr); len(fields) > 0 {
+		return options{}, status.Errorf(codes.InvalidArgument, "transform config must not reference server paths: %v", strings.Join(fields, ", "))
+	}
+
+	o := newTransformOptions(tr)
+	if err := o.validateOpts(); err != nil {
+		return options{}, status.Errorf(codes.InvalidArgument, "invalid transform: %v", err)
+	}
+
+	return o, nil
+}
+
+// serverPathFields returns the fields of a transform that read or write files or repositories on the server, which the
+// inputs and outputs of a request replace.
+func serverPathFields(t transform) []string {
+	paths := map[string]bool{
+		"input":                    t.Input != "",
+		"output":                   t.Output != "",
+		"input-repo":               t.InputRepo != "",
+		"presets":                  len(t.Presets) > 0,
+		"header-file":              t.HeaderFile != "",
+		"signature":                t.Signature != "",
+		"signed-manifest":          t.SignedManifest != "",
+		"tolerations-file":         t.TolerationsFile != "",
+		"volumes-file":             t.VolumesFile != "",
+		"tide-config":              t.TideConfig != "",
+		"branch-protection-config": t.BranchProtectionConfig != "",
+		"alert-rules":              t.AlertRules != "",
+		"secrets-output":           t.SecretsOutput != "",
+		"contexts-output":          t.ContextsOutput != "",
+		"manifest":                 t.Manifest != "",
+		"tenant-outputs":           len(t.TenantOutputs) > 0,
+		"fanout-outputs":           len(t.FanoutOutputs) > 0,
+	}
+
+	var fields []string
+	for field, set := range paths {
+		if set {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// filesFromProto converts API files into file contents keyed by path.
+func filesFromProto(files []*api.File) map[string][]byte {
+	m := make(map[string][]byte, len(files))
+	for _, f := range files {
+		m[f.GetPath()] = f.GetContent()
+	}
+	return m
+}
+
+// inputsFromProto converts API input files into file contents keyed by relative path.
+func inputsFromProto(files []*api.File) (map[string][]byte, error) {
+	inputs := filesFromProto(files)
+	if err := checkInputPaths(inputs); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return inputs, nil
+}
+
+// sortedPaths returns the sorted paths of files keyed by path.
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// countJobs counts the jobs of a generated job config file.
+func countJobs(content []byte) int {
+	var jobs struct {
+		Presubmits  map[string][]interface{} `json:"presubmits"`
+		Postsubmits map[string][]interface{} `json:"postsubmits"`
+		Periodics   []interface{}            `json:"periodics"`
+	}
+
+	if err := yaml.Unmarshal(content, &jobs); err != nil {
+		return 0
+	}
+
+	n := len(jobs.Periodics)
+	for _, pre := range jobs.Presubmits {
+		n += len(pre)
+	}
+	for _, post := range jobs.Postsubmits {
+		n += len(post)
+	}
+
+	return n
+}
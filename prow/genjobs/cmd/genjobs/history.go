@@ -0,0 +1,375 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	bolt "go.etcd.io/bbolt"
+
+	"istio.io/test-infra/prow/genjobs/pkg/git"
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	historyCommand = "history"
+	blameCommand   = "blame"
+
+	// historyLockTimeout is how long to wait for another generation run to release the history database.
+	historyLockTimeout = time.Minute
+)
+
+var (
+	// historyRunsBucket stores the history entry of every run keyed by its sequence number.
+	historyRunsBucket = []byte("runs")
+	// historyJobsBucket indexes the runs by the name of every job they changed, keyed by the name and run sequence number.
+	historyJobsBucket = []byte("jobs")
+)
+
+// historyOptions are the command-line flags for the history and blame subcommands.
+type historyOptions struct {
+	Limit int
+}
+
+// historyEntry is the record of a generation run in the history database.
+type historyEntry struct {
+	Time      time.Time   `json:"time"`
+	Input     string      `json:"input"`
+	SourceSHA string      `json:"source-sha,omitempty"`
+	Outputs   []string    `json:"outputs,omitempty"`
+	Changes   []jobChange `json:"changes,omitempty"`
+}
+
+// jobChange is a job added, changed, or removed in an output path by a generation run.
+type jobChange struct {
+	Path   string `json:"path"`
+	Job    string `json:"job"`
+	Action string `json:"action"`
+}
+
+func init() {
+	commands[historyCommand] = command{
+		flags:      addHistoryFlags,
+		run:        runHistory,
+		standalone: true,
+	}
+	commands[blameCommand] = command{
+		flags:      addHistoryFlags,
+		run:        runBlame,
+		standalone: true,
+	}
+}
+
+// addHistoryFlags registers the command-line flags for the history and blame subcommands.
+func addHistoryFlags(o *options) {
+	flag.IntVar(&o.history.Limit, "limit", 0, "Maximum number of most recent run(s) to show. Shows all run(s) if unset.")
+}
+
+// validateHistoryOpts validates the command-line flags for the history and blame subcommands.
+func validateHistoryOpts(o options) error {
+	if o.HistoryDB == "" {
+		return &util.ExitError{Message: "--history-db option is required.", Code: 1}
+	}
+	if o.history.Limit < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--limit option must not be negative: %d.", o.history.Limit), Code: 1}
+	}
+	return nil
+}
+
+// newHistoryEntry returns the history entry of a generation run from the planned change(s) of its output path(s).
+func newHistoryEntry(o options, plans []filePlan) historyEntry {
+	e := historyEntry{
+		Time:      time.Now().UTC(),
+		Input:     o.Input,
+		SourceSHA: inputSourceSHA(o),
+	}
+
+	for _, p := range plans {
+		e.Outputs = append(e.Outputs, p.path)
+		for _, key := range p.Added {
+			e.Changes = append(e.Changes, jobChange{Path: p.path, Job: key, Action: "added"})
+		}
+		for _, key := range p.Changed {
+			e.Changes = append(e.Changes, jobChange{Path: p.path, Job: key, Action: "changed"})
+		}
+		for _, key := range p.Removed {
+			e.Changes = append(e.Changes, jobChange{Path: p.path, Job: key, Action: "removed"})
+		}
+	}
+
+	return e
+}
+
+// inputSourceSHA returns the --source-sha option, or the commit SHA of the input tree when it is in a git repository.
+func inputSourceSHA(o options) string {
+	if o.SourceSHA != "" {
+		return o.SourceSHA
+	}
+
+	dir := o.Input
+	if util.IsFile(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	repo, err := git.Open(dir)
+	if err != nil {
+		return ""
+	}
+
+	sha, err := repo.HeadSHA()
+	if err != nil {
+		return ""
+	}
+
+	return sha
+}
+
+// recordHistory appends the history entry of a generation run to the history database.
+func recordHistory(o options, plans []filePlan) {
+	if err := os.MkdirAll(filepath.Dir(o.HistoryDB), os.ModePerm); err != nil {
+		util.PrintErr(fmt.Sprintf("unable to create directory for history database %v: %v.", o.HistoryDB, err))
+		return
+	}
+
+	if err := writeHistory(o.HistoryDB, newHistoryEntry(o, plans)); err != nil {
+		util.PrintErr(fmt.Sprintf("unable to write history database %v: %v.", o.HistoryDB, err))
+	}
+}
+
+// openHistory opens the history database, waiting for a concurrent generation run to release its lock.
+func openHistory(path string, readOnly bool) (*bolt.DB, error) {
+	return bolt.Open(path, 0644, &bolt.Options{Timeout: historyLockTimeout, ReadOnly: readOnly})
+}
+
+// writeHistory stores a history entry under the next run sequence number, indexing it by the name of every job it
+// changed.
+func writeHistory(path string, e historyEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("unable to marshal history entry: %v", err)
+	}
+
+	db, err := openHistory(path, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		runs, err := tx.CreateBucketIfNotExists(historyRunsBucket)
+		if err != nil {
+			return err
+		}
+		jobs, err := tx.CreateBucketIfNotExists(historyJobsBucket)
+		if err != nil {
+			return err
+		}
+
+		seq, err := runs.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := historyKey(seq)
+
+		if err := runs.Put(key, b); err != nil {
+			return err
+		}
+		for _, c := range e.Changes {
+			if err := jobs.Put(append([]byte(jobName(c.Job)+"\x00"), key...), nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// readHistory reads the entries of the history database in the order they were recorded.
+// Entries that cannot be decoded are reported and skipped.
+func readHistory(path string) ([]historyEntry, error) {
+	var entries []historyEntry
+
+	err := viewHistory(path, func(tx *bolt.Tx) error {
+		runs := tx.Bucket(historyRunsBucket)
+		if runs == nil {
+			return nil
+		}
+
+		return runs.ForEach(func(k, v []byte) error {
+			if e, ok := decodeHistoryEntry(k, v); ok {
+				entries = append(entries, e)
+			}
+			return nil
+		})
+	})
+
+	return entries, err
+}
+
+// readJobHistory reads the entries of the history database that changed a job name, in the order they were recorded.
+// Entries that cannot be decoded are reported and skipped.
+func readJobHistory(path string, name string) ([]historyEntry, error) {
+	var entries []historyEntry
+
+	err := viewHistory(path, func(tx *bolt.Tx) error {
+		runs, jobs := tx.Bucket(historyRunsBucket), tx.Bucket(historyJobsBucket)
+		if runs == nil || jobs == nil {
+			return nil
+		}
+
+		prefix := []byte(jobName(name) + "\x00")
+		c := jobs.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			key := k[len(prefix):]
+			if e, ok := decodeHistoryEntry(key, runs.Get(key)); ok {
+				entries = append(entries, e)
+			}
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// viewHistory runs a read-only transaction on the history database.
+func viewHistory(path string, fn func(tx *bolt.Tx) error) error {
+	if !util.Exists(path) {
+		return fmt.Errorf("no such file: %v", path)
+	}
+
+	db, err := openHistory(path, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(fn)
+}
+
+// decodeHistoryEntry decodes the history entry of a run, reporting the run when it is invalid.
+func decodeHistoryEntry(key, value []byte) (historyEntry, bool) {
+	var e historyEntry
+	if err := json.Unmarshal(value, &e); err != nil {
+		util.PrintErr(fmt.Sprintf("skipping invalid history entry of run %d: %v.", binary.BigEndian.Uint64(key), err))
+		return e, false
+	}
+	return e, true
+}
+
+// historyKey returns the key of a run sequence number, which sorts in the order the runs were recorded.
+func historyKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// jobName returns the name of a job from its key.
+func jobName(key string) string {
+	return key[strings.LastIndex(key, " ")+1:]
+}
+
+// lastEntries returns the most recent entries up to a limit, or all entries when the limit is unset.
+func lastEntries(entries []historyEntry, limit int) []historyEntry {
+	if limit > 0 && len(entries) > limit {
+		return entries[len(entries)-limit:]
+	}
+	return entries
+}
+
+// shortSHA returns the abbreviated form of a commit SHA.
+func shortSHA(sha string) string {
+	if sha == "" {
+		return "-"
+	}
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// runHistory prints the recorded generation runs and the number of job(s) they changed.
+func runHistory(o options) error {
+	if err := validateHistoryOpts(o); err != nil {
+		return err
+	}
+
+	entries, err := readHistory(o.HistoryDB)
+	if err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("unable to read history database %v: %v.", o.HistoryDB, err), Code: 1}
+	}
+
+	for _, e := range lastEntries(entries, o.history.Limit) {
+		fmt.Printf("%v %v %v: %d job(s) changed\n", e.Time.Format(time.RFC3339), shortSHA(e.SourceSHA), e.Input, len(e.Changes))
+		for _, c := range e.Changes {
+			fmt.Printf("  %-7v %v (%v)\n", c.Action, c.Job, c.Path)
+		}
+	}
+
+	return nil
+}
+
+// runBlame prints the generation runs that added, changed, or removed a job and the source commit they were generated from.
+func runBlame(o options) error {
+	if err := validateHistoryOpts(o); err != nil {
+		return err
+	}
+
+	if flag.NArg() != 1 {
+		return &util.ExitError{Message: "blame requires exactly one job name argument.", Code: 1}
+	}
+	name := flag.Arg(0)
+
+	entries, err := readJobHistory(o.HistoryDB, name)
+	if err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("unable to read history database %v: %v.", o.HistoryDB, err), Code: 1}
+	}
+
+	var matched []historyEntry
+	for _, e := range entries {
+		var changes []jobChange
+		for _, c := range e.Changes {
+			if c.Job == name || strings.HasSuffix(c.Job, " "+name) {
+				changes = append(changes, c)
+			}
+		}
+		if len(changes) > 0 {
+			e.Changes = changes
+			matched = append(matched, e)
+		}
+	}
+
+	if len(matched) == 0 {
+		return &util.ExitError{Message: fmt.Sprintf("no recorded change(s) to job %v.", name), Code: 1}
+	}
+
+	for _, e := range lastEntries(matched, o.history.Limit) {
+		for _, c := range e.Changes {
+			fmt.Printf("%v %v %-7v %v (%v from %v)\n", e.Time.Format(time.RFC3339), shortSHA(e.SourceSHA), c.Action, c.Job, c.Path, e.Input)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,576 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// updateJobBase updates the jobs JobBase fields based on provided inputs to work with private repositories.
+func updateJobBase(o options, job *config.JobBase, orgrepo string, host string) {
+	if len(o.Annotations) != 0 {
+		job.Annotations = o.Annotations
+	}
+
+	if o.Reverse {
+		job.CloneURI = ""
+	}
+
+	if orgrepo != "" {
+		updateCloneURI(o, job, orgrepo, host)
+	}
+
+	if o.Cluster != "" && o.Cluster != defaultCluster {
+		job.Cluster = o.Cluster
+	}
+
+	updateAlertAnnotations(o, job)
+	updatePodAnnotations(o, job)
+	updateActiveDeadline(o, job)
+	updateTerminationGracePeriod(o, job)
+	updateCacheVolume(o, job)
+	updatePriorityClass(o, job)
+	updateJobName(o, job)
+	updateReporterConfig(o, job)
+	updateRerunAuthConfig(o, job)
+	updateLabels(o, job)
+	updatePubSubLabels(o, job)
+	updateRuntimeClass(o, job)
+	updateRemoteCache(o, job)
+	updateNodeSelector(o, job)
+	updateTolerations(o, job)
+	updateServiceAccount(o, job)
+	updateSecurityContext(o, job)
+	updateResources(o, job)
+	updateEnvs(o, job)
+}
+
+// updateJobName updates the jobs Name fields based on provided inputs.
+func updateJobName(o options, job *config.JobBase) {
+	suffix := ""
+
+	if o.Modifier != "" {
+		suffix = jobnameSeparator + o.Modifier
+	}
+
+	if o.Reverse {
+		job.Name = strings.TrimSuffix(job.Name, suffix)
+		return
+	}
+
+	if !o.AllowLongJobNames {
+		maxNameLen := maxLabelLen - len(suffix)
+
+		if len(job.Name) > maxNameLen {
+			job.Name = job.Name[:maxNameLen]
+		}
+	}
+
+	job.Name += suffix
+}
+
+// updateCloneURI updates the jobs CloneURI to clone the private repository over ssh or from a git host other than GitHub.
+func updateCloneURI(o options, job *config.JobBase, orgrepo string, host string) {
+	if o.SSHClone {
+		job.CloneURI = fmt.Sprintf("git@%s:%s.git", host, orgrepo)
+	} else if host != gitHost {
+		job.CloneURI = fmt.Sprintf("https://%s/%s.git", host, orgrepo)
+	}
+}
+
+// updateReporterConfig updates the jobs ReporterConfig fields based on provided inputs.
+func updateReporterConfig(o options, job *config.JobBase) {
+	if o.NoReporter {
+		job.ReporterConfig = nil
+		return
+	}
+
+	if o.Channel == "" {
+		return
+	}
+
+	if job.ReporterConfig == nil {
+		job.ReporterConfig = &prowjob.ReporterConfig{}
+	}
+
+	job.ReporterConfig.Slack = &prowjob.SlackReporterConfig{Channel: o.Channel}
+}
+
+// updateRerunAuthConfig updates the jobs RerunAuthConfig fields based on provided inputs.
+func updateRerunAuthConfig(o options, job *config.JobBase) {
+	if len(o.RerunOrgs) == 0 && len(o.RerunUsers) == 0 && len(o.RerunTeams) == 0 && len(o.RerunTeamIDs) == 0 {
+		return
+	}
+
+	var teams []prowjob.GitHubTeamSlug
+	for _, team := range o.RerunTeams {
+		org, slug := util.SplitOrgRepo(team)
+		teams = append(teams, prowjob.GitHubTeamSlug{Org: org, Slug: slug})
+	}
+
+	// The original job `RerunAuthConfig` is overwritten with the user-defined values.
+	job.RerunAuthConfig = &prowjob.RerunAuthConfig{
+		GitHubOrgs:      o.RerunOrgs,
+		GitHubUsers:     o.RerunUsers,
+		GitHubTeamSlugs: teams,
+		GitHubTeamIDs:   o.RerunTeamIDs,
+	}
+}
+
+// updateLabels updates the jobs Labels fields based on provided inputs.
+func updateLabels(o options, job *config.JobBase) {
+	if len(o.Labels) == 0 {
+		return
+	}
+
+	if job.Labels == nil {
+		job.Labels = make(map[string]string)
+	}
+
+	for labelK, labelV := range o.Labels {
+		job.Labels[labelK] = labelV
+	}
+}
+
+// updatePubSubLabels updates the jobs PubSub reporter Labels based on provided inputs.
+func updatePubSubLabels(o options, job *config.JobBase) {
+	if o.PubSubTopic == "" {
+		return
+	}
+
+	if job.Labels == nil {
+		job.Labels = make(map[string]string)
+	}
+
+	job.Labels[pubSubProjectLabel] = o.PubSubProject
+	job.Labels[pubSubTopicLabel] = o.PubSubTopic
+}
+
+// updateNodeSelector updates the jobs NodeSelector fields based on provided inputs.
+func updateNodeSelector(o options, job *config.JobBase) {
+	if o.OverrideSelector {
+		job.Spec.NodeSelector = make(map[string]string)
+	}
+
+	if len(o.Selector) == 0 {
+		return
+	}
+
+	if job.Spec.NodeSelector == nil {
+		job.Spec.NodeSelector = make(map[string]string)
+	}
+
+	for selK, selV := range o.Selector {
+		job.Spec.NodeSelector[selK] = selV
+	}
+}
+
+// updateMaxConcurrency updates the jobs MaxConcurrency fields based on provided inputs.
+// The max concurrency is scaled first, then capped; unlimited jobs are only capped.
+func updateMaxConcurrency(o options, job *config.JobBase) {
+	if o.ConcurrencyScale > 0 && job.MaxConcurrency > 0 {
+		job.MaxConcurrency = int(math.Ceil(float64(job.MaxConcurrency) * o.ConcurrencyScale))
+	}
+
+	if o.MaxConcurrency > 0 && (job.MaxConcurrency == 0 || job.MaxConcurrency > o.MaxConcurrency) {
+		job.MaxConcurrency = o.MaxConcurrency
+	}
+}
+
+// parseToleration parses a toleration of the form key[=value]:effect. Tolerations without a value tolerate any value.
+func parseToleration(s string) (v1.Toleration, bool) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return v1.Toleration{}, false
+	}
+
+	toleration := v1.Toleration{Effect: v1.TaintEffect(s[i+1:]), Operator: v1.TolerationOpExists}
+	switch toleration.Effect {
+	case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+	default:
+		return v1.Toleration{}, false
+	}
+
+	toleration.Key = s[:i]
+	if kv := strings.SplitN(s[:i], "=", 2); len(kv) == 2 {
+		toleration.Key, toleration.Value, toleration.Operator = kv[0], kv[1], v1.TolerationOpEqual
+	}
+
+	return toleration, toleration.Key != ""
+}
+
+// validateSecurityContextField validates a --security-context field and its value.
+func validateSecurityContextField(k string, v string) error {
+	isBool, ok := securityContextFields[k]
+	if !ok {
+		return fmt.Errorf("unknown field %v", k)
+	}
+
+	var err error
+	if isBool {
+		_, err = strconv.ParseBool(v)
+	} else {
+		_, err = strconv.ParseInt(v, 10, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for %v: %v", k, v)
+	}
+
+	return nil
+}
+
+// updateSecurityContext updates the jobs pod and container SecurityContext fields based on provided inputs.
+func updateSecurityContext(o options, job *config.JobBase) {
+	if len(o.SecurityContext) == 0 || job.Spec == nil {
+		return
+	}
+
+	int64Field := func(k string) *int64 {
+		v, err := strconv.ParseInt(o.SecurityContext[k], 10, 64)
+		if err != nil {
+			return nil
+		}
+		return &v
+	}
+	boolField := func(k string) *bool {
+		v, err := strconv.ParseBool(o.SecurityContext[k])
+		if err != nil {
+			return nil
+		}
+		return &v
+	}
+
+	for _, k := range util.SortedKeys(o.SecurityContext) {
+		for i := range job.Spec.Containers {
+			c := &job.Spec.Containers[i]
+
+			// Containers overriding the pod security context are updated as well.
+			switch k {
+			case "runAsUser":
+				if c.SecurityContext != nil && c.SecurityContext.RunAsUser != nil {
+					c.SecurityContext.RunAsUser = int64Field(k)
+				}
+				continue
+			case "runAsGroup":
+				if c.SecurityContext != nil && c.SecurityContext.RunAsGroup != nil {
+					c.SecurityContext.RunAsGroup = int64Field(k)
+				}
+				continue
+			case "runAsNonRoot":
+				if c.SecurityContext != nil && c.SecurityContext.RunAsNonRoot != nil {
+					c.SecurityContext.RunAsNonRoot = boolField(k)
+				}
+				continue
+			case "fsGroup":
+				continue
+			}
+
+			if c.SecurityContext == nil {
+				c.SecurityContext = &v1.SecurityContext{}
+			}
+
+			switch k {
+			case "privileged":
+				c.SecurityContext.Privileged = boolField(k)
+			case "allowPrivilegeEscalation":
+				c.SecurityContext.AllowPrivilegeEscalation = boolField(k)
+			case "readOnlyRootFilesystem":
+				c.SecurityContext.ReadOnlyRootFilesystem = boolField(k)
+			}
+		}
+
+		pod := job.Spec.SecurityContext
+		if pod == nil {
+			pod = &v1.PodSecurityContext{}
+		}
+
+		switch k {
+		case "runAsUser":
+			pod.RunAsUser = int64Field(k)
+		case "runAsGroup":
+			pod.RunAsGroup = int64Field(k)
+		case "runAsNonRoot":
+			pod.RunAsNonRoot = boolField(k)
+		case "fsGroup":
+			pod.FSGroup = int64Field(k)
+		default:
+			continue
+		}
+
+		job.Spec.SecurityContext = pod
+	}
+}
+
+// updatePriorityClass updates the jobs PriorityClassName fields based on provided inputs.
+func updatePriorityClass(o options, job *config.JobBase) {
+	if o.PriorityClass == "" || job.Spec == nil || !matchesAny(o.PriorityClassJobs, job.Name) {
+		return
+	}
+
+	job.Spec.PriorityClassName = o.PriorityClass
+}
+
+// updateRuntimeClass updates the jobs RuntimeClassName fields based on provided inputs.
+func updateRuntimeClass(o options, job *config.JobBase) {
+	if o.RuntimeClass == "" || job.Spec == nil {
+		return
+	}
+
+	if o.runtimeClassSel != nil && !o.runtimeClassSel.Matches(labels.Set(job.Labels)) {
+		return
+	}
+
+	runtimeClass := o.RuntimeClass
+	job.Spec.RuntimeClassName = &runtimeClass
+}
+
+// updateServiceAccount updates the jobs ServiceAccountName fields based on provided inputs.
+// Org/repo service accounts take precedence over cluster service accounts.
+func updateServiceAccount(o options, job *config.JobBase) {
+	if job.Spec == nil {
+		return
+	}
+
+	cluster := job.Cluster
+	if cluster == "" {
+		cluster = defaultCluster
+	}
+
+	sa := o.ServiceAccount
+	if o.repoSA != "" {
+		sa = o.repoSA
+	} else if clusterSA, ok := o.ClusterServiceAccounts[cluster]; ok {
+		sa = clusterSA
+	}
+
+	if sa != "" {
+		job.Spec.ServiceAccountName = sa
+	}
+}
+
+// updateTolerations updates the jobs Tolerations fields based on provided inputs.
+func updateTolerations(o options, job *config.JobBase) {
+	if len(o.TolerationList) == 0 || job.Spec == nil {
+		return
+	}
+
+tolerations:
+	for i := range o.TolerationList {
+		for j := range job.Spec.Tolerations {
+			if job.Spec.Tolerations[j].MatchToleration(&o.TolerationList[i]) {
+				continue tolerations
+			}
+		}
+
+		job.Spec.Tolerations = append(job.Spec.Tolerations, o.TolerationList[i])
+	}
+}
+
+// parseResource parses a job container resource key of the form (requests|limits).resource.
+func parseResource(key string) (kind string, name string, ok bool) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", false
+	}
+
+	switch parts[0] {
+	case "requests", "limits":
+	default:
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// updateResources updates the jobs container Resources fields based on provided inputs.
+// Existing cpu and memory quantities are scaled before the explicit resources are applied.
+func updateResources(o options, job *config.JobBase) {
+	if job.Spec == nil {
+		return
+	}
+
+	if o.ResourceScale > 0 {
+		for i := range job.Spec.Containers {
+			scaleResources(o.ResourceScale, job.Spec.Containers[i].Resources.Requests)
+			scaleResources(o.ResourceScale, job.Spec.Containers[i].Resources.Limits)
+		}
+	}
+
+	if len(o.Resources) == 0 {
+		return
+	}
+
+	for i := range job.Spec.Containers {
+		requirements := &job.Spec.Containers[i].Resources
+
+		if o.ResourcesIfUnset && (len(requirements.Requests) > 0 || len(requirements.Limits) > 0) {
+			continue
+		}
+
+		for _, k := range util.SortedKeys(o.Resources) {
+			kind, name, ok := parseResource(k)
+			if !ok {
+				continue
+			}
+
+			quantity, err := resource.ParseQuantity(o.Resources[k])
+			if err != nil {
+				continue
+			}
+
+			list := &requirements.Requests
+			if kind == "limits" {
+				list = &requirements.Limits
+			}
+
+			if *list == nil {
+				*list = v1.ResourceList{}
+			}
+
+			(*list)[v1.ResourceName(name)] = quantity
+		}
+	}
+}
+
+// scaleResources scales the cpu and memory quantities of a resource list by a factor, rounded up.
+func scaleResources(scale float64, list v1.ResourceList) {
+	if q, ok := list[v1.ResourceCPU]; ok {
+		list[v1.ResourceCPU] = *resource.NewMilliQuantity(int64(math.Ceil(float64(q.MilliValue())*scale)), q.Format)
+	}
+	if q, ok := list[v1.ResourceMemory]; ok {
+		list[v1.ResourceMemory] = *resource.NewQuantity(int64(math.Ceil(float64(q.Value())*scale)), q.Format)
+	}
+}
+
+// updateAlertAnnotations updates the jobs alerting and SLO Annotations based on provided inputs.
+func updateAlertAnnotations(o options, job *config.JobBase) {
+	annotations := map[string]string{}
+
+	if o.AlertSeverity != "" {
+		annotations[alertSeverityAnnotation] = o.AlertSeverity
+	}
+	if o.AlertEmail != "" {
+		annotations[alertEmailAnnotation] = o.AlertEmail
+	}
+	if o.NumFailuresToAlert > 0 {
+		annotations[numFailuresToAlertAnnotation] = strconv.Itoa(o.NumFailuresToAlert)
+	}
+	if o.AlertStaleResultsHours > 0 {
+		annotations[alertStaleResultsHoursAnnotation] = strconv.Itoa(o.AlertStaleResultsHours)
+	}
+
+	mergeAnnotations(job, annotations)
+}
+
+// updatePodAnnotations updates the jobs Annotations fields with the pod annotations based on provided inputs.
+// Prow propagates the annotations of a job to the metadata of its pod.
+func updatePodAnnotations(o options, job *config.JobBase) {
+	mergeAnnotations(job, o.PodAnnotations)
+}
+
+// mergeAnnotations adds annotations to the jobs Annotations fields, overriding existing keys.
+func mergeAnnotations(job *config.JobBase, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+
+	// Copy the annotations so that maps shared between jobs are never modified.
+	merged := make(map[string]string, len(job.Annotations)+len(annotations))
+	for k, v := range job.Annotations {
+		merged[k] = v
+	}
+	for k, v := range annotations {
+		merged[k] = v
+	}
+
+	job.Annotations = merged
+}
+
+// updateActiveDeadline updates the jobs ActiveDeadlineSeconds field based on provided inputs.
+// The first pattern in lexical order matching the job name is applied.
+func updateActiveDeadline(o options, job *config.JobBase) {
+	if len(o.ActiveDeadlines) == 0 || job.Spec == nil {
+		return
+	}
+
+	for _, pattern := range util.SortedKeys(o.ActiveDeadlines) {
+		if !util.MustCompile(pattern).MatchString(job.Name) {
+			continue
+		}
+
+		d, err := time.ParseDuration(o.ActiveDeadlines[pattern])
+		if err != nil {
+			return
+		}
+
+		seconds := int64(d / time.Second)
+		job.Spec.ActiveDeadlineSeconds = &seconds
+
+		return
+	}
+}
+
+// updateTerminationGracePeriod updates the jobs TerminationGracePeriodSeconds field based on provided inputs.
+func updateTerminationGracePeriod(o options, job *config.JobBase) {
+	if o.TerminationGracePeriod == "" || job.Spec == nil {
+		return
+	}
+
+	d, err := time.ParseDuration(o.TerminationGracePeriod)
+	if err != nil {
+		return
+	}
+
+	seconds := int64(d / time.Second)
+	job.Spec.TerminationGracePeriodSeconds = &seconds
+}
+
+// updateEnvs updates the jobs Env fields based on provided inputs.
+func updateEnvs(o options, job *config.JobBase) {
+	if len(o.Env) == 0 {
+		return
+	}
+
+	envKs := util.SortedKeys(o.Env)
+
+	for _, envK := range envKs {
+	container:
+		for i := range job.Spec.Containers {
+
+			for j := range job.Spec.Containers[i].Env {
+				if job.Spec.Containers[i].Env[j].Name == envK {
+					job.Spec.Containers[i].Env[j].Value = o.Env[envK]
+					continue container
+				}
+			}
+
+			job.Spec.Containers[i].Env = append(job.Spec.Containers[i].Env, v1.EnvVar{Name: envK, Value: o.Env[envK]})
+		}
+	}
+}
@@ -17,40 +17,110 @@ limitations under the License.
 package genjobs
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	flag "github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"sigs.k8s.io/yaml"
 
+	"istio.io/test-infra/prow/genjobs/pkg/git"
 	"istio.io/test-infra/prow/genjobs/pkg/util"
 )
 
 const (
-	autogenHeader     = "# THIS FILE IS AUTOGENERATED. DO NOT EDIT. See genjobs/README.md\n"
-	filenameSeparator = "."
-	jobnameSeparator  = "_"
-	gitHost           = "github.com"
-	maxLabelLen       = 63
-	defaultModifier   = "private"
-	defaultCluster    = "default"
-	defaultsFilename  = ".defaults.yaml"
-	yamlExt           = ".(yml|yaml)$"
-	gerritReportLabel = "prow.k8s.io/gerrit-report-label"
+	autogenHeader      = "# THIS FILE IS AUTOGENERATED. DO NOT EDIT. See genjobs/README.md\n"
+	provenancePrefix   = "# genjobs-source-sha: "
+	sourcePrefix       = "# genjobs-source: "
+	versionPrefix      = "# genjobs-version: "
+	flagsHashPrefix    = "# genjobs-flags-hash: "
+	filenameSeparator  = "."
+	jobnameSeparator   = "_"
+	exclusionPrefix    = "!"
+	regexPrefix        = "~"
+	envRemovalSuffix   = "-"
+	gitHost            = "github.com"
+	maxLabelLen        = 63
+	defaultModifier    = "private"
+	defaultCluster     = "default"
+	defaultsFilename   = ".defaults.yaml"
+	stdio              = "-"
+	stdinFilename      = "stdin.yaml"
+	inputSeparator     = ","
+	defaultInputRef    = "master"
+	downloadTimeout    = time.Minute
+	yamlExt            = ".(yml|yaml)$"
+	jobConfigExt       = ".(yml|yaml|json)$"
+	formatYAML         = "yaml"
+	formatJSON         = "json"
+	gerritReportLabel  = "prow.k8s.io/gerrit-report-label"
+	pubSubProjectLabel = "prow.k8s.io/pubsub.project"
+	pubSubTopicLabel   = "prow.k8s.io/pubsub.topic"
+
+	alertSeverityAnnotation          = "alert-severity"
+	alertEmailAnnotation             = "testgrid-alert-email"
+	numFailuresToAlertAnnotation     = "testgrid-num-failures-to-alert"
+	alertStaleResultsHoursAnnotation = "testgrid-alert-stale-results-hours"
 )
 
 var defaultJobTypes = []string{"presubmit", "postsubmit", "periodic"}
 
+// version is the genjobs version recorded as provenance, set at build time with
+// -ldflags "-X istio.io/test-infra/prow/genjobs/cmd/genjobs.version=<version>".
+var version = "dev"
+
+// splitJobTypes are the names of the job type files written with --split-by-type, in the order presets are assigned.
+var splitJobTypes = []string{"presubmits", "postsubmits", "periodics"}
+
+const (
+	// pathAliasPreserve keeps the path alias(es) of mapped repos as-is.
+	pathAliasPreserve = "preserve"
+	// pathAliasClear removes the path alias(es) of mapped repos.
+	pathAliasClear = "clear"
+	// pathAliasRepo sets the path alias(es) of mapped repos to the import path of the private repo.
+	pathAliasRepo = "repo"
+)
+
+var pathAliasModes = []string{pathAliasPreserve, pathAliasClear, pathAliasRepo}
+
+// securityContextFields are the supported --security-context fields keyed by name, with whether their value is a bool.
+// Fields of the pod security context are also set on containers that override them.
+var securityContextFields = map[string]bool{
+	"runAsUser":                false,
+	"runAsGroup":               false,
+	"runAsNonRoot":             true,
+	"fsGroup":                  false,
+	"privileged":               true,
+	"allowPrivilegeEscalation": true,
+	"readOnlyRootFilesystem":   true,
+}
+
+// branchNameRegex matches the branch name(s) referenced by a branch pattern, including escaped dots.
+var branchNameRegex = regexp.MustCompile(`(?:[\w/-]|\\?\.)+`)
+
 // sortOrder is the type to define sort order.
 type sortOrder string
 
@@ -67,102 +137,478 @@ type configuration struct {
 
 // transform are the available transformation fields.
 type transform struct {
+	Name                   string            `json:"name,omitempty"`
 	Annotations            map[string]string `json:"annotations,omitempty"`
+	ActiveDeadlines        map[string]string `json:"active-deadline,omitempty"`
+	TerminationGracePeriod string            `json:"termination-grace-period,omitempty"`
+	AlertRules             string            `json:"alert-rules,omitempty"`
+	AlertStaleWindow       string            `json:"alert-stale-window,omitempty"`
+	AlertLabels            map[string]string `json:"alert-labels,omitempty"`
+	AlertSeverity          string            `json:"alert-severity,omitempty"`
+	AlertEmail             string            `json:"alert-email,omitempty"`
+	NumFailuresToAlert     int               `json:"num-failures-to-alert,omitempty"`
+	AlertStaleResultsHours int               `json:"alert-stale-results-hours,omitempty"`
 	Bucket                 string            `json:"bucket,omitempty"`
+	GCSCredentialsSecret   string            `json:"gcs-credentials-secret,omitempty"`
+	DefaultServiceAccount  string            `json:"default-service-account-name,omitempty"`
+	BucketMap              map[string]string `json:"bucket-map,omitempty"`
+	CacheVolume            string            `json:"cache-volume,omitempty"`
+	CacheMountPath         string            `json:"cache-mount-path,omitempty"`
+	CacheJobs              []string          `json:"cache-jobs,omitempty"`
+	CacheEnv               map[string]string `json:"cache-env,omitempty"`
 	Cluster                string            `json:"cluster,omitempty"`
+	ClusterMap             map[string]string `json:"cluster-map,omitempty"`
+	ServiceAccount         string            `json:"service-account,omitempty"`
+	ServiceAccountMap      map[string]string `json:"service-account-map,omitempty"`
+	ClusterServiceAccounts map[string]string `json:"cluster-service-accounts,omitempty"`
+	PriorityClass          string            `json:"priority-class,omitempty"`
+	PriorityClassJobs      []string          `json:"priority-class-jobs,omitempty"`
+	RuntimeClass           string            `json:"runtime-class,omitempty"`
+	RuntimeClassSelector   string            `json:"runtime-class-selector,omitempty"`
+	AnnotationSelector     string            `json:"annotation-selector,omitempty"`
+	ContextsOutput         string            `json:"contexts-output,omitempty"`
+	Manifest               string            `json:"manifest,omitempty"`
 	Channel                string            `json:"channel,omitempty"`
+	ChannelMap             map[string]string `json:"channel-map,omitempty"`
+	PubSubProject          string            `json:"pubsub-project,omitempty"`
+	PubSubTopic            string            `json:"pubsub-topic,omitempty"`
+	SlackReportTemplate    string            `json:"slack-report-template,omitempty"`
+	DeckURL                string            `json:"deck-url,omitempty"`
 	SSHKeySecret           string            `json:"ssh-key-secret,omitempty"`
+	OauthTokenSecret       string            `json:"oauth-token-secret,omitempty"`
+	GitHubAppID            string            `json:"github-app-id,omitempty"`
+	GitHubAppPrivateKey    string            `json:"github-app-private-key-secret,omitempty"`
+	GitHost                string            `json:"git-host,omitempty"`
+	PathAliasMode          string            `json:"path-alias-mode,omitempty"`
+	PathAliasMap           map[string]string `json:"path-alias-map,omitempty"`
+	MaxJobsPerFile         int               `json:"max-jobs-per-file,omitempty"`
+	SplitByType            bool              `json:"split-by-type,omitempty"`
+	SplitByJob             bool              `json:"split-by-job,omitempty"`
+	MaxConcurrency         int               `json:"max-concurrency,omitempty"`
+	ConcurrencyScale       float64           `json:"concurrency-scale,omitempty"`
 	Modifier               string            `json:"modifier,omitempty"`
+	ModifierMap            map[string]string `json:"modifier-map,omitempty"`
 	Input                  string            `json:"input,omitempty"`
 	Output                 string            `json:"output,omitempty"`
+	Exclude                []string          `json:"exclude,omitempty"`
+	Format                 string            `json:"format,omitempty"`
+	InputRepo              string            `json:"input-repo,omitempty"`
+	InputRef               string            `json:"input-ref,omitempty"`
+	RemoteCache            string            `json:"remote-cache,omitempty"`
+	RemoteCacheSecret      string            `json:"remote-cache-secret,omitempty"`
+	RemoteCacheLabels      []string          `json:"remote-cache-labels,omitempty"`
 	Sort                   string            `json:"sort,omitempty"`
+	SecretsOutput          string            `json:"secrets-output,omitempty"`
+	SecretsKind            string            `json:"secrets-kind,omitempty"`
+	SecretsNamespace       string            `json:"secrets-namespace,omitempty"`
+	Signature              string            `json:"signature,omitempty"`
+	SignedManifest         string            `json:"signed-manifest,omitempty"`
+	TrustedKeys            string            `json:"trusted-keys,omitempty"`
+	SourceSHA              string            `json:"source-sha,omitempty"`
+	HeaderFile             string            `json:"header-file,omitempty"`
+	Provenance             bool              `json:"provenance,omitempty"`
+	TideConfig             string            `json:"tide-config,omitempty"`
+	BranchProtectionConfig string            `json:"branch-protection-config,omitempty"`
+	TideMergeMethod        string            `json:"tide-merge-method,omitempty"`
 	ExtraRefs              []prowjob.Refs    `json:"extra-refs,omitempty"`
 	Branches               []string          `json:"branches,omitempty"`
+	SkipBranches           []string          `json:"skip-branches,omitempty"`
 	BranchesOut            []string          `json:"branches-out,omitempty"`
 	RefBranchOut           string            `json:"ref-branch-out,omitempty"`
+	BranchMap              map[string]string `json:"branch-map,omitempty"`
+	BaseRefMap             map[string]string `json:"base-ref-map,omitempty"`
 	Presets                []string          `json:"presets,omitempty"`
 	RerunOrgs              []string          `json:"rerun-orgs,omitempty"`
 	RerunUsers             []string          `json:"rerun-users,omitempty"`
+	RerunTeams             []string          `json:"rerun-teams,omitempty"`
+	RerunTeamIDs           []int             `json:"rerun-team-ids,omitempty"`
+	SlackJobStates         []string          `json:"slack-job-states,omitempty"`
+	TideLabels             []string          `json:"tide-labels,omitempty"`
+	TideMissingLabels      []string          `json:"tide-missing-labels,omitempty"`
 	EnvDenylist            []string          `json:"env-denylist,omitempty"`
 	VolumeDenylist         []string          `json:"volume-denylist,omitempty"`
+	RemoveLabels           []string          `json:"remove-labels,omitempty"`
 	JobAllowlist           []string          `json:"job-allowlist,omitempty"`
 	JobDenylist            []string          `json:"job-denylist,omitempty"`
 	RepoAllowlist          []string          `json:"repo-allowlist,omitempty"`
 	RepoDenylist           []string          `json:"repo-denylist,omitempty"`
 	JobType                []string          `json:"job-type,omitempty"`
 	Selector               map[string]string `json:"selector,omitempty"`
+	Tolerations            []string          `json:"tolerations,omitempty"`
+	TolerationsFile        string            `json:"tolerations-file,omitempty"`
+	VolumesFile            string            `json:"volumes-file,omitempty"`
 	Labels                 map[string]string `json:"labels,omitempty"`
+	PodAnnotations         map[string]string `json:"pod-annotations,omitempty"`
+	SecurityContext        map[string]string `json:"security-context,omitempty"`
 	Env                    map[string]string `json:"env,omitempty"`
 	RefOrgMap              map[string]string `json:"ref-mapping,omitempty"`
 	OrgMap                 map[string]string `json:"mapping,omitempty"`
+	BotTokenSecrets        map[string]string `json:"bot-token-secrets,omitempty"`
+	SecretMap              map[string]string `json:"secret-map,omitempty"`
+	DecorationResources    map[string]string `json:"decoration-resources,omitempty"`
+	UtilityImages          map[string]string `json:"utility-images,omitempty"`
+	Resources              map[string]string `json:"resources,omitempty"`
+	ResourceScale          float64           `json:"resource-scale,omitempty"`
+	Tenants                map[string]string `json:"tenants,omitempty"`
+	TenantOutputs          map[string]string `json:"tenant-outputs,omitempty"`
+	TenantClusters         map[string]string `json:"tenant-clusters,omitempty"`
+	TenantBuckets          map[string]string `json:"tenant-buckets,omitempty"`
+	Fanout                 map[string]string `json:"fanout,omitempty"`
+	FanoutModifiers        map[string]string `json:"fanout-modifiers,omitempty"`
+	FanoutOutputs          map[string]string `json:"fanout-outputs,omitempty"`
 	Clean                  bool              `json:"clean,omitempty"`
+	MigrateBootstrap       bool              `json:"migrate-bootstrap,omitempty"`
+	VerifyCommit           bool              `json:"verify-commit,omitempty"`
+	Consolidate            bool              `json:"consolidate,omitempty"`
+	EmitPresets            bool              `json:"emit-presets,omitempty"`
+	NoReporter             bool              `json:"no-reporter,omitempty"`
+	SkipReport             bool              `json:"skip-report,omitempty"`
+	Optional               bool              `json:"optional,omitempty"`
+	SkipOptional           bool              `json:"skip-optional,omitempty"`
+	AlwaysRun              string            `json:"always-run,omitempty"`
+	RunIfChangedPrefix     string            `json:"run-if-changed-prefix,omitempty"`
+	CronJitter             int               `json:"cron-jitter,omitempty"`
+	IntervalScale          float64           `json:"interval-scale,omitempty"`
+	IntervalToCron         bool              `json:"interval-to-cron,omitempty"`
+	PostsubmitPeriodics    []string          `json:"postsubmit-periodics,omitempty"`
+	PostsubmitPeriodicCron string            `json:"postsubmit-periodic-cron,omitempty"`
+	Reverse                bool              `json:"reverse,omitempty"`
 	DryRun                 bool              `json:"dry-run,omitempty"`
 	Refs                   bool              `json:"refs,omitempty"`
 	Resolve                bool              `json:"resolve,omitempty"`
 	SSHClone               bool              `json:"ssh-clone,omitempty"`
+	SSHHostFingerprints    []string          `json:"ssh-host-fingerprints,omitempty"`
+	SkipCloning            bool              `json:"skip-cloning,omitempty"`
+	CensorSecrets          bool              `json:"censor-secrets,omitempty"`
+	ForceDecorate          bool              `json:"force-decorate,omitempty"`
+	SkipSubmodules         bool              `json:"skip-submodules,omitempty"`
+	CloneDepth             int               `json:"clone-depth,omitempty"`
 	OverrideSelector       bool              `json:"override-selector,omitempty"`
+	ResourcesIfUnset       bool              `json:"resources-if-unset,omitempty"`
 	SupportGerritReporting bool              `json:"support-gerrit-reporting,omitempty"`
 	AllowLongJobNames      bool              `json:"allow-long-job-names,omitempty"`
+	RewriteTriggers        bool              `json:"rewrite-triggers,omitempty"`
+	StrictMapping          bool              `json:"strict-mapping,omitempty"`
+	Strict                 bool              `json:"strict,omitempty"`
+	FollowSymlinks         bool              `json:"follow-symlinks,omitempty"`
+	CopyUnmapped           bool              `json:"copy-unmapped,omitempty"`
 	Verbose                bool              `json:"verbose,omitempty"`
 }
 
+// command is a genjobs subcommand.
+type command struct {
+	// flags registers the command-line flags specific to the subcommand.
+	flags func(o *options)
+	// run executes the subcommand.
+	run func(o options) error
+	// standalone subcommands do not require the generation options (e.g. --mapping).
+	standalone bool
+}
+
+// commands are the available genjobs subcommands keyed by name.
+var commands = map[string]command{}
+
 // options are the available command-line flags.
 type options struct {
+	Command           string
+	Config            string
 	Configs           []string
 	Global            string
+	Interval          time.Duration
+	HealthPort        int
+	ValidateCluster   bool
+	Kubeconfig        string
+	ValidateNamespace string
+	CheckQuota        string
+	QuotaConcurrency  int
+	FailFast          bool
+	KeepGoing         bool
+	HistoryDB         string
+	bump              bumpOptions
+	drift             driftOptions
+	history           historyOptions
+	verifyMapping     verifyMappingOptions
+	plugins           pluginsOptions
+	gitOps            gitOpsOptions
+	serve             serveOptions
 	EnvDenylistSet    sets.String
 	VolumeDenylistSet sets.String
 	JobAllowlistSet   sets.String
 	JobDenylistSet    sets.String
 	RepoAllowlistSet  sets.String
 	RepoDenylistSet   sets.String
+	MappingExclusions sets.String
+	SecretNames       map[string]string
+	TolerationList    []v1.Toleration
+	VolumesPreset     *config.Preset
 	JobTypeSet        sets.String
+	tenant            string
+	repoSA            string
+	runtimeClassSel   labels.Selector
+	inputs            []string
+	annotationSel     labels.Selector
+	errs              *runErrors
+	header            string
 	transform
 }
 
+// mapValue is a stringToString flag value that also accepts entries without a value of a special form
+// (e.g. !org/repo mapping exclusions).
+type mapValue struct {
+	value   *map[string]string
+	changed bool
+	// bare returns whether an entry without a value is accepted.
+	bare func(entry string) bool
+	// bareForm describes the accepted entries without a value in errors.
+	bareForm string
+}
+
+// newMappingValue returns a mapValue storing the mapping in p, accepting !org/repo exclusions.
+func newMappingValue(p *map[string]string) *mapValue {
+	*p = map[string]string{}
+	return &mapValue{
+		value:    p,
+		bare:     func(entry string) bool { return strings.HasPrefix(entry, exclusionPrefix) },
+		bareForm: exclusionPrefix + "org/repo",
+	}
+}
+
+// newEnvValue returns a mapValue storing the env in p, accepting NAME- removals.
+func newEnvValue(p *map[string]string) *mapValue {
+	*p = map[string]string{}
+	return &mapValue{
+		value:    p,
+		bare:     func(entry string) bool { return strings.HasSuffix(entry, envRemovalSuffix) },
+		bareForm: "NAME" + envRemovalSuffix,
+	}
+}
+
+// Set parses the comma-separated entries, replacing the default on first use.
+func (m *mapValue) Set(val string) error {
+	entries, err := csv.NewReader(strings.NewReader(val)).Read()
+	if err != nil {
+		return err
+	}
+
+	if !m.changed {
+		*m.value = make(map[string]string, len(entries))
+		m.changed = true
+	}
+
+	for _, entry := range entries {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) == 2 {
+			(*m.value)[kv[0]] = kv[1]
+		} else if m.bare(entry) {
+			(*m.value)[entry] = ""
+		} else {
+			return fmt.Errorf("%s must be formatted as key=value or %s", entry, m.bareForm)
+		}
+	}
+
+	return nil
+}
+
+// Type returns the type name of the flag value.
+func (m *mapValue) Type() string {
+	return "stringToString"
+}
+
+// String returns the entries in lexical order.
+func (m *mapValue) String() string {
+	entries := make([]string, 0, len(*m.value))
+	for k, v := range *m.value {
+		entries = append(entries, k+"="+v)
+	}
+	sort.Strings(entries)
+
+	return "[" + strings.Join(entries, ",") + "]"
+}
+
 // parseOpts parses the command-line flags.
 func (o *options) parseOpts() {
+	flag.StringVar(&o.AlertRules, "alert-rules", "", "Path to write a PrometheusRule manifest alerting on failed and stale generated postsubmit and periodic job(s) to.")
+	flag.StringVar(&o.AlertStaleWindow, "alert-stale-window", defaultAlertStaleWindow, "Window without a successful run after which a generated periodic job is alerted on as stale.")
+	flag.StringToStringVar(&o.AlertLabels, "alert-labels", map[string]string{}, "Labels to apply to the generated alert(s) (e.g. severity=warning).")
+	flag.StringVar(&o.AlertSeverity, "alert-severity", "", "Alert severity to annotate the job(s) with (e.g. critical, warning).")
+	flag.StringVar(&o.AlertEmail, "alert-email", "", "TestGrid alert email address(es) to annotate the job(s) with.")
+	flag.IntVar(&o.NumFailuresToAlert, "num-failures-to-alert", 0, "Number of consecutive failures before TestGrid alerts on the job(s).")
+	flag.IntVar(&o.AlertStaleResultsHours, "alert-stale-results-hours", 0, "Number of hours without results before TestGrid alerts on the job(s).")
 	flag.StringVar(&o.Bucket, "bucket", "", "GCS bucket name to upload logs and build artifacts to.")
+	flag.StringVar(&o.GCSCredentialsSecret, "gcs-credentials-secret", "", "GKE cluster secret containing the GCS service account credentials used to upload logs and build artifacts.")
+	flag.StringVar(&o.DefaultServiceAccount, "default-service-account-name", "", "Kubernetes service account of the job pod(s) without one, used to upload logs and build artifacts with workload identity.")
+	flag.StringToStringVar(&o.BucketMap, "bucket-map", map[string]string{}, "GCS bucket name to upload logs and build artifacts of private Github organization(s) or org/repo(s) to, falling back to --bucket. Repo entries take precedence over org entries.")
+	flag.StringVar(&o.CacheVolume, "cache-volume", "", "Build cache volume to inject into the job(s): (e.g. emptyDir, emptyDir:10Gi, pvc:claim-name).")
+	flag.StringVar(&o.CacheMountPath, "cache-mount-path", defaultCacheMountPath, "Path to mount the build cache volume at.")
+	flag.StringSliceVar(&o.CacheJobs, "cache-jobs", []string{}, "Job name pattern(s) to inject the build cache volume into. Defaults to all job(s).")
+	flag.StringVar(&o.RuntimeClass, "runtime-class", "", "Kubernetes runtime class (e.g. gvisor) to assign to the job(s).")
+	flag.StringVar(&o.AnnotationSelector, "annotation-selector", "", "Selector of the job(s) to generate by their annotations (e.g. private.istio.io/generate!=false).")
+	flag.StringVar(&o.RuntimeClassSelector, "runtime-class-selector", "", "Label selector of the job(s) to assign the runtime class to (e.g. preset-untrusted=true). Defaults to all job(s).")
+	flag.StringToStringVar(&o.CacheEnv, "cache-env", map[string]string{}, "Env(s) to set to a directory of the build cache volume (e.g. GOCACHE=go-build).")
 	flag.StringVar(&o.Cluster, "cluster", "", "GCP cluster to run the job(s) in.")
+	flag.StringToStringVar(&o.ClusterMap, "cluster-map", map[string]string{}, "GCP cluster to run the job(s) of public Github organization(s) or org/repo(s) in, falling back to --cluster. Repo entries take precedence over org entries.")
+	flag.StringVar(&o.ServiceAccount, "service-account", "", "Kubernetes service account to run the job(s) as.")
+	flag.StringToStringVar(&o.ServiceAccountMap, "service-account-map", map[string]string{}, "Kubernetes service account to run the job(s) of public Github organization(s) or org/repo(s) as. Repo entries take precedence over org entries.")
+	flag.StringToStringVar(&o.ClusterServiceAccounts, "cluster-service-accounts", map[string]string{}, "Kubernetes service account to run the job(s) of each cluster as, falling back to --service-account.")
+	flag.StringVar(&o.PriorityClass, "priority-class", "", "Kubernetes priority class to assign to the job(s).")
+	flag.StringSliceVar(&o.PriorityClassJobs, "priority-class-jobs", []string{}, "Job name pattern(s) to assign the priority class to. Defaults to all job(s).")
+	flag.StringVar(&o.ContextsOutput, "contexts-output", "", "Path to write the required status contexts of the private repositories to as json or yaml.")
+	flag.StringVar(&o.Manifest, "manifest", "", "Path to write a json manifest of the generated file(s), the job(s) they contain, and their source file(s) to.")
 	flag.StringVar(&o.Channel, "channel", "", "Slack channel to report job status notifications to.")
+	flag.StringToStringVar(&o.ChannelMap, "channel-map", map[string]string{}, "Slack channel to report job status notifications of public Github organization(s) or org/repo(s) to, falling back to --channel. Repo entries take precedence over org entries.")
+	flag.StringVar(&o.PubSubProject, "pubsub-project", "", "GCP project of the PubSub topic to report job status notifications to.")
+	flag.StringVar(&o.PubSubTopic, "pubsub-topic", "", "PubSub topic to report job status notifications to.")
+	flag.StringSliceVar(&o.SlackJobStates, "slack-job-states", []string{}, "Job state(s) to report to Slack (e.g. failure, error).")
+	flag.StringVar(&o.SlackReportTemplate, "slack-report-template", "", "Go template of the message reported to Slack.")
+	flag.StringVar(&o.DeckURL, "deck-url", "", "URL of the private Deck to link the job(s) reported to Slack to, unless --slack-report-template is set.")
 	flag.StringVar(&o.Global, "global", "", "Path to file containing global defaults configuration.")
+	flag.DurationVar(&o.Interval, "interval", 0, "Interval to periodically regenerate job(s) at, serving health endpoints in between (e.g. 1h). Runs once if unset.")
+	flag.IntVar(&o.HealthPort, "health-port", defaultHealthPort, "Port to serve health and readiness endpoints on when running with --interval.")
+	flag.BoolVar(&o.ValidateCluster, "validate-cluster", false, "Validate the generated job(s) with a server-side dry-run of a representative pod against their build cluster(s) before writing.")
+	flag.StringVar(&o.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig with a context per build cluster used by --validate-cluster and --check-quota. Defaults to the standard kubeconfig loading rules.")
+	flag.StringVar(&o.ValidateNamespace, "validate-namespace", defaultPodNamespace, "Namespace of the build cluster(s) to dry-run the representative pod(s) in for --validate-cluster and to read the resource quotas of for --check-quota.")
+	flag.StringVar(&o.CheckQuota, "check-quota", "", "Check the expected resource usage of the generated job(s) against the resource quotas of their build cluster(s) and warn or fail when they cannot fit: (e.g. warn, fail).")
+	flag.IntVar(&o.QuotaConcurrency, "quota-concurrency", 1, "Expected number of concurrent runs of each presubmit and postsubmit for --check-quota.")
+	flag.BoolVar(&o.FailFast, "fail-fast", false, "Abort generation on the first transformation or write error, exiting non-zero.")
+	flag.BoolVar(&o.KeepGoing, "keep-going", false, "Finish generation despite transformation or write errors, exiting non-zero with a summary of the errors.")
+	flag.StringVar(&o.HistoryDB, "history-db", "", "Path to the history database to record each generation run and its job change(s) to, and to query with the history and blame subcommands.")
+	flag.StringVar(&o.GitHost, "git-host", gitHost, "Git host of the private repositories (e.g. a GitHub Enterprise host). Mappings may override it per org with a host prefix (e.g. istio=ghe.corp.com/istio-private).")
 	flag.StringVar(&o.SSHKeySecret, "ssh-key-secret", "", "GKE cluster secrets containing the Github ssh private key.")
+	flag.StringVar(&o.OauthTokenSecret, "oauth-token-secret", "", "GKE cluster secret and key containing the GitHub oauth token used to clone in place of ssh key secrets, in the form name:key.")
+	flag.StringVar(&o.GitHubAppID, "github-app-id", "", "ID of the GitHub App used to clone in place of ssh key secrets.")
+	flag.StringVar(&o.GitHubAppPrivateKey, "github-app-private-key-secret", "", "GKE cluster secret and key containing the private key of the GitHub App used to clone, in the form name:key.")
 	flag.StringVar(&o.Modifier, "modifier", defaultModifier, "Modifier to apply to generated file and job name(s).")
-	flag.StringVarP(&o.Input, "input", "i", ".", "Input file or directory containing job(s) to convert.")
-	flag.StringVarP(&o.Output, "output", "o", ".", "Output file or directory to write generated job(s).")
+	flag.StringToStringVar(&o.ModifierMap, "modifier-map", map[string]string{}, "Modifier to apply to generated job name(s) per public Github organization, falling back to --modifier.")
+	flag.StringSliceVarP(&o.inputs, "input", "i", []string{"."}, "Input file(s), directory(ies), glob pattern(s) (e.g. config/jobs/**/istio.*.yaml), or HTTP(S) URL(s) containing job(s) to convert, or - to read from stdin. Job(s) of all inputs are merged into the same output.")
+	flag.StringVar(&o.Format, "format", formatYAML, "Format of the generated output file(s) (e.g. yaml, json). Json output file(s) have no header.")
+	flag.StringVar(&o.InputRepo, "input-repo", "", "Public org/repo or git remote URL to check out the --input path(s) from instead of the local filesystem.")
+	flag.StringVar(&o.InputRef, "input-ref", defaultInputRef, "Branch, tag, or commit SHA of --input-repo to check out.")
+	flag.StringSliceVar(&o.Exclude, "exclude", []string{}, "Glob pattern(s) of the input file(s) to exclude, relative to their input (e.g. **/experimental/**).")
+	flag.StringVarP(&o.Output, "output", "o", ".", "Output file or directory to write generated job(s), or - to write to stdout.")
+	flag.StringVar(&o.RemoteCache, "remote-cache", "", "Remote build cache endpoint to inject into bazel and go build job(s) (e.g. grpcs://cache.example.com:443).")
+	flag.StringVar(&o.RemoteCacheSecret, "remote-cache-secret", "", "Secret containing the remote build cache credentials in the form name[:key].")
+	flag.StringSliceVar(&o.RemoteCacheLabels, "remote-cache-labels", defaultRemoteCacheLabels, "Label(s) identifying bazel and go build job(s) to inject the remote build cache into.")
+	flag.StringVar(&o.SecretsOutput, "secrets-output", "", "Path to write template secret manifests for the secret(s) referenced by the generated job(s) to.")
+	flag.StringVar(&o.SecretsKind, "secrets-kind", sealedSecretKind, "Kind of the template secret manifests: (e.g. SealedSecret, ExternalSecret).")
+	flag.StringVar(&o.SecretsNamespace, "secrets-namespace", defaultPodNamespace, "Namespace of the template secret manifests.")
+	flag.StringVar(&o.Signature, "signature", "", "Path to a detached OpenPGP signature of the input file, or of --signed-manifest, to verify before generating.")
+	flag.StringVar(&o.SignedManifest, "signed-manifest", "", "Path to a sha256sum manifest of the input file(s) covered by --signature.")
+	flag.StringVar(&o.TrustedKeys, "trusted-keys", "", "Path to the OpenPGP public key(s) trusted to sign the input.")
+	flag.BoolVar(&o.VerifyCommit, "verify-commit", false, "Verify the input tree is a clean checkout of a commit signed by --trusted-keys before generating.")
 	flag.StringVarP(&o.Sort, "sort", "s", "", "Sort the job(s) by name: (e.g. (asc)ending, (desc)ending).")
+	flag.StringVar(&o.SourceSHA, "source-sha", "", "Commit SHA of the input source tree to record as provenance in the generated file(s).")
+	flag.StringVar(&o.HeaderFile, "header-file", "", "Path to a file with the header to write at the top of the generated file(s) instead of the autogenerated header. Lines are written as comments.")
+	flag.BoolVar(&o.Provenance, "provenance", false, "Record the source file path(s), source git SHA, genjobs version, and flags hash as comments in the header of the generated file(s).")
+	flag.StringVar(&o.TideConfig, "tide-config", "", "Path to write a Tide configuration fragment for the private repositories with generated presubmit(s) to.")
+	flag.StringVar(&o.BranchProtectionConfig, "branch-protection-config", "", "Path to write a branch protection configuration fragment requiring the generated presubmit context(s) of the private repositories to.")
+	flag.StringVar(&o.TideMergeMethod, "tide-merge-method", "", "Tide merge method for the private repositories: (e.g. merge, squash, rebase).")
+	flag.IntVar(&o.MaxConcurrency, "max-concurrency", 0, "Maximum number of concurrent run(s) of each generated presubmit and postsubmit job, capping existing max_concurrency.")
+	flag.Float64Var(&o.ConcurrencyScale, "concurrency-scale", 0, "Factor to scale the max_concurrency of generated presubmit and postsubmit job(s) by, rounded up (e.g. 0.5).")
+	flag.IntVar(&o.MaxJobsPerFile, "max-jobs-per-file", 0, "Maximum number of job(s) per output file before splitting into numbered shards.")
+	flag.BoolVar(&o.SplitByType, "split-by-type", false, "Write the presubmits, postsubmits, and periodics of each output file to separate files (e.g. istio.presubmits.yaml).")
+	flag.BoolVar(&o.SplitByJob, "split-by-job", false, "Write each job to its own file named after the job, in a directory named after the output file (e.g. istio/istio_unit_tests.yaml).")
 	flag.StringSliceVar(&o.Branches, "branches", []string{}, "Branch(es) to generate job(s) for.")
+	flag.StringSliceVar(&o.SkipBranches, "skip-branches", []string{}, "Branch(es) to not generate job(s) for. Job(s) running on any of them are excluded.")
 	flag.StringSliceVar(&o.BranchesOut, "branches-out", []string{}, "Override output branch(es) for generated presubmit and postsubmit job(s).")
 	flag.StringVar(&o.RefBranchOut, "ref-branch-out", "", "Override ref branch for generated periodici job(s).")
+	flag.StringVar(&o.PathAliasMode, "path-alias-mode", pathAliasPreserve, "Path alias handling of mapped repos without a --path-alias-map entry: (e.g. preserve, clear, repo).")
+	flag.StringToStringVar(&o.PathAliasMap, "path-alias-map", map[string]string{}, "Mapping between public and private path alias(es) or path alias prefix(es) of mapped repos (e.g. istio.io=private.istio.io).")
+	flag.StringToStringVar(&o.BaseRefMap, "base-ref-map", map[string]string{}, "Base ref to set on the periodic job(s) extra refs of private Github organization(s) or org/repo(s) (e.g. istio-private=private-main), taking precedence over --ref-branch-out. Repo entries take precedence over org entries.")
+	flag.StringToStringVar(&o.BranchMap, "branch-map", map[string]string{}, "Mapping between public and private branch name(s) (e.g. master=main) to apply to branches, skip_branches and ref base_ref(s).")
+	flag.StringVar(&o.Config, configFlag, "", "Path to a yaml file of option(s) keyed by flag name. Options set on the command line take precedence.")
 	flag.StringSliceVar(&o.Configs, "configs", []string{}, "Path to files or directories containing yaml job transforms.")
-	flag.StringSliceVarP(&o.Presets, "presets", "p", []string{}, "Path to file(s) containing additional presets.")
+	flag.StringSliceVarP(&o.Presets, "presets", "p", []string{}, "Path(s) or HTTP(S) URL(s) to file(s) containing additional presets.")
 	flag.StringSliceVar(&o.RerunOrgs, "rerun-orgs", []string{}, "GitHub organizations to authorize job rerun for.")
 	flag.StringSliceVar(&o.RerunUsers, "rerun-users", []string{}, "GitHub user to authorize job rerun for.")
+	flag.StringSliceVar(&o.RerunTeams, "rerun-teams", []string{}, "GitHub teams to authorize job rerun for in the form org/team-slug.")
+	flag.IntSliceVar(&o.RerunTeamIDs, "rerun-team-ids", []int{}, "GitHub team IDs to authorize job rerun for.")
+	flag.StringSliceVar(&o.TideLabels, "tide-labels", defaultTideLabels, "Labels required by the generated Tide query.")
+	flag.StringSliceVar(&o.TideMissingLabels, "tide-missing-labels", defaultTideMissingLabels, "Labels that must be missing for the generated Tide query.")
 	flag.StringToStringVar(&o.Selector, "selector", map[string]string{}, "Node selector(s) to constrain job(s).")
+	flag.StringSliceVar(&o.Tolerations, "tolerations", []string{}, "Toleration(s) to append to the job(s) in the form key[=value]:effect (e.g. dedicated=build:NoSchedule).")
+	flag.StringVar(&o.TolerationsFile, "tolerations-file", "", "Path to a yaml file with a list of toleration(s) to append to the job(s).")
+	flag.StringVar(&o.VolumesFile, "volumes-file", "", "Path to a yaml file with the volumes and volumeMounts to merge into the job(s), replacing existing ones by name.")
 	flag.StringToStringVarP(&o.Labels, "labels", "l", map[string]string{}, "Prow labels to apply to the job(s).")
-	flag.StringToStringVarP(&o.Env, "env", "e", map[string]string{}, "Environment variables to set for the job(s).")
-	flag.StringToStringVarP(&o.OrgMap, "mapping", "m", map[string]string{}, "Mapping between public and private Github organization(s).")
+	flag.StringToStringVar(&o.PodAnnotations, "pod-annotations", map[string]string{}, "Annotations to apply to the pod(s) of the job(s) (e.g. sidecar.istio.io/inject=false).")
+	flag.StringToStringVar(&o.SecurityContext, "security-context", map[string]string{}, "Security context field(s) to set on the pod and container(s) of the job(s): (e.g. runAsUser=1000,runAsNonRoot=true,privileged=false).")
+	flag.VarP(newEnvValue(&o.Env), "env", "e", "Environment variables to set for the job(s). Entries of the form NAME- remove the variable from the job(s).")
+	flag.VarP(newMappingValue(&o.OrgMap), "mapping", "m", "Mapping between public and private Github organization(s) or org/repo(s). Repo mappings take precedence over org mappings. Entries of the form !org/repo exclude a repo from the mapping, and entries of the form ~regex map the orgs or org/repos fully matching the regex.")
 	flag.StringToStringVar(&o.RefOrgMap, "ref-mapping", map[string]string{}, "Mapping between public and private Github organization(s) in refs.")
+	flag.StringToStringVar(&o.BotTokenSecrets, "bot-token-secrets", map[string]string{}, "Mapping between public and private GitHub bot token secret name(s) (e.g. oauth-token, hmac-token).")
+	flag.StringToStringVar(&o.SecretMap, "secret-map", map[string]string{}, "Mapping between public and private Kubernetes secret name(s) referenced by env, volumes, and ssh_key_secrets.")
+	flag.StringToStringVar(&o.Resources, "resources", map[string]string{}, "Resources of the job container(s) in the form (requests|limits).resource=quantity (e.g. requests.cpu=2,limits.memory=8Gi).")
+	flag.Float64Var(&o.ResourceScale, "resource-scale", 0, "Factor to scale the existing cpu and memory requests and limits of the job container(s) by, rounded up (e.g. 0.5).")
+	flag.StringToStringVar(&o.DecorationResources, "decoration-resources", map[string]string{}, "Resources of the decoration container(s) in the form container.(requests|limits).resource=quantity (e.g. sidecar.limits.memory=1Gi).")
+	flag.StringToStringVar(&o.UtilityImages, "utility-images", map[string]string{}, "Pod utility image(s) of the decoration container(s) in the form utility=image (e.g. clonerefs=gcr.io/istio-private/clonerefs:v20200101-abcdef).")
+	flag.StringToStringVar(&o.ActiveDeadlines, "active-deadline", map[string]string{}, "Pod active deadline(s) to set for job(s) matching name pattern(s) (e.g. .*-e2e-.*=3h).")
+	flag.StringVar(&o.TerminationGracePeriod, "termination-grace-period", "", "Pod termination grace period to set for the job(s) (e.g. 15m).")
+	flag.StringToStringVar(&o.Tenants, "tenants", map[string]string{}, "Mapping between public Github organization(s) and the tenant(s) to route their generated job(s) to.")
+	flag.StringToStringVar(&o.TenantOutputs, "tenant-outputs", map[string]string{}, "Output file or directory of each tenant.")
+	flag.StringToStringVar(&o.TenantClusters, "tenant-clusters", map[string]string{}, "GCP cluster to run the job(s) of each tenant in.")
+	flag.StringToStringVar(&o.TenantBuckets, "tenant-buckets", map[string]string{}, "GCS bucket name to upload logs and build artifacts of each tenant to.")
+	flag.StringToStringVar(&o.Fanout, "fanout", map[string]string{}, "Additional target(s) to generate a complete job set for, in the form target=public-org:private-org (e.g. release=istio:istio-release).")
+	flag.StringToStringVar(&o.FanoutModifiers, "fanout-modifiers", map[string]string{}, "Modifier of each fan-out target. Defaults to the target name.")
+	flag.StringToStringVar(&o.FanoutOutputs, "fanout-outputs", map[string]string{}, "Output file or directory of each fan-out target.")
 	flag.StringToStringVarP(&o.Annotations, "annotations", "a", map[string]string{}, "Annotations to apply to the job(s)")
 	flag.StringSliceVar(&o.EnvDenylist, "env-denylist", []string{}, "Env(s) to denylist in generation process.")
 	flag.StringSliceVar(&o.VolumeDenylist, "volume-denylist", []string{}, "Volume(s) to denylist in generation process.")
+	flag.StringSliceVar(&o.RemoveLabels, "remove-labels", []string{}, "Label key(s) or key pattern(s) to remove from the job(s) once presets are resolved.")
 	flag.StringSliceVar(&o.JobAllowlist, "job-allowlist", []string{}, "Job(s) to allowlist in generation process.")
 	flag.StringSliceVar(&o.JobDenylist, "job-denylist", []string{}, "Job(s) to denylist in generation process.")
 	flag.StringSliceVar(&o.RepoAllowlist, "repo-allowlist", []string{}, "Repositories to allowlist in generation process.")
 	flag.StringSliceVar(&o.RepoDenylist, "repo-denylist", []string{}, "Repositories to denylist in generation process.")
 	flag.StringSliceVarP(&o.JobType, "job-type", "t", defaultJobTypes, "Job type(s) to process (e.g. presubmit, postsubmit. periodic).")
 	flag.BoolVar(&o.Clean, "clean", false, "Clean output files before job(s) generation.")
+	flag.BoolVar(&o.Consolidate, "consolidate", false, "Consolidate generated job(s) into one output file per org/repo regardless of input layout.")
 	flag.BoolVar(&o.DryRun, "dry-run", false, "Run in dry run mode.")
+	flag.BoolVar(&o.EmitPresets, "emit-presets", false, "Translate and emit the presets of the input file(s) into the generated output.")
+	flag.BoolVar(&o.Reverse, "reverse", false, "Reverse the mapping to regenerate public job(s) from private job(s), removing the modifier and private clone URI(s).")
+	flag.BoolVar(&o.NoReporter, "no-reporter", false, "Remove the reporter configuration (e.g. Slack) from the generated job(s).")
+	flag.BoolVar(&o.SkipReport, "skip-report", false, "Skip reporting the status of the generated presubmit job(s) to GitHub.")
+	flag.BoolVar(&o.SkipOptional, "skip-optional", false, "Exclude the optional presubmit job(s) from generation.")
+	flag.BoolVar(&o.Optional, "optional", false, "Make the generated presubmit job(s) optional so they do not block merges.")
+	flag.StringVar(&o.AlwaysRun, "always-run", "", "Override the always_run of the generated presubmit job(s): (e.g. true, false). Enabling it clears run_if_changed.")
+	flag.Float64Var(&o.IntervalScale, "interval-scale", 0, "Factor to stretch the interval or cron frequency of the periodic job(s) by (e.g. 2 to run half as often).")
+	flag.StringSliceVar(&o.PostsubmitPeriodics, "postsubmit-periodics", []string{}, "Name pattern(s) of the postsubmit job(s) to additionally mirror as periodic job(s) running against their branch.")
+	flag.StringVar(&o.PostsubmitPeriodicCron, "postsubmit-periodic-cron", defaultPostsubmitPeriodicCron, "Cron schedule of the periodic job(s) mirrored from postsubmit job(s) by --postsubmit-periodics.")
+	flag.BoolVar(&o.IntervalToCron, "interval-to-cron", false, "Convert the interval of the periodic job(s) to an equivalent cron schedule, subject to --cron-jitter.")
+	flag.IntVar(&o.CronJitter, "cron-jitter", 0, "Maximum number of minutes to offset the cron minute field of the periodic job(s) by, deterministically seeded by the job name.")
+	flag.StringVar(&o.RunIfChangedPrefix, "run-if-changed-prefix", "", "Path prefix of the public repository within the private repository to insert after the ^ anchor(s) of the run_if_changed of the job(s) (e.g. third_party/istio/).")
 	flag.BoolVar(&o.Refs, "refs", false, "Apply translation to all extra refs regardless of repo.")
 	flag.BoolVar(&o.Resolve, "resolve", false, "Resolve and expand values for presets in generated job(s).")
 	flag.BoolVar(&o.SSHClone, "ssh-clone", false, "Enable a clone of the git repository over ssh.")
+	flag.StringSliceVar(&o.SSHHostFingerprints, "ssh-host-fingerprints", []string{}, "Known ssh host fingerprint(s) of the git host to verify when cloning over ssh.")
+	flag.BoolVar(&o.ForceDecorate, "force-decorate", false, "Enable decoration of the job(s) that are not decorated.")
+	flag.BoolVar(&o.SkipCloning, "skip-cloning", false, "Skip cloning the git repositories of the job(s).")
+	flag.BoolVar(&o.CensorSecrets, "censor-secrets", false, "Censor the values of the secrets mounted in the job(s) from their uploaded logs and artifacts.")
+	flag.BoolVar(&o.SkipSubmodules, "skip-submodules", false, "Skip cloning the git submodules of the job(s).")
+	flag.IntVar(&o.CloneDepth, "clone-depth", 0, "Depth of a shallow clone of the git repositories of the job(s).")
+	flag.BoolVar(&o.ResourcesIfUnset, "resources-if-unset", false, "Only apply --resources to job container(s) without any resource requests or limits.")
 	flag.BoolVar(&o.OverrideSelector, "override-selector", false, "The existing node selector will be overridden rather than added to.")
 	flag.BoolVar(&o.SupportGerritReporting, "support-gerrit-reporting", false, "Generate Prow jobs that supports Gerrit reporting.")
 	flag.BoolVar(&o.AllowLongJobNames, "allow-long-job-names", false, "Allow job names that have more than 63 characters.")
+	flag.BoolVar(&o.RewriteTriggers, "rewrite-triggers", false, "Rewrite the trigger, rerun_command, and context of the presubmit job(s) to match their modified name.")
+	flag.BoolVar(&o.CopyUnmapped, "copy-unmapped", false, "Copy the job(s) of org/repo(s) that are not in the mapping to the output untouched instead of dropping them.")
+	flag.BoolVar(&o.StrictMapping, "strict-mapping", false, "Fail generation when job(s) of an org/repo that is not in the mapping would be dropped.")
+	flag.BoolVar(&o.FollowSymlinks, "follow-symlinks", false, "Follow symlinked directories when walking the input path(s). Symlink cycles are skipped.")
+	flag.BoolVar(&o.Strict, "strict", false, "Fail generation with a list of the offending file(s) when input or presets file(s) are unreadable or invalid.")
+	flag.BoolVar(&o.MigrateBootstrap, "migrate-bootstrap", false, "Convert legacy bootstrap job(s) to decorated pod-utilities job(s).")
 	flag.BoolVar(&o.Verbose, "verbose", false, "Enable verbose output.")
 
+	if cmd, ok := commands[o.Command]; ok && cmd.flags != nil {
+		cmd.flags(o)
+	}
+
 	flag.Parse()
 
+	if o.Config != "" {
+		if err := applyConfigFile(o, o.Config); err != nil {
+			util.PrintErrAndExit(err)
+		}
+	}
+
+	o.Input = strings.Join(o.inputs, inputSeparator)
+
 	o.EnvDenylistSet = sets.NewString(o.EnvDenylist...)
 	o.VolumeDenylistSet = sets.NewString(o.VolumeDenylist...)
 	o.JobAllowlistSet = sets.NewString(o.JobAllowlist...)
@@ -172,8 +618,8 @@ func (o *options) parseOpts() {
 	o.JobTypeSet = sets.NewString(o.JobType...)
 }
 
-// parseConfiguration parses the yaml configuration transforms.
-func (o *options) parseConfiguration() []options {
+// parseConfiguration parses the yaml configuration transforms, returning an error for an invalid transform.
+func (o *options) parseConfiguration() ([]options, error) {
 	var optsList []options
 	var global configuration
 
@@ -208,26 +654,28 @@ func (o *options) parseConfiguration() []options {
 				return nil
 			}
 
-			for _, t := range c.Transforms {
+			for i, t := range c.Transforms {
+				if t.Name == "" {
+					t.Name = fmt.Sprintf("%s#%d", filepath.Base(path), i)
+				}
 				if len(t.JobType) == 0 {
 					t.JobType = defaultJobTypes
 				}
 
 				applyDefaultTransforms(&t, &c.Defaults, &local.Defaults, &global.Defaults)
 
-				oc := options{
-					EnvDenylistSet:    sets.NewString(t.EnvDenylist...),
-					VolumeDenylistSet: sets.NewString(t.VolumeDenylist...),
-					JobAllowlistSet:   sets.NewString(t.JobAllowlist...),
-					JobDenylistSet:    sets.NewString(t.JobDenylist...),
-					RepoAllowlistSet:  sets.NewString(t.RepoAllowlist...),
-					RepoDenylistSet:   sets.NewString(t.RepoDenylist...),
-					JobTypeSet:        sets.NewString(t.JobType...),
-					transform:         t,
-				}
+				oc := newTransformOptions(t)
+				oc.ValidateCluster = o.ValidateCluster
+				oc.Kubeconfig = o.Kubeconfig
+				oc.ValidateNamespace = o.ValidateNamespace
+				oc.CheckQuota = o.CheckQuota
+				oc.QuotaConcurrency = o.QuotaConcurrency
+				oc.FailFast = o.FailFast
+				oc.KeepGoing = o.KeepGoing
+				oc.HistoryDB = o.HistoryDB
 
 				if err := oc.validateOpts(); err != nil {
-					util.PrintErrAndExit(err)
+					return err
 				}
 
 				optsList = append(optsList, oc)
@@ -235,11 +683,25 @@ func (o *options) parseConfiguration() []options {
 
 			return nil
 		}); err != nil {
-			util.PrintErr(err.Error())
+			return nil, err
 		}
 	}
 
-	return optsList
+	return optsList, nil
+}
+
+// newTransformOptions creates the options for a single configuration transform.
+func newTransformOptions(t transform) options {
+	return options{
+		EnvDenylistSet:    sets.NewString(t.EnvDenylist...),
+		VolumeDenylistSet: sets.NewString(t.VolumeDenylist...),
+		JobAllowlistSet:   sets.NewString(t.JobAllowlist...),
+		JobDenylistSet:    sets.NewString(t.JobDenylist...),
+		RepoAllowlistSet:  sets.NewString(t.RepoAllowlist...),
+		RepoDenylistSet:   sets.NewString(t.RepoDenylist...),
+		JobTypeSet:        sets.NewString(t.JobType...),
+		transform:         t,
+	}
 }
 
 // validateOpts validates the command-line flags.
@@ -266,883 +728,3299 @@ func (o *options) validateOpts() error {
 		}
 	}
 
-	if len(o.Configs) == 0 {
-		if len(o.OrgMap) == 0 {
-			return &util.ExitError{Message: "-m, --mapping option is required.", Code: 1}
+	if o.Signature != "" || o.VerifyCommit {
+		if o.TrustedKeys == "" {
+			return &util.ExitError{Message: "--trusted-keys option is required to verify the input.", Code: 1}
+		}
+		for name, p := range map[string]*string{"trusted-keys": &o.TrustedKeys, "signature": &o.Signature, "signed-manifest": &o.SignedManifest} {
+			if *p == "" {
+				continue
+			}
+			if *p, err = filepath.Abs(*p); err != nil || !util.IsFile(*p) {
+				return &util.ExitError{Message: fmt.Sprintf("--%s option path is not a file: %v.", name, *p), Code: 1}
+			}
 		}
+	}
+
+	var removedEnvs sets.String
+	if o.Env, removedEnvs, err = splitEnvRemovals(o.Env); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("-e, --env option invalid: %v.", err), Code: 1}
+	}
+	o.EnvDenylistSet = o.EnvDenylistSet.Union(removedEnvs)
+
+	if o.SecretNames, err = mergeSecretNames(o.BotTokenSecrets, o.SecretMap); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("--secret-map option invalid: %v.", err), Code: 1}
+	}
+
+	if o.OrgMap, o.MappingExclusions, err = splitMappingExclusions(o.OrgMap); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("-m, --mapping option invalid: %v.", err), Code: 1}
+	}
+
+	if o.PathAliasMode != "" && !sets.NewString(pathAliasModes...).Has(o.PathAliasMode) {
+		return &util.ExitError{Message: fmt.Sprintf("--path-alias-mode option invalid: %v.", o.PathAliasMode), Code: 1}
+	}
 
-		if o.Input, err = filepath.Abs(o.Input); err != nil {
-			return &util.ExitError{Message: fmt.Sprintf("-i, --input option invalid: %v.", o.Input), Code: 1}
+	if o.SecretsKind != "" && !sets.NewString(secretKinds...).Has(o.SecretsKind) {
+		return &util.ExitError{Message: fmt.Sprintf("--secrets-kind option invalid: %v.", o.SecretsKind), Code: 1}
+	}
+
+	if o.SignedManifest != "" && o.Signature == "" {
+		return &util.ExitError{Message: fmt.Sprintf("--signed-manifest option requires --signature: %v.", o.SignedManifest), Code: 1}
+	}
+
+	if o.CheckQuota != "" && !sets.NewString(quotaModes...).Has(o.CheckQuota) {
+		return &util.ExitError{Message: fmt.Sprintf("--check-quota option invalid: %v.", o.CheckQuota), Code: 1}
+	}
+
+	if o.CheckQuota != "" && o.QuotaConcurrency < 1 {
+		return &util.ExitError{Message: fmt.Sprintf("--quota-concurrency option must be positive: %d.", o.QuotaConcurrency), Code: 1}
+	}
+
+	if o.StrictMapping && o.CopyUnmapped {
+		return &util.ExitError{Message: "--strict-mapping and --copy-unmapped options are mutually exclusive.", Code: 1}
+	}
+
+	if o.FailFast && o.KeepGoing {
+		return &util.ExitError{Message: "--fail-fast and --keep-going options are mutually exclusive.", Code: 1}
+	}
+
+	if o.HistoryDB != "" {
+		if o.HistoryDB, err = filepath.Abs(o.HistoryDB); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--history-db option invalid: %v.", o.HistoryDB), Code: 1}
 		}
+	}
 
-		if o.Output, err = filepath.Abs(o.Output); err != nil {
-			return &util.ExitError{Message: fmt.Sprintf("-o, --output option invalid: %v.", o.Output), Code: 1}
+	if o.Kubeconfig != "" {
+		if !o.ValidateCluster && o.CheckQuota == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--kubeconfig option requires --validate-cluster or --check-quota: %v.", o.Kubeconfig), Code: 1}
+		} else if !util.Exists(o.Kubeconfig) {
+			return &util.ExitError{Message: fmt.Sprintf("--kubeconfig option path does not exist: %v.", o.Kubeconfig), Code: 1}
 		}
+	}
 
-		for i, c := range o.Presets {
-			if o.Presets[i], err = filepath.Abs(c); err != nil {
-				return &util.ExitError{Message: fmt.Sprintf("-p, --preset option invalid: %v.", o.Presets[i]), Code: 1}
-			} else if !util.Exists(o.Presets[i]) {
-				return &util.ExitError{Message: fmt.Sprintf("-p, --preset option path does not exist: %v.", o.Presets[i]), Code: 1}
-			} else if util.IsFile(o.Presets[i]) && !util.HasExtension(o.Presets[i], yamlExt) {
-				return &util.ExitError{Message: fmt.Sprintf("-p, --preset option path is not a yaml file: %v.", o.Presets[i]), Code: 1}
-			}
+	if o.AlertStaleWindow != "" {
+		if d, err := time.ParseDuration(o.AlertStaleWindow); err != nil || d < time.Minute {
+			return &util.ExitError{Message: fmt.Sprintf("--alert-stale-window option must be a duration of at least a minute: %v.", o.AlertStaleWindow), Code: 1}
 		}
 	}
 
-	return nil
-}
+	if o.Interval < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--interval option must not be negative: %v.", o.Interval), Code: 1}
+	}
 
-// applyDefaultTransforms defaults transform struct from left to right with decreasing precedence.
-func applyDefaultTransforms(dst *transform, srcs ...*transform) {
-	for _, src := range srcs {
-		if dst.Annotations == nil {
-			dst.Annotations = src.Annotations
+	if o.NoReporter && o.Channel != "" {
+		return &util.ExitError{Message: fmt.Sprintf("--no-reporter option cannot be used with --channel: %v.", o.Channel), Code: 1}
+	}
+
+	for k, v := range o.ActiveDeadlines {
+		if _, err := regexp.Compile(k); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--active-deadline option pattern invalid: %v.", k), Code: 1}
 		}
-		if dst.Bucket == "" {
-			dst.Bucket = src.Bucket
+		if d, err := time.ParseDuration(v); err != nil || d < time.Second {
+			return &util.ExitError{Message: fmt.Sprintf("--active-deadline option duration invalid: %v.", v), Code: 1}
 		}
-		if dst.Cluster == "" {
-			dst.Cluster = src.Cluster
+	}
+
+	if o.TerminationGracePeriod != "" {
+		if d, err := time.ParseDuration(o.TerminationGracePeriod); err != nil || d < 0 {
+			return &util.ExitError{Message: fmt.Sprintf("--termination-grace-period option duration invalid: %v.", o.TerminationGracePeriod), Code: 1}
 		}
-		if dst.Channel == "" {
-			dst.Channel = src.Channel
+	}
+
+	if o.CacheVolume != "" {
+		if _, err := parseCacheVolume(o.CacheVolume); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--cache-volume option invalid: %v: %v.", o.CacheVolume, err), Code: 1}
 		}
-		if dst.SSHKeySecret == "" {
-			dst.SSHKeySecret = src.SSHKeySecret
+		if !path.IsAbs(o.CacheMountPath) {
+			return &util.ExitError{Message: fmt.Sprintf("--cache-mount-path option must be absolute: %v.", o.CacheMountPath), Code: 1}
 		}
-		if dst.Modifier == "" {
-			dst.Modifier = src.Modifier
+	}
+
+	for _, pattern := range o.CacheJobs {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--cache-jobs option pattern invalid: %v.", pattern), Code: 1}
 		}
-		if dst.Input == "" {
-			dst.Input = src.Input
+	}
+
+	for _, pattern := range o.PriorityClassJobs {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--priority-class-jobs option pattern invalid: %v.", pattern), Code: 1}
 		}
-		if dst.Output == "" {
-			dst.Output = src.Output
+	}
+
+	if len(o.PriorityClassJobs) > 0 && o.PriorityClass == "" {
+		return &util.ExitError{Message: "--priority-class-jobs option requires --priority-class.", Code: 1}
+	}
+
+	if o.AnnotationSelector != "" {
+		if o.annotationSel, err = labels.Parse(o.AnnotationSelector); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--annotation-selector option invalid: %v.", err), Code: 1}
 		}
-		if dst.Sort == "" {
-			dst.Sort = src.Sort
+	}
+
+	if o.RuntimeClassSelector != "" {
+		if o.RuntimeClass == "" {
+			return &util.ExitError{Message: "--runtime-class-selector option requires --runtime-class.", Code: 1}
 		}
-		if len(dst.ExtraRefs) == 0 {
-			dst.ExtraRefs = src.ExtraRefs
+		if o.runtimeClassSel, err = labels.Parse(o.RuntimeClassSelector); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--runtime-class-selector option invalid: %v.", err), Code: 1}
 		}
-		if len(dst.Branches) == 0 {
-			dst.Branches = src.Branches
+	}
+
+	for _, pattern := range o.RemoveLabels {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--remove-labels option pattern invalid: %v.", pattern), Code: 1}
 		}
-		if len(dst.BranchesOut) == 0 {
-			dst.BranchesOut = src.BranchesOut
+	}
+
+	if o.RemoteCacheSecret != "" {
+		if o.RemoteCache == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--remote-cache-secret option requires --remote-cache: %v.", o.RemoteCacheSecret), Code: 1}
 		}
-		if len(dst.RefBranchOut) == 0 {
-			dst.RefBranchOut = src.RefBranchOut
+		if name, key := parseRemoteCacheSecret(o.RemoteCacheSecret); name == "" || key == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--remote-cache-secret option invalid: %v.", o.RemoteCacheSecret), Code: 1}
 		}
-		if len(dst.Presets) == 0 {
-			dst.Presets = src.Presets
+	}
+
+	for org, tenant := range o.Tenants {
+		if _, ok := mapOrg(*o, org); !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--tenants option org is not mapped: %v.", org), Code: 1}
 		}
-		if len(dst.RerunOrgs) == 0 {
-			dst.RerunOrgs = src.RerunOrgs
+		if _, ok := o.TenantOutputs[tenant]; !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--tenant-outputs option missing for tenant: %v.", tenant), Code: 1}
 		}
-		if len(dst.RerunUsers) == 0 {
-			dst.RerunUsers = src.RerunUsers
+	}
+
+	tenantOutputs := make(map[string]string, len(o.TenantOutputs))
+	for tenant, out := range o.TenantOutputs {
+		if tenantOutputs[tenant], err = filepath.Abs(out); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--tenant-outputs option invalid: %v.", out), Code: 1}
 		}
-		if len(dst.EnvDenylist) == 0 {
-			dst.EnvDenylist = src.EnvDenylist
+	}
+	o.TenantOutputs = tenantOutputs
+
+	if err := validateFanout(o); err != nil {
+		return err
+	}
+
+	for k, v := range o.DecorationResources {
+		if _, _, _, ok := parseDecorationResource(k); !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--decoration-resources option key invalid: %v.", k), Code: 1}
 		}
-		if len(dst.VolumeDenylist) == 0 {
-			dst.VolumeDenylist = src.VolumeDenylist
+		if _, err := resource.ParseQuantity(v); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--decoration-resources option quantity invalid: %v.", v), Code: 1}
 		}
-		if len(dst.JobAllowlist) == 0 {
-			dst.JobAllowlist = src.JobAllowlist
+	}
+
+	if len(o.SSHHostFingerprints) > 0 && !o.SSHClone {
+		return &util.ExitError{Message: "--ssh-host-fingerprints option requires --ssh-clone.", Code: 1}
+	}
+
+	if o.CloneDepth < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--clone-depth option must not be negative: %v.", o.CloneDepth), Code: 1}
+	}
+
+	if o.SkipCloning && (o.SkipSubmodules || o.CloneDepth > 0) {
+		return &util.ExitError{Message: "--skip-cloning option cannot be used with --skip-submodules or --clone-depth.", Code: 1}
+	}
+
+	if o.OauthTokenSecret != "" {
+		if _, ok := parseOauthTokenSecret(o.OauthTokenSecret); !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--oauth-token-secret option invalid: %v.", o.OauthTokenSecret), Code: 1}
 		}
-		if len(dst.JobDenylist) == 0 {
-			dst.JobDenylist = src.JobDenylist
+		if o.SSHKeySecret != "" || o.SSHClone {
+			return &util.ExitError{Message: "--oauth-token-secret option cannot be used with --ssh-key-secret or --ssh-clone.", Code: 1}
 		}
-		if len(dst.RepoAllowlist) == 0 {
-			dst.RepoAllowlist = src.RepoAllowlist
+	}
+
+	if (o.GitHubAppID == "") != (o.GitHubAppPrivateKey == "") {
+		return &util.ExitError{Message: "--github-app-id and --github-app-private-key-secret options must be used together.", Code: 1}
+	}
+
+	if o.GitHubAppID != "" {
+		if _, _, ok := parseSecretKey(o.GitHubAppPrivateKey); !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--github-app-private-key-secret option invalid: %v.", o.GitHubAppPrivateKey), Code: 1}
 		}
-		if len(dst.RepoDenylist) == 0 {
-			dst.RepoDenylist = src.RepoDenylist
+		if o.OauthTokenSecret != "" || o.SSHKeySecret != "" || o.SSHClone {
+			return &util.ExitError{Message: "--github-app-id option cannot be used with --oauth-token-secret, --ssh-key-secret, or --ssh-clone.", Code: 1}
 		}
-		if len(dst.JobType) == 0 {
-			dst.JobType = src.JobType
+	}
+
+	for k, v := range o.UtilityImages {
+		switch k {
+		case "clonerefs", "initupload", "entrypoint", "sidecar":
+		default:
+			return &util.ExitError{Message: fmt.Sprintf("--utility-images option utility invalid: %v.", k), Code: 1}
 		}
-		if len(dst.Selector) == 0 {
-			dst.Selector = src.Selector
+		if v == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--utility-images option image is empty: %v.", k), Code: 1}
 		}
-		if len(dst.Labels) == 0 {
-			dst.Labels = src.Labels
+	}
+
+	o.TolerationList = nil
+	for _, t := range o.Tolerations {
+		toleration, ok := parseToleration(t)
+		if !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--tolerations option invalid: %v.", t), Code: 1}
 		}
-		if len(dst.Env) == 0 {
-			dst.Env = src.Env
+		o.TolerationList = append(o.TolerationList, toleration)
+	}
+
+	if o.HeaderFile != "" {
+		if o.HeaderFile, err = filepath.Abs(o.HeaderFile); err != nil || !util.IsFile(o.HeaderFile) {
+			return &util.ExitError{Message: fmt.Sprintf("--header-file option path is not a file: %v.", o.HeaderFile), Code: 1}
 		}
-		if len(dst.OrgMap) == 0 {
-			dst.OrgMap = src.OrgMap
+		d, err := ioutil.ReadFile(o.HeaderFile)
+		if err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--header-file option unreadable: %v.", err), Code: 1}
 		}
-		if len(dst.RefOrgMap) == 0 {
-			dst.RefOrgMap = src.RefOrgMap
+		o.header = commentHeader(string(d))
+	}
+
+	if o.TolerationsFile != "" {
+		if o.TolerationsFile, err = filepath.Abs(o.TolerationsFile); err != nil || !util.IsFile(o.TolerationsFile) {
+			return &util.ExitError{Message: fmt.Sprintf("--tolerations-file option path is not a file: %v.", o.TolerationsFile), Code: 1}
 		}
-		if !dst.DryRun {
-			dst.DryRun = src.DryRun
+		var tolerations []v1.Toleration
+		if d, err := ioutil.ReadFile(o.TolerationsFile); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--tolerations-file option unreadable: %v.", err), Code: 1}
+		} else if err := yaml.UnmarshalStrict(d, &tolerations); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--tolerations-file option invalid: %v.", err), Code: 1}
 		}
-		if !dst.Refs {
-			dst.Refs = src.Refs
+		o.TolerationList = append(o.TolerationList, tolerations...)
+	}
+
+	o.VolumesPreset = nil
+	if o.VolumesFile != "" {
+		if o.VolumesFile, err = filepath.Abs(o.VolumesFile); err != nil || !util.IsFile(o.VolumesFile) {
+			return &util.ExitError{Message: fmt.Sprintf("--volumes-file option path is not a file: %v.", o.VolumesFile), Code: 1}
 		}
-		if !dst.Resolve {
-			dst.Resolve = src.Resolve
+		var volumes struct {
+			Volumes      []v1.Volume      `json:"volumes,omitempty"`
+			VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
 		}
-		if !dst.SSHClone {
-			dst.SSHClone = src.SSHClone
+		if d, err := ioutil.ReadFile(o.VolumesFile); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--volumes-file option unreadable: %v.", err), Code: 1}
+		} else if err := yaml.UnmarshalStrict(d, &volumes); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--volumes-file option invalid: %v.", err), Code: 1}
 		}
-		if !dst.OverrideSelector {
-			dst.OverrideSelector = src.OverrideSelector
+		o.VolumesPreset = &config.Preset{Volumes: volumes.Volumes, VolumeMounts: volumes.VolumeMounts}
+	}
+
+	for k, v := range o.SecurityContext {
+		if err := validateSecurityContextField(k, v); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--security-context option invalid: %v.", err), Code: 1}
 		}
-		if !dst.AllowLongJobNames {
-			dst.AllowLongJobNames = src.AllowLongJobNames
+	}
+
+	for k, v := range o.Resources {
+		if _, _, ok := parseResource(k); !ok {
+			return &util.ExitError{Message: fmt.Sprintf("--resources option key invalid: %v.", k), Code: 1}
 		}
-		if !dst.Verbose {
-			dst.Verbose = src.Verbose
+		if _, err := resource.ParseQuantity(v); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--resources option quantity invalid: %v.", v), Code: 1}
 		}
-		if !dst.Clean {
-			dst.Clean = src.Clean
+	}
+
+	for _, team := range o.RerunTeams {
+		if strings.Count(team, "/") != 1 || strings.HasPrefix(team, "/") || strings.HasSuffix(team, "/") {
+			return &util.ExitError{Message: fmt.Sprintf("--rerun-teams option must be an org/team-slug: %v.", team), Code: 1}
 		}
 	}
-}
 
-// validateOrgRepo validates that the org and repo for a job pass validation and should be converted.
-func validateOrgRepo(o options, org string, repo string) bool {
-	_, hasOrg := o.OrgMap[org]
+	for _, state := range o.SlackJobStates {
+		if !sets.NewString(slackStates...).Has(state) {
+			return &util.ExitError{Message: fmt.Sprintf("--slack-job-states option invalid: %v.", state), Code: 1}
+		}
+	}
 
-	if !hasOrg || o.RepoDenylistSet.Has(repo) || (len(o.RepoAllowlistSet) > 0 && !o.RepoAllowlistSet.Has(repo)) {
-		return false
+	if o.SlackReportTemplate != "" {
+		if _, err := template.New("slack").Parse(o.SlackReportTemplate); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--slack-report-template option invalid: %v.", err), Code: 1}
+		}
 	}
 
-	return true
-}
+	if o.DeckURL != "" && !isURL(o.DeckURL) {
+		return &util.ExitError{Message: fmt.Sprintf("--deck-url option must be an HTTP(S) URL: %v.", o.DeckURL), Code: 1}
+	}
 
-// validateJob validates that the job passes validation and should be converted.
-func validateJob(o options, name string, patterns []string, jType string) bool {
-	if hasMatch(name, o.JobDenylistSet.List()) || (len(o.JobAllowlistSet) > 0 && !hasMatch(name, o.JobAllowlistSet.List())) ||
-		!isMatchBranch(o, patterns) || !o.JobTypeSet.Has(jType) {
-		return false
+	if o.TideMergeMethod != "" && !sets.NewString(tideMergeMethods...).Has(o.TideMergeMethod) {
+		return &util.ExitError{Message: fmt.Sprintf("--tide-merge-method option invalid: %v.", o.TideMergeMethod), Code: 1}
 	}
 
-	return true
-}
+	if o.NumFailuresToAlert < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--num-failures-to-alert option must not be negative: %v.", o.NumFailuresToAlert), Code: 1}
+	}
 
-// isMatchBranch validates that the branch for a job passes validation and should be converted.
-func isMatchBranch(o options, patterns []string) bool {
-	if len(o.Branches) == 0 {
-		return true
+	if o.AlertStaleResultsHours < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--alert-stale-results-hours option must not be negative: %v.", o.AlertStaleResultsHours), Code: 1}
 	}
 
-	for _, branch := range o.Branches {
-		if hasMatch(branch, patterns) {
-			return true
-		}
+	if o.MaxConcurrency < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--max-concurrency option must not be negative: %v.", o.MaxConcurrency), Code: 1}
 	}
 
-	return false
-}
+	if o.ResourceScale < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--resource-scale option must not be negative: %v.", o.ResourceScale), Code: 1}
+	}
 
-// hasMatch checks if there is any match in patterns for the given name.
-func hasMatch(name string, patterns []string) bool {
-	for _, pattern := range patterns {
-		if regexp.MustCompile(pattern).MatchString(name) {
-			return true
-		}
+	if o.ConcurrencyScale < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--concurrency-scale option must not be negative: %v.", o.ConcurrencyScale), Code: 1}
 	}
-	return false
-}
 
-// allRefs returns true if all predicate function returns true for the array of ref.
-func allRefs(array []prowjob.Refs, predicate func(val prowjob.Refs, idx int) bool) bool {
-	for idx, item := range array {
+	if o.Format != "" && o.Format != formatYAML && o.Format != formatJSON {
+		return &util.ExitError{Message: fmt.Sprintf("--format option must be one of %v, %v: %v.", formatYAML, formatJSON, o.Format), Code: 1}
+	}
+
+	if o.MaxJobsPerFile < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--max-jobs-per-file option must not be negative: %v.", o.MaxJobsPerFile), Code: 1}
+	}
+
+	if o.SplitByJob && (o.SplitByType || o.MaxJobsPerFile > 0) {
+		return &util.ExitError{Message: "--split-by-job option cannot be used with --split-by-type or --max-jobs-per-file.", Code: 1}
+	}
+
+	if err := validateMappings(o); err != nil {
+		return err
+	}
+
+	for from, to := range o.BranchMap {
+		if branchNameRegex.FindString(from) != from || to == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--branch-map option must map a branch name to a branch name: %v=%v.", from, to), Code: 1}
+		}
+	}
+
+	for _, pattern := range o.PostsubmitPeriodics {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--postsubmit-periodics option pattern invalid: %v.", pattern), Code: 1}
+		}
+	}
+
+	if len(o.PostsubmitPeriodics) > 0 && len(strings.Fields(o.PostsubmitPeriodicCron)) != 5 {
+		return &util.ExitError{Message: fmt.Sprintf("--postsubmit-periodic-cron option must be a five field cron schedule: %v.", o.PostsubmitPeriodicCron), Code: 1}
+	}
+
+	if o.IntervalScale < 0 {
+		return &util.ExitError{Message: fmt.Sprintf("--interval-scale option must not be negative: %v.", o.IntervalScale), Code: 1}
+	}
+
+	if o.CronJitter < 0 || o.CronJitter > 59 {
+		return &util.ExitError{Message: fmt.Sprintf("--cron-jitter option must be between 0 and 59 minutes: %v.", o.CronJitter), Code: 1}
+	}
+
+	if o.AlwaysRun != "" {
+		if _, err := strconv.ParseBool(o.AlwaysRun); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--always-run option invalid: %v.", o.AlwaysRun), Code: 1}
+		}
+	}
+
+	if (o.PubSubProject == "") != (o.PubSubTopic == "") {
+		return &util.ExitError{Message: "--pubsub-project and --pubsub-topic options must be used together.", Code: 1}
+	}
+
+	if o.NoReporter && o.PubSubTopic != "" {
+		return &util.ExitError{Message: fmt.Sprintf("--no-reporter option cannot be used with --pubsub-topic: %v.", o.PubSubTopic), Code: 1}
+	}
+
+	if o.NoReporter && len(o.ChannelMap) > 0 {
+		return &util.ExitError{Message: "--no-reporter option cannot be used with --channel-map.", Code: 1}
+	}
+
+	if o.Reverse {
+		if o.OrgMap, err = reverseMapping(o.OrgMap); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("--reverse option invalid: %v.", err), Code: 1}
+		}
+	}
+
+	if o.GitHost != "" && (strings.Contains(o.GitHost, "/") || !strings.Contains(o.GitHost, ".")) {
+		return &util.ExitError{Message: fmt.Sprintf("--git-host option must be a host name: %v.", o.GitHost), Code: 1}
+	}
+
+	if len(o.Configs) == 0 {
+		if len(o.OrgMap) == 0 {
+			return &util.ExitError{Message: "-m, --mapping option is required.", Code: 1}
+		}
+
+		o.inputs = nil
+		for _, in := range strings.Split(o.Input, inputSeparator) {
+			switch {
+			case in == stdio && o.InputRepo != "":
+				return &util.ExitError{Message: "-i, --input option cannot read stdin with --input-repo.", Code: 1}
+			case in == stdio && o.Interval > 0:
+				return &util.ExitError{Message: "-i, --input option cannot read stdin with --interval.", Code: 1}
+			case in == stdio, isURL(in), o.InputRepo != "" && !filepath.IsAbs(in):
+				// Stdin, URL and --input-repo input(s) are resolved by resolveInputs for each generation run.
+			default:
+				if in, err = filepath.Abs(in); err != nil {
+					return &util.ExitError{Message: fmt.Sprintf("-i, --input option invalid: %v.", in), Code: 1}
+				}
+			}
+			o.inputs = append(o.inputs, in)
+		}
+		o.Input = o.inputs[0]
+
+		if len(o.inputs) > 1 && (o.Signature != "" || o.VerifyCommit) {
+			return &util.ExitError{Message: "-i, --input option must be a single input with --signature or --verify-commit.", Code: 1}
+		}
+
+		if o.Output == stdio {
+			if o.MaxJobsPerFile > 0 || o.SplitByType || o.SplitByJob || o.HistoryDB != "" {
+				return &util.ExitError{Message: "-o, --output option cannot write to stdout with --max-jobs-per-file, --split-by-type, --split-by-job, or --history-db.", Code: 1}
+			}
+		} else if o.Output, err = filepath.Abs(o.Output); err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("-o, --output option invalid: %v.", o.Output), Code: 1}
+		}
+
+		if o.TideConfig != "" {
+			if o.TideConfig, err = filepath.Abs(o.TideConfig); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("--tide-config option invalid: %v.", o.TideConfig), Code: 1}
+			} else if !util.HasExtension(o.TideConfig, yamlExt) {
+				return &util.ExitError{Message: fmt.Sprintf("--tide-config option path is not a yaml file: %v.", o.TideConfig), Code: 1}
+			}
+		}
+
+		if o.BranchProtectionConfig != "" {
+			if o.BranchProtectionConfig, err = filepath.Abs(o.BranchProtectionConfig); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("--branch-protection-config option invalid: %v.", o.BranchProtectionConfig), Code: 1}
+			} else if !util.HasExtension(o.BranchProtectionConfig, yamlExt) {
+				return &util.ExitError{Message: fmt.Sprintf("--branch-protection-config option path is not a yaml file: %v.", o.BranchProtectionConfig), Code: 1}
+			}
+		}
+
+		if o.AlertRules != "" {
+			if o.AlertRules, err = filepath.Abs(o.AlertRules); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("--alert-rules option invalid: %v.", o.AlertRules), Code: 1}
+			} else if !util.HasExtension(o.AlertRules, yamlExt) {
+				return &util.ExitError{Message: fmt.Sprintf("--alert-rules option path is not a yaml file: %v.", o.AlertRules), Code: 1}
+			}
+		}
+
+		if o.SecretsOutput != "" {
+			if o.SecretsOutput, err = filepath.Abs(o.SecretsOutput); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("--secrets-output option invalid: %v.", o.SecretsOutput), Code: 1}
+			} else if !util.HasExtension(o.SecretsOutput, yamlExt) {
+				return &util.ExitError{Message: fmt.Sprintf("--secrets-output option path is not a yaml file: %v.", o.SecretsOutput), Code: 1}
+			}
+		}
+
+		if o.ContextsOutput != "" {
+			if o.ContextsOutput, err = filepath.Abs(o.ContextsOutput); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("--contexts-output option invalid: %v.", o.ContextsOutput), Code: 1}
+			} else if !util.HasExtension(o.ContextsOutput, yamlExt) && !util.HasExtension(o.ContextsOutput, jsonExt) {
+				return &util.ExitError{Message: fmt.Sprintf("--contexts-output option path is not a json or yaml file: %v.", o.ContextsOutput), Code: 1}
+			}
+		}
+
+		if o.Manifest != "" {
+			if o.Manifest, err = filepath.Abs(o.Manifest); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("--manifest option invalid: %v.", o.Manifest), Code: 1}
+			} else if !util.HasExtension(o.Manifest, jsonExt) {
+				return &util.ExitError{Message: fmt.Sprintf("--manifest option path is not a json file: %v.", o.Manifest), Code: 1}
+			}
+		}
+
+		for i, c := range o.Presets {
+			if isURL(c) {
+				continue
+			}
+			if o.Presets[i], err = filepath.Abs(c); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("-p, --preset option invalid: %v.", o.Presets[i]), Code: 1}
+			} else if !util.Exists(o.Presets[i]) {
+				return &util.ExitError{Message: fmt.Sprintf("-p, --preset option path does not exist: %v.", o.Presets[i]), Code: 1}
+			} else if util.IsFile(o.Presets[i]) && !util.HasExtension(o.Presets[i], jobConfigExt) {
+				return &util.ExitError{Message: fmt.Sprintf("-p, --preset option path is not a yaml file: %v.", o.Presets[i]), Code: 1}
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyDefaultTransforms defaults transform struct from left to right with decreasing precedence.
+func applyDefaultTransforms(dst *transform, srcs ...*transform) {
+	for _, src := range srcs {
+		if dst.Annotations == nil {
+			dst.Annotations = src.Annotations
+		}
+		if len(dst.Tenants) == 0 {
+			dst.Tenants = src.Tenants
+		}
+		if len(dst.TenantOutputs) == 0 {
+			dst.TenantOutputs = src.TenantOutputs
+		}
+		if len(dst.TenantClusters) == 0 {
+			dst.TenantClusters = src.TenantClusters
+		}
+		if len(dst.TenantBuckets) == 0 {
+			dst.TenantBuckets = src.TenantBuckets
+		}
+		if len(dst.Fanout) == 0 {
+			dst.Fanout = src.Fanout
+		}
+		if len(dst.FanoutModifiers) == 0 {
+			dst.FanoutModifiers = src.FanoutModifiers
+		}
+		if len(dst.FanoutOutputs) == 0 {
+			dst.FanoutOutputs = src.FanoutOutputs
+		}
+		if len(dst.ActiveDeadlines) == 0 {
+			dst.ActiveDeadlines = src.ActiveDeadlines
+		}
+		if dst.TerminationGracePeriod == "" {
+			dst.TerminationGracePeriod = src.TerminationGracePeriod
+		}
+		if dst.Bucket == "" {
+			dst.Bucket = src.Bucket
+		}
+		if dst.GCSCredentialsSecret == "" {
+			dst.GCSCredentialsSecret = src.GCSCredentialsSecret
+		}
+		if dst.DefaultServiceAccount == "" {
+			dst.DefaultServiceAccount = src.DefaultServiceAccount
+		}
+		if len(dst.BucketMap) == 0 {
+			dst.BucketMap = src.BucketMap
+		}
+		if dst.CacheVolume == "" {
+			dst.CacheVolume = src.CacheVolume
+		}
+		if dst.CacheMountPath == "" {
+			dst.CacheMountPath = src.CacheMountPath
+		}
+		if len(dst.CacheJobs) == 0 {
+			dst.CacheJobs = src.CacheJobs
+		}
+		if len(dst.CacheEnv) == 0 {
+			dst.CacheEnv = src.CacheEnv
+		}
+		if dst.RemoteCache == "" {
+			dst.RemoteCache = src.RemoteCache
+		}
+		if dst.RemoteCacheSecret == "" {
+			dst.RemoteCacheSecret = src.RemoteCacheSecret
+		}
+		if len(dst.RemoteCacheLabels) == 0 {
+			dst.RemoteCacheLabels = src.RemoteCacheLabels
+		}
+		if dst.Cluster == "" {
+			dst.Cluster = src.Cluster
+		}
+		if len(dst.ClusterMap) == 0 {
+			dst.ClusterMap = src.ClusterMap
+		}
+		if dst.ServiceAccount == "" {
+			dst.ServiceAccount = src.ServiceAccount
+		}
+		if len(dst.ServiceAccountMap) == 0 {
+			dst.ServiceAccountMap = src.ServiceAccountMap
+		}
+		if len(dst.ClusterServiceAccounts) == 0 {
+			dst.ClusterServiceAccounts = src.ClusterServiceAccounts
+		}
+		if dst.PriorityClass == "" {
+			dst.PriorityClass = src.PriorityClass
+		}
+		if len(dst.PriorityClassJobs) == 0 {
+			dst.PriorityClassJobs = src.PriorityClassJobs
+		}
+		if dst.RuntimeClass == "" {
+			dst.RuntimeClass = src.RuntimeClass
+		}
+		if dst.RuntimeClassSelector == "" {
+			dst.RuntimeClassSelector = src.RuntimeClassSelector
+		}
+		if dst.AnnotationSelector == "" {
+			dst.AnnotationSelector = src.AnnotationSelector
+		}
+		if dst.Channel == "" {
+			dst.Channel = src.Channel
+		}
+		if len(dst.ChannelMap) == 0 {
+			dst.ChannelMap = src.ChannelMap
+		}
+		if dst.PubSubProject == "" {
+			dst.PubSubProject = src.PubSubProject
+		}
+		if dst.PubSubTopic == "" {
+			dst.PubSubTopic = src.PubSubTopic
+		}
+		if dst.SlackReportTemplate == "" {
+			dst.SlackReportTemplate = src.SlackReportTemplate
+		}
+		if dst.DeckURL == "" {
+			dst.DeckURL = src.DeckURL
+		}
+		if len(dst.SlackJobStates) == 0 {
+			dst.SlackJobStates = src.SlackJobStates
+		}
+		if dst.ContextsOutput == "" {
+			dst.ContextsOutput = src.ContextsOutput
+		}
+		if dst.Manifest == "" {
+			dst.Manifest = src.Manifest
+		}
+		if dst.SSHKeySecret == "" {
+			dst.SSHKeySecret = src.SSHKeySecret
+		}
+		if dst.OauthTokenSecret == "" {
+			dst.OauthTokenSecret = src.OauthTokenSecret
+		}
+		if dst.GitHubAppID == "" {
+			dst.GitHubAppID = src.GitHubAppID
+		}
+		if dst.GitHubAppPrivateKey == "" {
+			dst.GitHubAppPrivateKey = src.GitHubAppPrivateKey
+		}
+		if dst.GitHost == "" {
+			dst.GitHost = src.GitHost
+		}
+		if dst.Modifier == "" {
+			dst.Modifier = src.Modifier
+		}
+		if len(dst.ModifierMap) == 0 {
+			dst.ModifierMap = src.ModifierMap
+		}
+		if dst.Input == "" {
+			dst.Input = src.Input
+		}
+		if len(dst.Exclude) == 0 {
+			dst.Exclude = src.Exclude
+		}
+		if dst.Format == "" {
+			dst.Format = src.Format
+		}
+		if dst.InputRepo == "" {
+			dst.InputRepo = src.InputRepo
+		}
+		if dst.InputRef == "" {
+			dst.InputRef = src.InputRef
+		}
+		if dst.Output == "" {
+			dst.Output = src.Output
+		}
+		if dst.Sort == "" {
+			dst.Sort = src.Sort
+		}
+		if dst.SourceSHA == "" {
+			dst.SourceSHA = src.SourceSHA
+		}
+		if dst.HeaderFile == "" {
+			dst.HeaderFile = src.HeaderFile
+		}
+		if !dst.Provenance {
+			dst.Provenance = src.Provenance
+		}
+		if dst.TideConfig == "" {
+			dst.TideConfig = src.TideConfig
+		}
+		if dst.BranchProtectionConfig == "" {
+			dst.BranchProtectionConfig = src.BranchProtectionConfig
+		}
+		if dst.AlertRules == "" {
+			dst.AlertRules = src.AlertRules
+		}
+		if dst.AlertStaleWindow == "" {
+			dst.AlertStaleWindow = src.AlertStaleWindow
+		}
+		if len(dst.AlertLabels) == 0 {
+			dst.AlertLabels = src.AlertLabels
+		}
+		if dst.AlertSeverity == "" {
+			dst.AlertSeverity = src.AlertSeverity
+		}
+		if dst.AlertEmail == "" {
+			dst.AlertEmail = src.AlertEmail
+		}
+		if dst.NumFailuresToAlert == 0 {
+			dst.NumFailuresToAlert = src.NumFailuresToAlert
+		}
+		if dst.AlertStaleResultsHours == 0 {
+			dst.AlertStaleResultsHours = src.AlertStaleResultsHours
+		}
+		if dst.TideMergeMethod == "" {
+			dst.TideMergeMethod = src.TideMergeMethod
+		}
+		if dst.MaxJobsPerFile == 0 {
+			dst.MaxJobsPerFile = src.MaxJobsPerFile
+		}
+		if !dst.SplitByType {
+			dst.SplitByType = src.SplitByType
+		}
+		if !dst.SplitByJob {
+			dst.SplitByJob = src.SplitByJob
+		}
+		if dst.MaxConcurrency == 0 {
+			dst.MaxConcurrency = src.MaxConcurrency
+		}
+		if dst.ConcurrencyScale == 0 {
+			dst.ConcurrencyScale = src.ConcurrencyScale
+		}
+		if len(dst.ExtraRefs) == 0 {
+			dst.ExtraRefs = src.ExtraRefs
+		}
+		if len(dst.Branches) == 0 {
+			dst.Branches = src.Branches
+		}
+		if len(dst.SkipBranches) == 0 {
+			dst.SkipBranches = src.SkipBranches
+		}
+		if len(dst.BranchesOut) == 0 {
+			dst.BranchesOut = src.BranchesOut
+		}
+		if len(dst.RefBranchOut) == 0 {
+			dst.RefBranchOut = src.RefBranchOut
+		}
+		if len(dst.BranchMap) == 0 {
+			dst.BranchMap = src.BranchMap
+		}
+		if len(dst.BaseRefMap) == 0 {
+			dst.BaseRefMap = src.BaseRefMap
+		}
+		if dst.PathAliasMode == "" {
+			dst.PathAliasMode = src.PathAliasMode
+		}
+		if len(dst.PathAliasMap) == 0 {
+			dst.PathAliasMap = src.PathAliasMap
+		}
+		if len(dst.Presets) == 0 {
+			dst.Presets = src.Presets
+		}
+		if len(dst.RerunOrgs) == 0 {
+			dst.RerunOrgs = src.RerunOrgs
+		}
+		if len(dst.RerunUsers) == 0 {
+			dst.RerunUsers = src.RerunUsers
+		}
+		if len(dst.RerunTeams) == 0 {
+			dst.RerunTeams = src.RerunTeams
+		}
+		if len(dst.RerunTeamIDs) == 0 {
+			dst.RerunTeamIDs = src.RerunTeamIDs
+		}
+		if len(dst.TideLabels) == 0 {
+			dst.TideLabels = src.TideLabels
+		}
+		if len(dst.TideMissingLabels) == 0 {
+			dst.TideMissingLabels = src.TideMissingLabels
+		}
+		if len(dst.EnvDenylist) == 0 {
+			dst.EnvDenylist = src.EnvDenylist
+		}
+		if len(dst.VolumeDenylist) == 0 {
+			dst.VolumeDenylist = src.VolumeDenylist
+		}
+		if len(dst.RemoveLabels) == 0 {
+			dst.RemoveLabels = src.RemoveLabels
+		}
+		if len(dst.JobAllowlist) == 0 {
+			dst.JobAllowlist = src.JobAllowlist
+		}
+		if len(dst.JobDenylist) == 0 {
+			dst.JobDenylist = src.JobDenylist
+		}
+		if len(dst.RepoAllowlist) == 0 {
+			dst.RepoAllowlist = src.RepoAllowlist
+		}
+		if len(dst.RepoDenylist) == 0 {
+			dst.RepoDenylist = src.RepoDenylist
+		}
+		if len(dst.JobType) == 0 {
+			dst.JobType = src.JobType
+		}
+		if len(dst.Selector) == 0 {
+			dst.Selector = src.Selector
+		}
+		if len(dst.Tolerations) == 0 {
+			dst.Tolerations = src.Tolerations
+		}
+		if dst.TolerationsFile == "" {
+			dst.TolerationsFile = src.TolerationsFile
+		}
+		if dst.VolumesFile == "" {
+			dst.VolumesFile = src.VolumesFile
+		}
+		if len(dst.Labels) == 0 {
+			dst.Labels = src.Labels
+		}
+		if len(dst.PodAnnotations) == 0 {
+			dst.PodAnnotations = src.PodAnnotations
+		}
+		if len(dst.SecurityContext) == 0 {
+			dst.SecurityContext = src.SecurityContext
+		}
+		if len(dst.Env) == 0 {
+			dst.Env = src.Env
+		}
+		if len(dst.OrgMap) == 0 {
+			dst.OrgMap = src.OrgMap
+		}
+		if len(dst.RefOrgMap) == 0 {
+			dst.RefOrgMap = src.RefOrgMap
+		}
+		if len(dst.BotTokenSecrets) == 0 {
+			dst.BotTokenSecrets = src.BotTokenSecrets
+		}
+		if len(dst.SecretMap) == 0 {
+			dst.SecretMap = src.SecretMap
+		}
+		if len(dst.DecorationResources) == 0 {
+			dst.DecorationResources = src.DecorationResources
+		}
+		if len(dst.UtilityImages) == 0 {
+			dst.UtilityImages = src.UtilityImages
+		}
+		if len(dst.Resources) == 0 {
+			dst.Resources = src.Resources
+		}
+		if dst.ResourceScale == 0 {
+			dst.ResourceScale = src.ResourceScale
+		}
+		if !dst.DryRun {
+			dst.DryRun = src.DryRun
+		}
+		if !dst.Refs {
+			dst.Refs = src.Refs
+		}
+		if !dst.Resolve {
+			dst.Resolve = src.Resolve
+		}
+		if !dst.SSHClone {
+			dst.SSHClone = src.SSHClone
+		}
+		if len(dst.SSHHostFingerprints) == 0 {
+			dst.SSHHostFingerprints = src.SSHHostFingerprints
+		}
+		if !dst.SkipCloning {
+			dst.SkipCloning = src.SkipCloning
+		}
+		if !dst.CensorSecrets {
+			dst.CensorSecrets = src.CensorSecrets
+		}
+		if !dst.ForceDecorate {
+			dst.ForceDecorate = src.ForceDecorate
+		}
+		if !dst.SkipSubmodules {
+			dst.SkipSubmodules = src.SkipSubmodules
+		}
+		if dst.CloneDepth == 0 {
+			dst.CloneDepth = src.CloneDepth
+		}
+		if !dst.OverrideSelector {
+			dst.OverrideSelector = src.OverrideSelector
+		}
+		if !dst.ResourcesIfUnset {
+			dst.ResourcesIfUnset = src.ResourcesIfUnset
+		}
+		if !dst.AllowLongJobNames {
+			dst.AllowLongJobNames = src.AllowLongJobNames
+		}
+		if !dst.RewriteTriggers {
+			dst.RewriteTriggers = src.RewriteTriggers
+		}
+		if !dst.StrictMapping {
+			dst.StrictMapping = src.StrictMapping
+		}
+		if !dst.Strict {
+			dst.Strict = src.Strict
+		}
+		if !dst.FollowSymlinks {
+			dst.FollowSymlinks = src.FollowSymlinks
+		}
+		if !dst.CopyUnmapped {
+			dst.CopyUnmapped = src.CopyUnmapped
+		}
+		if !dst.Verbose {
+			dst.Verbose = src.Verbose
+		}
+		if !dst.Clean {
+			dst.Clean = src.Clean
+		}
+		if !dst.Consolidate {
+			dst.Consolidate = src.Consolidate
+		}
+		if !dst.MigrateBootstrap {
+			dst.MigrateBootstrap = src.MigrateBootstrap
+		}
+		if !dst.VerifyCommit {
+			dst.VerifyCommit = src.VerifyCommit
+		}
+		if dst.Signature == "" {
+			dst.Signature = src.Signature
+		}
+		if dst.SecretsOutput == "" {
+			dst.SecretsOutput = src.SecretsOutput
+		}
+		if dst.SecretsKind == "" {
+			dst.SecretsKind = src.SecretsKind
+		}
+		if dst.SecretsNamespace == "" {
+			dst.SecretsNamespace = src.SecretsNamespace
+		}
+		if dst.SignedManifest == "" {
+			dst.SignedManifest = src.SignedManifest
+		}
+		if dst.TrustedKeys == "" {
+			dst.TrustedKeys = src.TrustedKeys
+		}
+		if !dst.EmitPresets {
+			dst.EmitPresets = src.EmitPresets
+		}
+		if !dst.NoReporter {
+			dst.NoReporter = src.NoReporter
+		}
+		if !dst.SkipReport {
+			dst.SkipReport = src.SkipReport
+		}
+		if !dst.Optional {
+			dst.Optional = src.Optional
+		}
+		if !dst.SkipOptional {
+			dst.SkipOptional = src.SkipOptional
+		}
+		if dst.AlwaysRun == "" {
+			dst.AlwaysRun = src.AlwaysRun
+		}
+		if dst.RunIfChangedPrefix == "" {
+			dst.RunIfChangedPrefix = src.RunIfChangedPrefix
+		}
+		if dst.CronJitter == 0 {
+			dst.CronJitter = src.CronJitter
+		}
+		if dst.IntervalScale == 0 {
+			dst.IntervalScale = src.IntervalScale
+		}
+		if !dst.IntervalToCron {
+			dst.IntervalToCron = src.IntervalToCron
+		}
+		if len(dst.PostsubmitPeriodics) == 0 {
+			dst.PostsubmitPeriodics = src.PostsubmitPeriodics
+		}
+		if dst.PostsubmitPeriodicCron == "" {
+			dst.PostsubmitPeriodicCron = src.PostsubmitPeriodicCron
+		}
+		if !dst.Reverse {
+			dst.Reverse = src.Reverse
+		}
+	}
+}
+
+// validateOrgRepo validates that the org and repo for a job pass validation and should be converted.
+func validateOrgRepo(o options, org string, repo string) bool {
+	_, _, hasOrg := mapOrgRepo(o, org, repo)
+
+	if !hasOrg || isExcluded(o, org, repo) || o.RepoDenylistSet.Has(repo) || (len(o.RepoAllowlistSet) > 0 && !o.RepoAllowlistSet.Has(repo)) {
+		return false
+	}
+
+	return true
+}
+
+// isUnmapped checks if an org/repo is neither mapped nor excluded from the mapping.
+func isUnmapped(o options, org string, repo string) bool {
+	_, _, hasOrg := mapOrgRepo(o, org, repo)
+	return !hasOrg && !isExcluded(o, org, repo)
+}
+
+// unmappedRepos returns the org/repo(s) of the input job(s) of the generation run that were dropped because they
+// are not in the mapping. Periodics are only dropped if none of their refs are mapped.
+func unmappedRepos(o options) []string {
+	if o.errs == nil {
+		return nil
+	}
+	return sets.NewString(o.errs.unmapped.list()...).List()
+}
+
+// validateJob validates that the job passes validation and should be converted.
+func validateJob(o options, job config.JobBase, brancher config.Brancher, jType string) bool {
+	if hasMatch(job.Name, o.JobDenylistSet.List()) || (len(o.JobAllowlistSet) > 0 && !hasMatch(job.Name, o.JobAllowlistSet.List())) ||
+		!isMatchBranch(o, brancher) || !o.JobTypeSet.Has(jType) {
+		return false
+	}
+
+	if o.annotationSel != nil && !o.annotationSel.Matches(labels.Set(job.Annotations)) {
+		return false
+	}
+
+	return true
+}
+
+// isSkippedOptional returns true if the presubmit is optional and optional presubmits are excluded from generation.
+func isSkippedOptional(o options, job config.Presubmit) bool {
+	return o.SkipOptional && job.Optional
+}
+
+// isMatchBranch validates that the branch for a job passes validation and should be converted.
+func isMatchBranch(o options, brancher config.Brancher) bool {
+	for _, branch := range o.SkipBranches {
+		if runsOnBranch(brancher, branch) {
+			return false
+		}
+	}
+
+	if len(o.Branches) == 0 {
+		return true
+	}
+
+	for _, branch := range o.Branches {
+		if runsOnBranch(brancher, branch) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runsOnBranch checks if a job runs on the given branch, honoring both its branches and skip_branches.
+func runsOnBranch(brancher config.Brancher, branch string) bool {
+	if hasMatch(branch, brancher.SkipBranches) {
+		return false
+	}
+
+	return len(brancher.Branches) == 0 || hasMatch(branch, brancher.Branches)
+}
+
+// hasMatch checks if there is any match in patterns for the given name.
+func hasMatch(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if util.MustCompile(pattern).MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// allRefs returns true if all predicate function returns true for the array of ref.
+func allRefs(array []prowjob.Refs, predicate func(val prowjob.Refs, idx int) bool) bool {
+	for idx, item := range array {
 		if !predicate(item, idx) {
 			return false
 		}
 	}
-	return true
-}
+	return true
+}
+
+// splitEnvRemovals splits the removal entries (e.g. NAME-) from an env.
+func splitEnvRemovals(m map[string]string) (map[string]string, sets.String, error) {
+	env := make(map[string]string, len(m))
+	removed := sets.NewString()
+
+	for k, v := range m {
+		if !strings.HasSuffix(k, envRemovalSuffix) || v != "" {
+			env[k] = v
+			continue
+		}
+
+		name := strings.TrimSuffix(k, envRemovalSuffix)
+		if name == "" {
+			return nil, nil, fmt.Errorf("removal must be of the form NAME%s: %v", envRemovalSuffix, k)
+		}
+		removed.Insert(name)
+	}
+
+	return env, removed, nil
+}
+
+// combinePresets reads a list of paths and aggregates the presets.
+func combinePresets(o options, paths []string) []config.Preset {
+	presets := []config.Preset{}
+
+	if len(paths) == 0 {
+		return presets
+	}
+
+	for _, p := range paths {
+		c, err := config.ReadJobConfig(p)
+		if err != nil {
+			reportInputErr(o, p, err)
+			continue
+		}
+		presets = append(presets, c.Presets...)
+	}
+
+	return presets
+}
+
+// mergePreset merges a preset into a job Spec based on defined labels.
+func mergePreset(labels map[string]string, job *config.JobBase, preset config.Preset) {
+	for l, v := range preset.Labels {
+		if v2, exists := labels[l]; !exists || v != v2 {
+			return
+		}
+	}
+
+	for _, env := range preset.Env {
+	econtainer:
+		for i := range job.Spec.Containers {
+			for j := range job.Spec.Containers[i].Env {
+				if job.Spec.Containers[i].Env[j].Name == env.Name {
+					job.Spec.Containers[i].Env[j].Value = env.Value
+					continue econtainer
+				}
+			}
+
+			job.Spec.Containers[i].Env = append(job.Spec.Containers[i].Env, env)
+		}
+	}
+
+volume:
+	for _, vol := range preset.Volumes {
+
+		for i := range job.Spec.Volumes {
+			if job.Spec.Volumes[i].Name == vol.Name {
+				job.Spec.Volumes[i] = vol
+				continue volume
+			}
+		}
+
+		job.Spec.Volumes = append(job.Spec.Volumes, vol)
+	}
+
+	for _, volm := range preset.VolumeMounts {
+	vcontainer:
+		for i := range job.Spec.Containers {
+			for j := range job.Spec.Containers[i].VolumeMounts {
+				if job.Spec.Containers[i].VolumeMounts[j].Name == volm.Name {
+					job.Spec.Containers[i].VolumeMounts[j] = volm
+					continue vcontainer
+				}
+			}
+
+			job.Spec.Containers[i].VolumeMounts = append(job.Spec.Containers[i].VolumeMounts, volm)
+		}
+	}
+}
+
+// presetIndex indexes presets by their label pairs so that only candidate presets are merged into a job.
+type presetIndex struct {
+	presets   []config.Preset
+	unlabeled []int
+	byLabel   map[string][]int
+}
+
+// newPresetIndex builds a presetIndex from a list of presets, preserving their order of precedence.
+func newPresetIndex(presets []config.Preset) *presetIndex {
+	idx := &presetIndex{
+		presets: presets,
+		byLabel: make(map[string][]int),
+	}
+
+	for i, preset := range presets {
+		if len(preset.Labels) == 0 {
+			idx.unlabeled = append(idx.unlabeled, i)
+			continue
+		}
+		for l, v := range preset.Labels {
+			idx.byLabel[l+"="+v] = append(idx.byLabel[l+"="+v], i)
+		}
+	}
+
+	return idx
+}
+
+// match returns the presets that may apply to a set of labels in their original order.
+func (idx *presetIndex) match(labels map[string]string) []config.Preset {
+	if idx == nil || len(idx.presets) == 0 {
+		return nil
+	}
+
+	candidates := sets.NewInt(idx.unlabeled...)
+	for l, v := range labels {
+		candidates.Insert(idx.byLabel[l+"="+v]...)
+	}
+
+	matched := make([]config.Preset, 0, candidates.Len())
+	for _, i := range candidates.List() {
+		matched = append(matched, idx.presets[i])
+	}
+
+	return matched
+}
+
+// resolvePresets resolves all preset for a particular job Spec based on defined labels.
+func resolvePresets(o options, labels map[string]string, job *config.JobBase, presets *presetIndex) {
+	if !o.Resolve {
+		return
+	}
+
+	if job.Spec != nil {
+		for _, preset := range presets.match(labels) {
+			mergePreset(labels, job, preset)
+		}
+	}
+}
+
+// injectVolumes merges the volumes and volume mounts of --volumes-file into the job Spec the same way as a preset.
+func injectVolumes(o options, job *config.JobBase) {
+	if o.VolumesPreset == nil || job.Spec == nil {
+		return
+	}
+
+	mergePreset(nil, job, *o.VolumesPreset)
+}
+
+// translatePresets translates presets to work with private repositories.
+// Denylisted env and volume fields are pruned and env overrides are applied so that
+// emitted presets produce the same result as resolving them into the job Spec.
+func translatePresets(o options, presets []config.Preset) []config.Preset {
+	translated := make([]config.Preset, 0, len(presets))
+
+	for _, preset := range presets {
+		p := config.Preset{Labels: preset.Labels}
+
+		for _, env := range preset.Env {
+			if o.EnvDenylistSet.Has(env.Name) {
+				continue
+			}
+			if v, ok := o.Env[env.Name]; ok {
+				env.Value = v
+				env.ValueFrom = nil
+			}
+			p.Env = append(p.Env, env)
+		}
+
+		for _, vol := range preset.Volumes {
+			if o.VolumeDenylistSet.Has(vol.Name) {
+				continue
+			}
+			p.Volumes = append(p.Volumes, vol)
+		}
+
+		renameEnvSecrets(o.SecretNames, p.Env)
+		renameVolumeSecrets(o.SecretNames, p.Volumes)
+
+		for _, volm := range preset.VolumeMounts {
+			if o.VolumeDenylistSet.Has(volm.Name) {
+				continue
+			}
+			p.VolumeMounts = append(p.VolumeMounts, volm)
+		}
+
+		translated = append(translated, p)
+	}
+
+	return translated
+}
+
+// mergeSecretNames merges the bot token secrets and the secret map into a single secret renaming.
+func mergeSecretNames(botTokenSecrets, secretMap map[string]string) (map[string]string, error) {
+	names := make(map[string]string, len(botTokenSecrets)+len(secretMap))
+
+	for _, m := range []map[string]string{botTokenSecrets, secretMap} {
+		for k, v := range m {
+			if k == "" || v == "" {
+				return nil, fmt.Errorf("empty secret name in %s=%s", k, v)
+			}
+			if prev, ok := names[k]; ok && prev != v {
+				return nil, fmt.Errorf("secret %s renamed to both %s and %s", k, prev, v)
+			}
+			names[k] = v
+		}
+	}
+
+	return names, nil
+}
+
+// updateSecretNames renames references to the public secrets in the job Spec to their private equivalents.
+func updateSecretNames(o options, job *config.JobBase) {
+	if len(o.SecretNames) == 0 || job.Spec == nil {
+		return
+	}
+
+	renameVolumeSecrets(o.SecretNames, job.Spec.Volumes)
+
+	for i := range job.Spec.Containers {
+		renameEnvSecrets(o.SecretNames, job.Spec.Containers[i].Env)
+
+		for j := range job.Spec.Containers[i].EnvFrom {
+			if ref := job.Spec.Containers[i].EnvFrom[j].SecretRef; ref != nil {
+				if name, ok := o.SecretNames[ref.Name]; ok {
+					renamed := *ref
+					renamed.Name = name
+					job.Spec.Containers[i].EnvFrom[j].SecretRef = &renamed
+				}
+			}
+		}
+	}
+}
+
+// renameVolumeSecrets renames the secrets referenced by secret and projected volumes.
+// Renamed volumes are copied since their sources may be shared with presets.
+func renameVolumeSecrets(secrets map[string]string, volumes []v1.Volume) {
+	if len(secrets) == 0 {
+		return
+	}
+
+	for i := range volumes {
+		vol := volumes[i].DeepCopy()
+		renamed := false
+
+		if s := vol.Secret; s != nil {
+			if name, ok := secrets[s.SecretName]; ok {
+				s.SecretName = name
+				renamed = true
+			}
+		}
+
+		if p := vol.Projected; p != nil {
+			for j := range p.Sources {
+				if s := p.Sources[j].Secret; s != nil {
+					if name, ok := secrets[s.Name]; ok {
+						s.Name = name
+						renamed = true
+					}
+				}
+			}
+		}
+
+		if renamed {
+			volumes[i] = *vol
+		}
+	}
+}
+
+// renameEnvSecrets renames the secrets referenced by env values.
+// Renamed env values are copied since their sources may be shared with presets.
+func renameEnvSecrets(secrets map[string]string, envs []v1.EnvVar) {
+	if len(secrets) == 0 {
+		return
+	}
+
+	for i := range envs {
+		if envs[i].ValueFrom == nil || envs[i].ValueFrom.SecretKeyRef == nil {
+			continue
+		}
+
+		if name, ok := secrets[envs[i].ValueFrom.SecretKeyRef.Name]; ok {
+			envs[i].ValueFrom = envs[i].ValueFrom.DeepCopy()
+			envs[i].ValueFrom.SecretKeyRef.Name = name
+		}
+	}
+}
+
+// pruneJobBase prunes denylisted fields from the job Spec.
+func pruneJobBase(o options, job *config.JobBase) {
+	if len(o.RemoveLabels) > 0 {
+		pruneLabels(o.RemoveLabels, job)
+	}
+	if job.Spec != nil {
+		if len(o.VolumeDenylistSet) > 0 {
+			pruneVolumes(o.VolumeDenylistSet, job)
+		}
+		if len(o.EnvDenylistSet) > 0 {
+			pruneEnvs(o.EnvDenylistSet, job)
+		}
+	}
+}
+
+// pruneLabels prunes the Labels fields with a key matching any of the patterns.
+func pruneLabels(patterns []string, job *config.JobBase) {
+	if len(job.Labels) == 0 {
+		return
+	}
+
+	labels := make(map[string]string, len(job.Labels))
+
+label:
+	for k, v := range job.Labels {
+		for _, pattern := range patterns {
+			if util.MustCompile(`^(?:` + pattern + `)$`).MatchString(k) {
+				continue label
+			}
+		}
+		labels[k] = v
+	}
+
+	job.Labels = labels
+}
+
+// pruneEnvs prunes denylisted Env fields.
+func pruneEnvs(denylist sets.String, job *config.JobBase) {
+	for i := range job.Spec.Containers {
+		var envs []v1.EnvVar
+
+		for _, env := range job.Spec.Containers[i].Env {
+			if denylist.Has(env.Name) {
+				continue
+			}
+			envs = append(envs, env)
+		}
+		job.Spec.Containers[i].Env = envs
+	}
+}
+
+// pruneVolumes prunes denylisted Volume and VolueMount fields.
+func pruneVolumes(denylist sets.String, job *config.JobBase) {
+	var volumes []v1.Volume
+
+	for _, vol := range job.Spec.Volumes {
+		if denylist.Has(vol.Name) {
+			continue
+		}
+		volumes = append(volumes, vol)
+	}
+	job.Spec.Volumes = volumes
+
+	for i := range job.Spec.Containers {
+		var volumeMounts []v1.VolumeMount
+
+		for _, volm := range job.Spec.Containers[i].VolumeMounts {
+			if denylist.Has(volm.Name) {
+				continue
+			}
+			volumeMounts = append(volumeMounts, volm)
+		}
+		job.Spec.Containers[i].VolumeMounts = volumeMounts
+	}
+}
+
+// withOrgModifier returns the options with the modifier of a public org, if any.
+func withOrgModifier(o options, org string) options {
+	if modifier, ok := o.ModifierMap[org]; ok {
+		o.Modifier = modifier
+	}
+
+	return o
+}
+
+// withRepoOptions returns the options with the overrides of a public org/repo or its org applied.
+func withRepoOptions(o options, org string, repo string) options {
+	o = withOrgModifier(o, org)
+	o = withRepoCluster(o, org, repo)
+	o = withRepoServiceAccount(o, org, repo)
+
+	return withRepoChannel(o, org, repo)
+}
+
+// withRepoChannel returns the options with the Slack channel of a public org/repo or its org, if any.
+func withRepoChannel(o options, org string, repo string) options {
+	if channel, ok := o.ChannelMap[org+"/"+repo]; ok {
+		o.Channel = channel
+	} else if channel, ok := o.ChannelMap[org]; ok {
+		o.Channel = channel
+	}
+
+	return o
+}
+
+// withRepoServiceAccount returns the options with the service account of a public org/repo or its org, if any.
+func withRepoServiceAccount(o options, org string, repo string) options {
+	if sa, ok := o.ServiceAccountMap[org+"/"+repo]; ok {
+		o.repoSA = sa
+	} else if sa, ok := o.ServiceAccountMap[org]; ok {
+		o.repoSA = sa
+	}
+
+	return o
+}
+
+// withRepoCluster returns the options with the cluster of a public org/repo or its org, if any.
+func withRepoCluster(o options, org string, repo string) options {
+	if cluster, ok := o.ClusterMap[org+"/"+repo]; ok {
+		o.Cluster = cluster
+	} else if cluster, ok := o.ClusterMap[org]; ok {
+		o.Cluster = cluster
+	}
+
+	return o
+}
+
+// withRepoBucket returns the options with the bucket of a private org/repo or its org, if any.
+func withRepoBucket(o options, orgrepo string) options {
+	org, _ := util.SplitOrgRepo(orgrepo)
+
+	if bucket, ok := o.BucketMap[orgrepo]; ok {
+		o.Bucket = bucket
+	} else if bucket, ok := o.BucketMap[org]; ok {
+		o.Bucket = bucket
+	}
+
+	return o
+}
+
+// sortJobs sorts jobs based on a provided sort order.
+func sortJobs(o options, pre map[string][]config.Presubmit, post map[string][]config.Postsubmit, per []config.Periodic) {
+	if o.Sort == "" {
+		return
+	}
+
+	choices := strings.Join([]string{string(ascending), string(descending)}, "|")
+	matches := util.MustCompile(`^(` + choices + `)(?:ending)?$`).FindStringSubmatch(o.Sort)
+	if len(matches) < 2 {
+		return
+	}
+
+	var comparator func(a, b string) bool
+
+	switch sortOrder(matches[1]) {
+	case ascending:
+		comparator = func(a, b string) bool {
+			return a < b
+		}
+	case descending:
+		comparator = func(a, b string) bool {
+			return a > b
+		}
+	}
+
+	for _, c := range pre {
+		sort.Slice(c, func(a, b int) bool {
+			return comparator(c[a].Name, c[b].Name)
+		})
+	}
+
+	for _, c := range post {
+		sort.Slice(c, func(a, b int) bool {
+			return comparator(c[a].Name, c[b].Name)
+		})
+	}
+
+	sort.Slice(per, func(a, b int) bool {
+		return comparator(per[a].Name, per[b].Name)
+	})
+}
+
+// getOutPath derives the output path in the output format from the specified input directory and current path.
+func getOutPath(o options, p string, in string) string {
+	return formatOutPath(o, deriveOutPath(o, p, in))
+}
+
+// formatOutPath changes the extension of a derived output file to the one of the output format.
+// Explicit output files keep their extension, which determines their format instead.
+func formatOutPath(o options, p string) string {
+	if p == "" || p == o.Output || !util.HasExtension(p, jobConfigExt) {
+		return p
+	}
+
+	if o.Format == formatJSON {
+		return strings.TrimSuffix(p, filepath.Ext(p)) + ".json"
+	} else if !util.HasExtension(p, yamlExt) {
+		return strings.TrimSuffix(p, filepath.Ext(p)) + ".yaml"
+	}
+
+	return p
+}
+
+// isJSONOutput checks if an output path is written as json rather than yaml.
+func isJSONOutput(o options, p string) bool {
+	return util.HasExtension(p, jsonExt) || (p == stdio && o.Format == formatJSON)
+}
+
+// deriveOutPath derives the output path from the specified input directory and current path.
+func deriveOutPath(o options, p string, in string) string {
+	var segments []string
+	if rel, err := filepath.Rel(in, p); err == nil && rel != "." {
+		segments = util.SplitPath(rel)
+	}
+
+	var (
+		org  string
+		repo string
+		file string
+	)
+
+	switch {
+	case o.Output == stdio || util.HasExtension(o.Output, jobConfigExt):
+		return o.Output
+	case len(segments) >= 3:
+		org = segments[len(segments)-3]
+		repo = segments[len(segments)-2]
+		file = segments[len(segments)-1]
+		if newOrg, newRepo, ok := mapOrgRepo(o, org, repo); ok {
+			prefix := util.NormalizeOrg(org, filenameSeparator)
+			newPrefix := util.NormalizeOrg(newOrg, filenameSeparator)
+			// Rename the repo in the filename along with the org when the repo is renamed.
+			if repoPrefix := `^` + regexp.QuoteMeta(prefix+filenameSeparator+repo) + `\b`; newRepo != repo && util.MustCompile(repoPrefix).MatchString(file) {
+				filename := util.RenameFile(repoPrefix, file, newPrefix+filenameSeparator+newRepo)
+				return filepath.Join(o.Output, util.GetTopLevelOrg(newOrg), newRepo, filename)
+			}
+			filename := util.RenameFile(`^`+prefix+`\b`, file, newPrefix)
+			return filepath.Join(o.Output, util.GetTopLevelOrg(newOrg), newRepo, filename)
+		}
+	case len(segments) == 2:
+		org = segments[len(segments)-2]
+		file = segments[len(segments)-1]
+		if newOrg, ok := mapOrg(o, org); ok {
+			filename := util.RenameFile(`^`+util.NormalizeOrg(org, filenameSeparator)+`\b`, file, util.NormalizeOrg(newOrg, filenameSeparator))
+			return filepath.Join(o.Output, util.GetTopLevelOrg(newOrg), filename)
+		}
+	case len(segments) == 1:
+		file = segments[len(segments)-1]
+		return modifyFilename(o, file)
+	case len(segments) == 0:
+		file = filepath.Base(in)
+		return modifyFilename(o, file)
+	}
+
+	// Files of unmapped org/repo(s) are copied to the same relative path.
+	if o.CopyUnmapped && isUnmapped(o, org, repo) {
+		return filepath.Join(o.Output, org, repo, file)
+	}
+
+	return ""
+}
+
+// modifyFilename derives the output path of a file by prefixing it with the modifier, or removing the prefix when reversing.
+// Files already (or, when reversing, not) prefixed are skipped.
+func modifyFilename(o options, file string) string {
+	prefix := o.Modifier + filenameSeparator
+
+	if o.Reverse {
+		if strings.HasPrefix(file, prefix) {
+			return filepath.Join(o.Output, strings.TrimPrefix(file, prefix))
+		}
+		return ""
+	}
+
+	if !strings.HasPrefix(file, o.Modifier) {
+		return filepath.Join(o.Output, prefix+file)
+	}
+
+	return ""
+}
+
+// shardPath derives the path of a numbered output shard.
+func shardPath(p string, n int) string {
+	ext := filepath.Ext(p)
+	return strings.TrimSuffix(p, ext) + filenameSeparator + strconv.Itoa(n) + ext
+}
+
+// shardPaths returns the existing numbered output shards for a path.
+func shardPaths(p string) []string {
+	ext := filepath.Ext(p)
+	base := strings.TrimSuffix(filepath.Base(p), ext)
+
+	files, err := ioutil.ReadDir(filepath.Dir(p))
+	if err != nil {
+		return nil
+	}
+
+	shardRe := util.MustCompile(`^` + regexp.QuoteMeta(base+filenameSeparator) + `\d+` + regexp.QuoteMeta(ext) + `$`)
+
+	var paths []string
+	for _, f := range files {
+		if !f.IsDir() && shardRe.MatchString(f.Name()) {
+			paths = append(paths, filepath.Join(filepath.Dir(p), f.Name()))
+		}
+	}
+
+	return paths
+}
+
+// typePath derives the path of the output file of a job type.
+func typePath(p string, jobType string) string {
+	ext := filepath.Ext(p)
+	return strings.TrimSuffix(p, ext) + filenameSeparator + jobType + ext
+}
+
+// jobDir derives the directory of the output files of each job of a path.
+func jobDir(p string) string {
+	return strings.TrimSuffix(p, filepath.Ext(p))
+}
+
+// jobPath derives the path of the output file of a job.
+func jobPath(p string, name string) string {
+	return filepath.Join(jobDir(p), name+filepath.Ext(p))
+}
+
+// jobPaths returns the existing output files of each job of a path.
+func jobPaths(p string) []string {
+	files, err := ioutil.ReadDir(jobDir(p))
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, f := range files {
+		if !f.IsDir() && util.HasExtension(f.Name(), jobConfigExt) {
+			paths = append(paths, filepath.Join(jobDir(p), f.Name()))
+		}
+	}
+
+	return paths
+}
+
+// splitPaths returns the existing numbered output shards, job type files, including their shards,
+// and job files for a path.
+func splitPaths(p string) []string {
+	paths := shardPaths(p)
+
+	for _, jobType := range splitJobTypes {
+		tp := typePath(p, jobType)
+		if util.Exists(tp) {
+			paths = append(paths, tp)
+		}
+		paths = append(paths, shardPaths(tp)...)
+	}
+
+	return append(paths, jobPaths(p)...)
+}
+
+// getConsolidatedOutPath derives the output path for all jobs of an org/repo.
+func getConsolidatedOutPath(o options, orgrepo string) string {
+	if o.Output == stdio || util.HasExtension(o.Output, jobConfigExt) {
+		return o.Output
+	}
+
+	org, repo := util.SplitOrgRepo(orgrepo)
+
+	segments := []string{util.NormalizeOrg(org, filenameSeparator), repo}
+	if o.Modifier != "" && !o.Reverse {
+		segments = append(segments, o.Modifier)
+	}
+	filename := strings.Join(segments, filenameSeparator) + ".yaml"
+
+	return formatOutPath(o, filepath.Join(o.Output, util.GetTopLevelOrg(org), repo, filename))
+}
+
+// cleanOutFile deletes a path, any children, and any numbered output shards, job type files, or job files.
+func cleanOutFile(o options, p string) {
+	paths := append([]string{p}, splitPaths(p)...)
+	if util.Exists(jobDir(p)) {
+		paths = append(paths, jobDir(p))
+	}
+
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			reportErr(o, fmt.Sprintf("unable to clean file %v: %v.", path, err))
+		}
+	}
+}
+
+// errorTracker records errors concurrently.
+type errorTracker struct {
+	mu   sync.Mutex
+	errs []string
+}
+
+// runErrors are the errors of a generation run, shared by the copies of its options.
+type runErrors struct {
+	// gen are the transformation and write errors.
+	gen errorTracker
+	// input are the unreadable or invalid input files.
+	input errorTracker
+	// unmapped are the org/repo(s) whose job(s) are dropped because they are not in the mapping.
+	unmapped errorTracker
+}
+
+// withRunErrors returns the options of a new generation run that records its own errors.
+func withRunErrors(o options) options {
+	o.errs = &runErrors{}
+	return o
+}
+
+// add records an error.
+func (t *errorTracker) add(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errs = append(t.errs, msg)
+}
+
+// list returns the recorded errors in order.
+func (t *errorTracker) list() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.errs...)
+}
+
+// reportErr prints and records a transformation or write error of the generation run.
+func reportErr(o options, msg string) {
+	util.PrintErr(msg)
+	if o.errs != nil {
+		o.errs.gen.add(msg)
+	}
+}
+
+// reportInputErr records an unreadable or invalid input file of the generation run.
+func reportInputErr(o options, p string, err error) {
+	if o.errs != nil {
+		o.errs.input.add(fmt.Sprintf("%v: %v", p, err))
+	}
+}
+
+// reportUnmapped records an org/repo whose job(s) are dropped because it is not in the mapping.
+func reportUnmapped(o options, org string, repo string) {
+	if o.errs != nil && isUnmapped(o, org, repo) {
+		o.errs.unmapped.add(org + "/" + repo)
+	}
+}
+
+// genErrors returns the transformation and write errors of the generation run.
+func genErrors(o options) []string {
+	if o.errs == nil {
+		return nil
+	}
+	return o.errs.gen.list()
+}
+
+// inputErrors returns the unreadable or invalid input files of the generation run.
+func inputErrors(o options) []string {
+	if o.errs == nil {
+		return nil
+	}
+	return o.errs.input.list()
+}
+
+// failedFast returns whether generation should be aborted after an error.
+func failedFast(o options) bool {
+	return o.FailFast && len(genErrors(o)) > 0
+}
+
+// errGenerate returns a summary of the errors of the generation run.
+func errGenerate(o options) error {
+	errs := genErrors(o)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d error(s) generating job(s) from %v:", len(errs), strings.Join(o.inputPaths(), ", "))
+	for _, msg := range errs {
+		fmt.Fprintf(&b, "\n  %v", msg)
+	}
+
+	return &util.ExitError{Message: b.String(), Code: 1}
+}
+
+// errStrict returns the unreadable or invalid input files of the generation run.
+func errStrict(o options) error {
+	errs := inputErrors(o)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--strict option: %d input file(s) are unreadable or invalid:", len(errs))
+	for _, msg := range errs {
+		fmt.Fprintf(&b, "\n  %v", msg)
+	}
+
+	return &util.ExitError{Message: b.String(), Code: 1}
+}
+
+func handleRecover() {
+	if r := recover(); r != nil {
+		switch t := r.(type) {
+		case string:
+			util.PrintErrAndExit(errors.New(t))
+		case error:
+			util.PrintErrAndExit(t)
+		default:
+			util.PrintErrAndExit(errors.New("unknown panic"))
+		}
+	}
+}
+
+// jobSet is the collection of generated jobs and presets destined for a single output path.
+type jobSet struct {
+	presubmits  map[string][]config.Presubmit
+	postsubmits map[string][]config.Postsubmit
+	periodics   []config.Periodic
+	presets     []config.Preset
+	slack       slackExtras
+	decoration  decorationExtras
+	sources     map[string]string
+}
+
+// newJobSet returns an empty jobSet.
+func newJobSet() *jobSet {
+	return &jobSet{
+		presubmits:  map[string][]config.Presubmit{},
+		postsubmits: map[string][]config.Postsubmit{},
+		periodics:   []config.Periodic{},
+		slack:       slackExtras{},
+		decoration:  decorationExtras{},
+		sources:     map[string]string{},
+	}
+}
+
+// empty checks if the jobSet contains no jobs or presets.
+func (s *jobSet) empty() bool {
+	return len(s.presubmits) == 0 && len(s.postsubmits) == 0 && len(s.periodics) == 0 && len(s.presets) == 0
+}
+
+// size returns the total number of jobs in the jobSet.
+func (s *jobSet) size() int {
+	n := len(s.periodics)
+	for _, pre := range s.presubmits {
+		n += len(pre)
+	}
+	for _, post := range s.postsubmits {
+		n += len(post)
+	}
+	return n
+}
+
+// split divides the jobSet into shards containing at most max jobs each.
+// Jobs are distributed in order: presubmits and postsubmits by org/repo, followed by periodics.
+// Presets are always written to the first shard.
+func (s *jobSet) split(max int) []*jobSet {
+	shards := []*jobSet{newJobSet()}
+	shards[0].presets = s.presets
+
+	next := func() *jobSet {
+		cur := shards[len(shards)-1]
+		if cur.size() >= max {
+			cur = newJobSet()
+			shards = append(shards, cur)
+		}
+		return cur
+	}
+
+	for _, orgrepo := range sortedOrgRepos(s.presubmits) {
+		for _, job := range s.presubmits[orgrepo] {
+			cur := next()
+			cur.presubmits[orgrepo] = append(cur.presubmits[orgrepo], job)
+		}
+	}
+	for _, orgrepo := range sortedOrgRepos(s.postsubmits) {
+		for _, job := range s.postsubmits[orgrepo] {
+			cur := next()
+			cur.postsubmits[orgrepo] = append(cur.postsubmits[orgrepo], job)
+		}
+	}
+	for _, job := range s.periodics {
+		cur := next()
+		cur.periodics = append(cur.periodics, job)
+	}
+
+	for _, shard := range shards {
+		shard.slack = s.slack
+		shard.decoration = s.decoration
+		shard.sources = s.sources
+	}
+
+	return shards
+}
+
+// sortedOrgRepos returns the sorted org/repo keys of a presubmit or postsubmit map.
+func sortedOrgRepos(m interface{}) []string {
+	var keys []string
+
+	switch jobs := m.(type) {
+	case map[string][]config.Presubmit:
+		for k := range jobs {
+			keys = append(keys, k)
+		}
+	case map[string][]config.Postsubmit:
+		for k := range jobs {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// byType groups the jobs by their type, keyed by the name of the job type files.
+// Presets are assigned to the first job type group so that they are emitted only once.
+func (s *jobSet) byType() map[string]*jobSet {
+	groups := map[string]*jobSet{}
+
+	group := func(jobType string) *jobSet {
+		if _, exists := groups[jobType]; !exists {
+			groups[jobType] = newJobSet()
+		}
+		return groups[jobType]
+	}
+
+	if len(s.presubmits) > 0 {
+		group(splitJobTypes[0]).presubmits = s.presubmits
+	}
+	if len(s.postsubmits) > 0 {
+		group(splitJobTypes[1]).postsubmits = s.postsubmits
+	}
+	if len(s.periodics) > 0 {
+		group(splitJobTypes[2]).periodics = s.periodics
+	}
+
+	if len(s.presets) > 0 {
+		for _, jobType := range splitJobTypes {
+			if _, exists := groups[jobType]; exists || len(groups) == 0 {
+				group(jobType).presets = s.presets
+				break
+			}
+		}
+	}
+
+	for _, group := range groups {
+		group.slack = s.slack
+		group.decoration = s.decoration
+		group.sources = s.sources
+	}
+
+	return groups
+}
+
+// setSource records the source file of all jobs in the jobSet.
+func (s *jobSet) setSource(source string) {
+	s.eachJob(func(key string) {
+		s.sources[key] = source
+	})
+}
+
+// sourceFiles returns the sorted source files of the jobs in the jobSet.
+func (s *jobSet) sourceFiles() []string {
+	files := sets.NewString()
+	s.eachJob(func(key string) {
+		if source, ok := s.sources[key]; ok {
+			files.Insert(source)
+		}
+	})
+	return files.List()
+}
+
+// eachJob calls fn with the key of each job in the jobSet.
+func (s *jobSet) eachJob(fn func(key string)) {
+	for orgrepo, pre := range s.presubmits {
+		for _, job := range pre {
+			fn(jobKey("presubmit", orgrepo, job.Name))
+		}
+	}
+	for orgrepo, post := range s.postsubmits {
+		for _, job := range post {
+			fn(jobKey("postsubmit", orgrepo, job.Name))
+		}
+	}
+	for _, job := range s.periodics {
+		fn(jobKey("periodic", "", job.Name))
+	}
+}
+
+// byJob groups the jobs by their name.
+// Presets are assigned to the first job group so that they are emitted only once.
+func (s *jobSet) byJob() map[string]*jobSet {
+	groups := map[string]*jobSet{}
+
+	group := func(name string) *jobSet {
+		if _, exists := groups[name]; !exists {
+			groups[name] = newJobSet()
+		}
+		return groups[name]
+	}
+
+	for orgrepo, pre := range s.presubmits {
+		for _, job := range pre {
+			group(job.Name).presubmits[orgrepo] = append(group(job.Name).presubmits[orgrepo], job)
+		}
+	}
+	for orgrepo, post := range s.postsubmits {
+		for _, job := range post {
+			group(job.Name).postsubmits[orgrepo] = append(group(job.Name).postsubmits[orgrepo], job)
+		}
+	}
+	for _, job := range s.periodics {
+		group(job.Name).periodics = append(group(job.Name).periodics, job)
+	}
+
+	if len(s.presets) > 0 && len(groups) > 0 {
+		keys := make([]string, 0, len(groups))
+		for name := range groups {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		groups[keys[0]].presets = s.presets
+	}
+
+	for _, group := range groups {
+		group.slack = s.slack
+		group.decoration = s.decoration
+		group.sources = s.sources
+	}
+
+	return groups
+}
+
+// byOrgRepo groups the jobs by their org/repo.
+// Periodics are grouped by the first extra ref that targets a mapped org, or under the empty org/repo without extra refs.
+// Presets are assigned to the first org/repo group so that they are emitted only once.
+func (s *jobSet) byOrgRepo(o options) map[string]*jobSet {
+	groups := map[string]*jobSet{}
+
+	group := func(orgrepo string) *jobSet {
+		if _, exists := groups[orgrepo]; !exists {
+			groups[orgrepo] = newJobSet()
+		}
+		return groups[orgrepo]
+	}
+
+	for orgrepo, pre := range s.presubmits {
+		group(orgrepo).presubmits[orgrepo] = pre
+	}
+	for orgrepo, post := range s.postsubmits {
+		group(orgrepo).postsubmits[orgrepo] = post
+	}
+
+	targets := mappedOrgs(o)
+
+	for _, job := range s.periodics {
+		var orgrepo string
+		for _, ref := range job.ExtraRefs {
+			if targets.Has(ref.Org) {
+				orgrepo = ref.Org + "/" + ref.Repo
+				break
+			}
+		}
+		if orgrepo == "" && len(job.ExtraRefs) > 0 {
+			orgrepo = job.ExtraRefs[0].Org + "/" + job.ExtraRefs[0].Repo
+		}
+		group(orgrepo).periodics = append(group(orgrepo).periodics, job)
+	}
+
+	if len(s.presets) > 0 && len(groups) > 0 {
+		keys := make([]string, 0, len(groups))
+		for orgrepo := range groups {
+			keys = append(keys, orgrepo)
+		}
+		sort.Strings(keys)
+		if keys[0] == "" && len(keys) > 1 {
+			keys = keys[1:]
+		}
+		groups[keys[0]].presets = s.presets
+	}
+
+	for _, group := range groups {
+		group.slack = s.slack
+		group.decoration = s.decoration
+		group.sources = s.sources
+	}
+
+	return groups
+}
+
+// merge appends all jobs and any presets not already present from another jobSet.
+func (s *jobSet) merge(other *jobSet) {
+	for orgrepo, pre := range other.presubmits {
+		s.presubmits[orgrepo] = append(s.presubmits[orgrepo], pre...)
+	}
+	for orgrepo, post := range other.postsubmits {
+		s.postsubmits[orgrepo] = append(s.postsubmits[orgrepo], post...)
+	}
+	s.periodics = append(s.periodics, other.periodics...)
+
+	for key, fields := range other.slack {
+		s.slack[key] = fields
+	}
+	for key, fields := range other.decoration {
+		s.decoration[key] = fields
+	}
+	for key, source := range other.sources {
+		s.sources[key] = source
+	}
+
+preset:
+	for _, preset := range other.presets {
+		for _, existing := range s.presets {
+			if reflect.DeepEqual(existing, preset) {
+				continue preset
+			}
+		}
+		s.presets = append(s.presets, preset)
+	}
+}
+
+// outBufPool is a pool of buffers reused for rendering output files.
+var outBufPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// writeOutFile writes all jobs definitions to the designated output path and returns the jobSet of each written file.
+// The output is split into numbered shards when it exceeds the maximum number of jobs per file.
+func writeOutFile(o options, p string, jobs *jobSet) map[string]*jobSet {
+	if jobs.empty() {
+		return nil
+	}
+
+	files, stale := layoutOutFile(o, p, jobs, p != stdio)
+
+	for _, fp := range sortedJobSetPaths(files) {
+		writeJobSet(o, fp, outHeader(o, files[fp].sourceFiles()), files[fp], isJSONOutput(o, fp))
+	}
+
+	// Remove previously written files that are no longer part of the output.
+	for _, sp := range stale {
+		if err := os.Remove(sp); err != nil {
+			reportErr(o, fmt.Sprintf("unable to clean file %v: %v.", sp, err))
+		}
+	}
+
+	return files
+}
+
+// layoutOutFile returns the jobSet of each file written for the jobs of an output path, combined with
+// the jobs of the existing file(s) when requested, and the existing file(s) no longer part of the output.
+func layoutOutFile(o options, p string, jobs *jobSet, existing bool) (map[string]*jobSet, []string) {
+	combined := newJobSet()
+	stale := sets.NewString()
+
+	if existing {
+		for _, existingPath := range append([]string{p}, splitPaths(p)...) {
+			existingJobs, err := config.ReadJobConfig(existingPath)
+			if err != nil {
+				continue
+			}
+
+			stale.Insert(existingPath)
+			combined.merge(&jobSet{
+				presubmits:  existingJobs.PresubmitsStatic,
+				postsubmits: existingJobs.PostsubmitsStatic,
+				periodics:   existingJobs.Periodics,
+				presets:     existingJobs.Presets,
+				slack:       readSlackExtras(existingPath),
+				decoration:  readDecorationExtras(existingPath),
+			})
+		}
+	}
+
+	// Combine presubmits, postsubmits, and periodics
+	combined.merge(jobs)
+
+	// Sort presubmits, postsubmits, and periodics
+	sortJobs(o, combined.presubmits, combined.postsubmits, combined.periodics)
+
+	parts := map[string]*jobSet{p: combined}
+	if o.SplitByType {
+		parts = map[string]*jobSet{}
+		for jobType, part := range combined.byType() {
+			parts[typePath(p, jobType)] = part
+		}
+	}
+	if o.SplitByJob && combined.size() > 0 {
+		parts = map[string]*jobSet{}
+		for name, part := range combined.byJob() {
+			parts[jobPath(p, name)] = part
+		}
+	}
+
+	files := map[string]*jobSet{}
+
+	for pp, part := range parts {
+		if o.MaxJobsPerFile == 0 || part.size() <= o.MaxJobsPerFile {
+			stale.Delete(pp)
+			files[pp] = part
+			continue
+		}
+		for i, shard := range part.split(o.MaxJobsPerFile) {
+			sp := shardPath(pp, i+1)
+			stale.Delete(sp)
+			files[sp] = shard
+		}
+	}
+
+	return files, stale.List()
+}
+
+// sortedJobSetPaths returns the paths of the jobSets in sorted order.
+func sortedJobSetPaths(files map[string]*jobSet) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// outHeader returns the header written at the top of each output file, followed by the provenance of
+// the file generated from the source file(s).
+func outHeader(o options, sources []string) string {
+	var b strings.Builder
+
+	b.WriteString(o.header)
+	if o.header == "" {
+		b.WriteString(autogenHeader)
+	}
+
+	if o.Provenance && len(sources) > 0 {
+		b.WriteString(sourcePrefix + strings.Join(sources, ", ") + "\n")
+	}
+	if o.SourceSHA != "" {
+		b.WriteString(provenancePrefix + o.SourceSHA + "\n")
+	}
+	if o.Provenance {
+		b.WriteString(versionPrefix + version + "\n")
+		b.WriteString(flagsHashPrefix + flagsHash(o) + "\n")
+	}
+
+	return b.String()
+}
+
+// commentHeader returns a header with every line written as a comment.
+func commentHeader(header string) string {
+	var b strings.Builder
+
+	for _, line := range strings.Split(strings.TrimRight(header, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "#"):
+			b.WriteString(line)
+		case strings.TrimSpace(line) == "":
+			b.WriteString("#")
+		default:
+			b.WriteString("# " + line)
+		}
+		b.WriteString("\n")
+	}
 
-// convertOrgRepoStr translates the provided job org and repo based on the specified org mapping.
-func convertOrgRepoStr(o options, s string) string {
-	org, repo := util.SplitOrgRepo(s)
+	return b.String()
+}
 
-	valid := validateOrgRepo(o, org, repo)
+// flagsHash returns a short hash of the transformation options, excluding the input and output paths,
+// so that files generated with different options can be told apart.
+func flagsHash(o options) string {
+	t := o.transform
+	t.Input, t.Output, t.InputRepo, t.InputRef, t.SourceSHA = "", "", "", "", ""
 
-	if !valid {
+	b, err := json.Marshal(t)
+	if err != nil {
 		return ""
 	}
 
-	return strings.Join([]string{o.OrgMap[org], repo}, "/")
+	return fmt.Sprintf("%x", sha256.Sum256(b))[:12]
 }
 
-// combinePresets reads a list of paths and aggregates the presets.
-func combinePresets(paths []string) []config.Preset {
-	presets := []config.Preset{}
+// readHeader returns the leading comment lines of an output file.
+func readHeader(p string) (string, error) {
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
 
-	if len(paths) == 0 {
-		return presets
+	var b strings.Builder
+	for _, line := range strings.Split(string(d), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		b.WriteString(line + "\n")
 	}
 
-	for _, p := range paths {
-		c, err := config.ReadJobConfig(p)
-		if err != nil {
-			continue
+	return b.String(), nil
+}
+
+// readProvenance returns the source SHA recorded in the header of an output file.
+func readProvenance(p string) (string, error) {
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(d), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if strings.HasPrefix(line, provenancePrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, provenancePrefix)), nil
 		}
-		presets = append(presets, c.Presets...)
 	}
 
-	return presets
+	return "", nil
 }
 
-// mergePreset merges a preset into a job Spec based on defined labels.
-func mergePreset(labels map[string]string, job *config.JobBase, preset config.Preset) {
-	for l, v := range preset.Labels {
-		if v2, exists := labels[l]; !exists || v != v2 {
+// writeJobSet renders and writes a jobSet with a header to a path, or as json without a header.
+func writeJobSet(o options, p string, header string, jobs *jobSet, asJSON bool) {
+	jobConfigYaml, err := renderJobSet(p, jobs)
+	if err != nil {
+		reportErr(o, fmt.Sprintf("%v.", err))
+		return
+	}
+
+	buf := outBufPool.Get().(*bytes.Buffer)
+	defer outBufPool.Put(buf)
+	buf.Reset()
+
+	if asJSON {
+		jobConfigJSON, err := yaml.YAMLToJSON(jobConfigYaml)
+		if err != nil {
+			reportErr(o, fmt.Sprintf("unable to convert jobs to json for path %v: %v.", p, err))
 			return
 		}
+		if err := json.Indent(buf, jobConfigJSON, "", "  "); err != nil {
+			reportErr(o, fmt.Sprintf("unable to format jobs as json for path %v: %v.", p, err))
+			return
+		}
+		buf.WriteString("\n")
+	} else {
+		buf.WriteString(header)
+		buf.Write(jobConfigYaml)
 	}
 
-	for _, env := range preset.Env {
-	econtainer:
-		for i := range job.Spec.Containers {
-			for j := range job.Spec.Containers[i].Env {
-				if job.Spec.Containers[i].Env[j].Name == env.Name {
-					job.Spec.Containers[i].Env[j].Value = env.Value
-					continue econtainer
+	if p == stdio {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			reportErr(o, fmt.Sprintf("unable to write jobs to stdout: %v.", err))
+		}
+		return
+	}
+
+	dir := filepath.Dir(p)
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to create output directory %v: %v.", dir, err))
+	}
+
+	err = ioutil.WriteFile(p, buf.Bytes(), 0644)
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to write jobs to path %v: %v.", p, err))
+	}
+}
+
+// renderJobSet renders the job config yaml of a jobSet written to a path.
+func renderJobSet(p string, jobs *jobSet) ([]byte, error) {
+	jobConfig := config.JobConfig{}
+
+	if err := jobConfig.SetPresubmits(jobs.presubmits); err != nil {
+		return nil, fmt.Errorf("unable to set presubmits for path %v: %v", p, err)
+	}
+
+	if err := jobConfig.SetPostsubmits(jobs.postsubmits); err != nil {
+		return nil, fmt.Errorf("unable to set postsubmits for path %v: %v", p, err)
+	}
+
+	jobConfig.Periodics = jobs.periodics
+	jobConfig.Presets = jobs.presets
+
+	jobConfigYaml, err := yaml.Marshal(jobConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal job config output directory: %v", err)
+	}
+
+	if len(jobs.slack) > 0 {
+		if jobConfigYaml, err = patchSlackExtras(jobConfigYaml, jobs.slack); err != nil {
+			return nil, fmt.Errorf("unable to set slack reporter fields for path %v: %v", p, err)
+		}
+	}
+
+	if len(jobs.decoration) > 0 {
+		if jobConfigYaml, err = patchDecorationExtras(jobConfigYaml, jobs.decoration); err != nil {
+			return nil, fmt.Errorf("unable to set decoration config fields for path %v: %v", p, err)
+		}
+	}
+
+	return jobConfigYaml, nil
+}
+
+// transformJobs applies all transformations to the jobs of a parsed job config.
+func transformJobs(o options, jobs *config.JobConfig, presets *presetIndex) *jobSet {
+	out := newJobSet()
+
+	// Presubmits
+	for orgrepo, pre := range jobs.PresubmitsStatic {
+		org, repo := util.SplitOrgRepo(orgrepo)
+		if !inTenant(o, org) {
+			continue
+		}
+
+		if o.CopyUnmapped && isUnmapped(o, org, repo) {
+			for _, job := range pre {
+				if validateJob(o, job.JobBase, job.Brancher, "presubmit") && !isSkippedOptional(o, job) {
+					out.presubmits[orgrepo] = append(out.presubmits[orgrepo], job)
 				}
 			}
+			continue
+		}
 
-			job.Spec.Containers[i].Env = append(job.Spec.Containers[i].Env, env)
+		o := withRepoOptions(o, org, repo)
+
+		host := mapGitHost(o, org, repo)
+		orgrepo = convertOrgRepoStr(o, orgrepo)
+		if orgrepo == "" {
+			reportUnmapped(o, org, repo)
+			continue
+		}
+
+		o = withRepoBucket(o, orgrepo)
+
+		for _, job := range pre {
+			valid := validateJob(o, job.JobBase, job.Brancher, "presubmit")
+			if !valid || isSkippedOptional(o, job) {
+				continue
+			}
+
+			name := job.Name
+
+			migrateBootstrap(o, &job.JobBase, &job.UtilityConfig, orgrepo)
+			updateExtraRefs(o, &job.UtilityConfig)
+			updatePathAlias(o, &job.UtilityConfig, orgrepo, host)
+			updateJobBase(o, &job.JobBase, orgrepo, host)
+			updateBrancher(o, &job.Brancher)
+			updateMaxConcurrency(o, &job.JobBase)
+			updateUtilityConfig(o, &job.UtilityConfig)
+			updateChangeMatcher(o, &job.RegexpChangeMatcher)
+			updateReporter(o, &job.Reporter)
+			updateRunPolicy(o, &job)
+			updateTriggers(o, &job, name)
+			updateGerritReportingLabels(o, job.SkipReport, job.Optional, job.Labels)
+			updateSlackExtras(o, out.slack, jobKey("presubmit", orgrepo, job.Name), job.ReporterConfig)
+			updateDecorationExtras(o, out.decoration, jobKey("presubmit", orgrepo, job.Name), job.DecorationConfig)
+			resolvePresets(o, job.Labels, &job.JobBase, presets)
+			injectVolumes(o, &job.JobBase)
+			updateSecretNames(o, &job.JobBase)
+			pruneJobBase(o, &job.JobBase)
+
+			out.presubmits[orgrepo] = append(out.presubmits[orgrepo], job)
 		}
 	}
 
-volume:
-	for _, vol := range preset.Volumes {
+	// Postsubmits
+	for orgrepo, post := range jobs.PostsubmitsStatic {
+		org, repo := util.SplitOrgRepo(orgrepo)
+		if !inTenant(o, org) {
+			continue
+		}
 
-		for i := range job.Spec.Volumes {
-			if job.Spec.Volumes[i].Name == vol.Name {
-				job.Spec.Volumes[i] = vol
-				continue volume
+		if o.CopyUnmapped && isUnmapped(o, org, repo) {
+			for _, job := range post {
+				if validateJob(o, job.JobBase, job.Brancher, "postsubmit") {
+					out.postsubmits[orgrepo] = append(out.postsubmits[orgrepo], job)
+				}
 			}
+			continue
 		}
 
-		job.Spec.Volumes = append(job.Spec.Volumes, vol)
+		o := withRepoOptions(o, org, repo)
+
+		host := mapGitHost(o, org, repo)
+		orgrepo = convertOrgRepoStr(o, orgrepo)
+		if orgrepo == "" {
+			reportUnmapped(o, org, repo)
+			continue
+		}
+
+		o = withRepoBucket(o, orgrepo)
+
+		for _, job := range post {
+			valid := validateJob(o, job.JobBase, job.Brancher, "postsubmit")
+			if !valid {
+				continue
+			}
+
+			migrateBootstrap(o, &job.JobBase, &job.UtilityConfig, orgrepo)
+			updateExtraRefs(o, &job.UtilityConfig)
+			updatePathAlias(o, &job.UtilityConfig, orgrepo, host)
+			updateJobBase(o, &job.JobBase, orgrepo, host)
+			updateBrancher(o, &job.Brancher)
+			updateMaxConcurrency(o, &job.JobBase)
+			updateUtilityConfig(o, &job.UtilityConfig)
+			updateChangeMatcher(o, &job.RegexpChangeMatcher)
+			updateSlackExtras(o, out.slack, jobKey("postsubmit", orgrepo, job.Name), job.ReporterConfig)
+			updateDecorationExtras(o, out.decoration, jobKey("postsubmit", orgrepo, job.Name), job.DecorationConfig)
+			resolvePresets(o, job.Labels, &job.JobBase, presets)
+			injectVolumes(o, &job.JobBase)
+			updateSecretNames(o, &job.JobBase)
+			pruneJobBase(o, &job.JobBase)
+
+			out.postsubmits[orgrepo] = append(out.postsubmits[orgrepo], job)
+
+			if periodic, ok := postsubmitPeriodic(o, job, orgrepo); ok {
+				out.periodics = append(out.periodics, periodic)
+			}
+		}
 	}
 
-	for _, volm := range preset.VolumeMounts {
-	vcontainer:
-		for i := range job.Spec.Containers {
-			for j := range job.Spec.Containers[i].VolumeMounts {
-				if job.Spec.Containers[i].VolumeMounts[j].Name == volm.Name {
-					job.Spec.Containers[i].VolumeMounts[j] = volm
-					continue vcontainer
+	// Periodic
+	for _, job := range jobs.Periodics {
+		if o.CopyUnmapped && o.tenant == "" && len(job.ExtraRefs) > 0 && allRefs(job.ExtraRefs, func(val prowjob.Refs, idx int) bool {
+			return isUnmapped(o, val.Org, val.Repo)
+		}) {
+			branches := make([]string, 0, len(job.ExtraRefs))
+			for _, ref := range job.ExtraRefs {
+				branches = append(branches, ref.BaseRef)
+			}
+			if validateJob(o, job.JobBase, config.Brancher{Branches: branches}, "periodic") {
+				out.periodics = append(out.periodics, job)
+			}
+			continue
+		}
+
+		migrateBootstrap(o, &job.JobBase, &job.UtilityConfig, "")
+
+		if len(job.ExtraRefs) == 0 {
+			continue
+		}
+
+		if allRefs(job.ExtraRefs, func(val prowjob.Refs, idx int) bool {
+			return !validateOrgRepo(o, val.Org, val.Repo)
+		}) {
+			if allRefs(job.ExtraRefs, func(val prowjob.Refs, idx int) bool {
+				return isUnmapped(o, val.Org, val.Repo)
+			}) {
+				for _, ref := range job.ExtraRefs {
+					reportUnmapped(o, ref.Org, ref.Repo)
 				}
 			}
+			continue
+		}
 
-			job.Spec.Containers[i].VolumeMounts = append(job.Spec.Containers[i].VolumeMounts, volm)
+		if !refsInTenant(o, job.ExtraRefs) {
+			continue
+		}
+
+		org, repo := refsOrgRepo(o, job.ExtraRefs)
+		o := withRepoOptions(o, org, repo)
+		if newOrg, newRepo, ok := mapOrgRepo(o, org, repo); ok {
+			o = withRepoBucket(o, newOrg+"/"+newRepo)
 		}
+
+		branches := make([]string, 0)
+		for _, ref := range job.ExtraRefs {
+			if validateOrgRepo(o, ref.Org, ref.Repo) {
+				branches = append(branches, ref.BaseRef)
+			}
+		}
+		if !validateJob(o, job.JobBase, config.Brancher{Branches: branches}, "periodic") {
+			continue
+		}
+
+		updateExtraRefs(o, &job.UtilityConfig)
+		updateJobBase(o, &job.JobBase, "", "")
+		updateUtilityConfig(o, &job.UtilityConfig)
+		updateSchedule(o, &job)
+		updateSlackExtras(o, out.slack, jobKey("periodic", "", job.Name), job.ReporterConfig)
+		updateDecorationExtras(o, out.decoration, jobKey("periodic", "", job.Name), job.DecorationConfig)
+		resolvePresets(o, job.Labels, &job.JobBase, presets)
+		injectVolumes(o, &job.JobBase)
+		updateSecretNames(o, &job.JobBase)
+		pruneJobBase(o, &job.JobBase)
+
+		out.periodics = append(out.periodics, job)
 	}
+
+	return out
 }
 
-// resolvePresets resolves all preset for a particular job Spec based on defined labels.
-func resolvePresets(o options, labels map[string]string, job *config.JobBase, presets []config.Preset) {
-	if !o.Resolve {
-		return
+// fileResult is the outcome of transforming a single input file.
+type fileResult struct {
+	outPath string
+	jobs    *jobSet
+}
+
+// outputs returns the generated jobs of the file keyed by output path.
+func (res *fileResult) outputs(o options) map[string]*jobSet {
+	if !o.Consolidate {
+		return map[string]*jobSet{res.outPath: res.jobs}
 	}
 
-	if job.Spec != nil {
-		for _, preset := range presets {
-			mergePreset(labels, job, preset)
+	outputs := map[string]*jobSet{}
+	for orgrepo, jobs := range res.jobs.byOrgRepo(o) {
+		outPath := res.outPath
+		if orgrepo != "" {
+			outPath = getConsolidatedOutPath(o, orgrepo)
+		} else if outPath == "" {
+			continue
+		}
+		if _, exists := outputs[outPath]; !exists {
+			outputs[outPath] = newJobSet()
 		}
+		outputs[outPath].merge(jobs)
 	}
+
+	return outputs
 }
 
-// pruneJobBase prunes denylisted fields from the job Spec.
-func pruneJobBase(o options, job *config.JobBase) {
-	if job.Spec != nil {
-		if len(o.VolumeDenylistSet) > 0 {
-			pruneVolumes(o.VolumeDenylistSet, job)
-		}
-		if len(o.EnvDenylistSet) > 0 {
-			pruneEnvs(o.EnvDenylistSet, job)
-		}
+// transformFile reads and transforms a single input file.
+func transformFile(o options, p string, presets []config.Preset) *fileResult {
+	outPath := getOutPath(o, p, inputRoot(o, p))
+	// With --strict-mapping, the files without an output path are still transformed to record their unmapped org/repo(s).
+	if outPath == "" && !o.Consolidate && !o.StrictMapping {
+		return nil
+	}
+
+	res := &fileResult{outPath: outPath, jobs: newJobSet()}
+
+	jobs, err := config.ReadJobConfig(p)
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to read jobs from path %v: %v.", p, err))
+		reportInputErr(o, p, err)
+		return res
+	}
+
+	// Copy the presets so that appending file-local presets never aliases the shared slice.
+	filePresets := make([]config.Preset, 0, len(presets)+len(jobs.Presets))
+	filePresets = append(filePresets, presets...)
+	filePresets = append(filePresets, jobs.Presets...)
+
+	res.jobs = transformJobs(o, &jobs, newPresetIndex(filePresets))
+	res.jobs.setSource(sourcePath(o, p))
+
+	if o.EmitPresets {
+		res.jobs.presets = translatePresets(o, jobs.Presets)
 	}
+
+	return res
 }
 
-// pruneEnvs prunes denylisted Env fields.
-func pruneEnvs(denylist sets.String, job *config.JobBase) {
-	for i := range job.Spec.Containers {
-		var envs []v1.EnvVar
+// sourcePath returns the path of an input file relative to the input containing it.
+func sourcePath(o options, p string) string {
+	root := inputRoot(o, p)
+	if util.IsFile(root) {
+		return filepath.Base(p)
+	}
 
-		for _, env := range job.Spec.Containers[i].Env {
-			if denylist.Has(env.Name) {
-				continue
-			}
-			envs = append(envs, env)
-		}
-		job.Spec.Containers[i].Env = envs
+	if rel, err := filepath.Rel(root, p); err == nil {
+		return filepath.ToSlash(rel)
 	}
+
+	return p
 }
 
-// pruneVolumes prunes denylisted Volume and VolueMount fields.
-func pruneVolumes(denylist sets.String, job *config.JobBase) {
-	var volumes []v1.Volume
+// resolveInputs checks out the --input-repo, reads stdin and downloads the URL input(s) and preset(s) into a
+// temporary directory, returning the options with the resolved paths and a function removing the directory.
+func resolveInputs(o options) (options, func(), error) {
+	noop := func() {}
 
-	for _, vol := range job.Spec.Volumes {
-		if denylist.Has(vol.Name) {
-			continue
+	remote := o.InputRepo != ""
+	for _, in := range append(o.inputPaths(), o.Presets...) {
+		remote = remote || in == stdio || isURL(in)
+	}
+	if !remote {
+		return o, noop, nil
+	}
+
+	dir, err := ioutil.TempDir("", "genjobs")
+	if err != nil {
+		return o, noop, &util.ExitError{Message: fmt.Sprintf("unable to create temporary input directory: %v.", err), Code: 1}
+	}
+	cleanup := func() {
+		os.RemoveAll(dir)
+	}
+
+	resolved, err := resolveInputsInto(o, dir)
+	if err != nil {
+		cleanup()
+		return o, noop, err
+	}
+
+	return resolved, cleanup, nil
+}
+
+// resolveInputsInto resolves the input(s) and preset(s) of the options into a directory.
+func resolveInputsInto(o options, dir string) (options, error) {
+	var (
+		inputDir string
+		err      error
+	)
+
+	if o.InputRepo != "" {
+		inputDir = filepath.Join(dir, "repo")
+		if err := checkoutInputRepo(o.InputRepo, o.InputRef, inputDir); err != nil {
+			return o, &util.ExitError{Message: fmt.Sprintf("--input-repo option unable to check out %v@%v: %v.", o.InputRepo, o.InputRef, err), Code: 1}
 		}
-		volumes = append(volumes, vol)
 	}
-	job.Spec.Volumes = volumes
 
-	for i := range job.Spec.Containers {
-		var volumeMounts []v1.VolumeMount
+	inputs := make([]string, 0, len(o.inputPaths()))
+	for i, in := range o.inputPaths() {
+		inDir := filepath.Join(dir, fmt.Sprintf("input-%d", i))
+		if in == stdio {
+			if in, err = readStdin(inDir); err != nil {
+				return o, &util.ExitError{Message: fmt.Sprintf("-i, --input option unable to read stdin: %v.", err), Code: 1}
+			}
+		} else if isURL(in) {
+			url := in
+			if in, err = downloadInput(url, inDir); err != nil {
+				return o, &util.ExitError{Message: fmt.Sprintf("-i, --input option unable to download %v: %v.", url, err), Code: 1}
+			}
+		} else if inputDir != "" && !filepath.IsAbs(in) {
+			in = filepath.Join(inputDir, in)
+		}
+		inputs = append(inputs, in)
+	}
 
-		for _, volm := range job.Spec.Containers[i].VolumeMounts {
-			if denylist.Has(volm.Name) {
-				continue
+	presets := make([]string, 0, len(o.Presets))
+	for i, c := range o.Presets {
+		if isURL(c) {
+			url := c
+			if c, err = downloadInput(url, filepath.Join(dir, fmt.Sprintf("preset-%d", i))); err != nil {
+				return o, &util.ExitError{Message: fmt.Sprintf("-p, --preset option unable to download %v: %v.", url, err), Code: 1}
 			}
-			volumeMounts = append(volumeMounts, volm)
 		}
-		job.Spec.Containers[i].VolumeMounts = volumeMounts
+		presets = append(presets, c)
+	}
+
+	o.inputs = inputs
+	o.Input = inputs[0]
+	o.Presets = presets
+
+	return o, nil
+}
+
+// readStdin reads the job(s) from stdin into an input file in a directory and returns its path.
+func readStdin(dir string) (string, error) {
+	d, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+
+	return writeTempInput(dir, stdinFilename, d)
+}
+
+// isURL checks if an input is an HTTP(S) URL.
+func isURL(in string) bool {
+	return strings.HasPrefix(in, "https://") || strings.HasPrefix(in, "http://")
+}
+
+// downloadInput downloads an HTTP(S) URL into an input file in a directory named after the URL path and returns its
+// path.
+func downloadInput(url string, dir string) (string, error) {
+	client := &http.Client{Timeout: downloadTimeout}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %v", resp.Status)
+	}
+
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	name := path.Base(resp.Request.URL.Path)
+	if !util.HasExtension(name, jobConfigExt) {
+		return "", fmt.Errorf("path is not a yaml or json file: %v", name)
 	}
+
+	return writeTempInput(dir, name, d)
 }
 
-// updateJobName updates the jobs Name fields based on provided inputs.
-func updateJobName(o options, job *config.JobBase) {
-	suffix := ""
+// writeTempInput writes the content of an input file into a directory and returns its path.
+func writeTempInput(dir string, name string, d []byte) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
 
-	if o.Modifier != "" {
-		suffix = jobnameSeparator + o.Modifier
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, d, 0644); err != nil {
+		return "", err
 	}
 
-	if !o.AllowLongJobNames {
-		maxNameLen := maxLabelLen - len(suffix)
+	return p, nil
+}
 
-		if len(job.Name) > maxNameLen {
-			job.Name = job.Name[:maxNameLen]
-		}
+// checkoutInputRepo checks out a ref of a public org/repo or git remote URL into a directory.
+func checkoutInputRepo(repo string, ref string, dir string) error {
+	remote := repo
+	if strings.Count(repo, "/") == 1 && !strings.Contains(repo, ":") && !strings.HasPrefix(repo, ".") {
+		remote = remoteURL(repo, "")
 	}
 
-	job.Name += suffix
+	_, err := git.Checkout(remote, ref, dir)
+
+	return err
 }
 
-// updateBrancher updates the jobs Brancher fields based on provided inputs.
-func updateBrancher(o options, job *config.Brancher) {
-	if len(o.BranchesOut) == 0 {
-		return
+// inputPaths returns the input file(s) or directory(ies), falling back to the input path when they are unresolved.
+func (o options) inputPaths() []string {
+	if len(o.inputs) == 0 {
+		return []string{o.Input}
 	}
 
-	job.Branches = o.BranchesOut
+	return o.inputs
 }
 
-// updateUtilityConfig updates the jobs UtilityConfig fields based on provided inputs.
-func updateUtilityConfig(o options, job *config.UtilityConfig) {
-	if o.Bucket == "" && o.SSHKeySecret == "" {
-		return
+// inputRoot returns the innermost input file or directory containing a path.
+func inputRoot(o options, p string) string {
+	var root string
+
+	for _, in := range o.inputPaths() {
+		in = inputBase(in)
+		if (p == in || strings.HasPrefix(p, in+string(filepath.Separator))) && len(in) > len(root) {
+			root = in
+		}
 	}
 
-	if job.DecorationConfig == nil {
-		job.DecorationConfig = &prowjob.DecorationConfig{}
+	if root == "" {
+		return o.Input
 	}
 
-	updateGCSConfiguration(o, job.DecorationConfig)
-	updateSSHKeySecrets(o, job.DecorationConfig)
+	return root
 }
 
-// updateGCSConfiguration updates the jobs GCSConfiguration fields based on provided inputs.
-func updateGCSConfiguration(o options, job *prowjob.DecorationConfig) {
-	if o.Bucket == "" {
-		return
+// inputBase returns the directory of a glob pattern input preceding its first wildcard, or the input itself.
+func inputBase(in string) string {
+	if !util.IsGlob(in) {
+		return in
 	}
 
-	if job.GCSConfiguration == nil {
-		job.GCSConfiguration = &prowjob.GCSConfiguration{
-			Bucket: o.Bucket,
+	segments := strings.Split(in, string(filepath.Separator))
+	for i, segment := range segments {
+		if util.IsGlob(segment) {
+			return strings.Join(segments[:i], string(filepath.Separator))
 		}
-	} else {
-		job.GCSConfiguration.Bucket = o.Bucket
 	}
+
+	return in
 }
 
-// updateSSHKeySecrets updates the jobs SSHKeySecrets fields based on provided inputs.
-func updateSSHKeySecrets(o options, job *prowjob.DecorationConfig) {
-	if o.SSHKeySecret == "" {
-		return
+// isExcludedInput checks if an input file matches any of the exclude pattern(s) relative to its input.
+func isExcludedInput(o options, p string, root string) bool {
+	if len(o.Exclude) == 0 {
+		return false
 	}
 
-	if job.SSHKeySecrets == nil {
-		job.SSHKeySecrets = []string{o.SSHKeySecret}
-	} else {
-		job.SSHKeySecrets = append(job.SSHKeySecrets, o.SSHKeySecret)
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." {
+		rel = filepath.Base(p)
 	}
-}
 
-// updateGerritReportingLabels updates the gerrit reporting labels based on provided inputs.
-func updateGerritReportingLabels(o options, skipReport, optional bool, labels map[string]string) {
-	if o.SupportGerritReporting && !skipReport {
-		if !optional {
-			// For non-optional jobs, only add the label if it's not configured,
-			// this allows us defining internal jobs that report to a different label.
-			if _, ok := labels[gerritReportLabel]; !ok {
-				labels[gerritReportLabel] = "Verified"
-			}
-		} else {
-			labels[gerritReportLabel] = "Advisory"
+	for _, pattern := range o.Exclude {
+		if util.MustCompile(util.GlobPattern(pattern)).MatchString(filepath.ToSlash(rel)) {
+			return true
 		}
-	} else {
-		delete(labels, gerritReportLabel)
 	}
+
+	return false
 }
 
-// updateReporterConfig updates the jobs ReporterConfig fields based on provided inputs.
-func updateReporterConfig(o options, job *config.JobBase) {
-	if o.Channel == "" {
-		return
+// walkInput walks the file tree rooted at root like filepath.Walk, following symlinked directories with --follow-symlinks.
+func walkInput(o options, root string, walkFn filepath.WalkFunc) error {
+	if !o.FollowSymlinks {
+		return filepath.Walk(root, walkFn)
 	}
 
-	if job.ReporterConfig == nil {
-		job.ReporterConfig = &prowjob.ReporterConfig{}
+	err := walkSymlinks(root, sets.NewString(), walkFn)
+	if err == filepath.SkipDir {
+		return nil
 	}
 
-	job.ReporterConfig.Slack = &prowjob.SlackReporterConfig{Channel: o.Channel}
+	return err
 }
 
-// updateRerunAuthConfig updates the jobs RerunAuthConfig fields based on provided inputs.
-func updateRerunAuthConfig(o options, job *config.JobBase) {
-	if len(o.RerunOrgs) == 0 && len(o.RerunUsers) == 0 {
-		return
+// walkSymlinks walks the file tree rooted at p following symlinks. Directories are identified by their
+// resolved path and a directory that resolves to one of its ancestors is skipped as a cycle.
+func walkSymlinks(p string, ancestors sets.String, walkFn filepath.WalkFunc) error {
+	info, err := os.Stat(p)
+	if err != nil {
+		return walkFn(p, nil, err)
 	}
 
-	// The original job `RerunAuthConfig` is overwritten with the user-defined values.
-	job.RerunAuthConfig = &prowjob.RerunAuthConfig{
-		GitHubOrgs:  o.RerunOrgs,
-		GitHubUsers: o.RerunUsers,
+	if !info.IsDir() {
+		return walkFn(p, info, nil)
 	}
-}
 
-// updateLabels updates the jobs Labels fields based on provided inputs.
-func updateLabels(o options, job *config.JobBase) {
-	if len(o.Labels) == 0 {
-		return
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return walkFn(p, info, err)
 	}
 
-	if job.Labels == nil {
-		job.Labels = make(map[string]string)
+	if ancestors.Has(resolved) {
+		util.PrintErr(fmt.Sprintf("skipping symlink cycle %v to directory %v.", p, resolved))
+		return nil
 	}
 
-	for labelK, labelV := range o.Labels {
-		job.Labels[labelK] = labelV
+	if err := walkFn(p, info, nil); err != nil {
+		return err
 	}
-}
 
-// updateNodeSelector updates the jobs NodeSelector fields based on provided inputs.
-func updateNodeSelector(o options, job *config.JobBase) {
-	if o.OverrideSelector {
-		job.Spec.NodeSelector = make(map[string]string)
+	names, err := readDirNames(p)
+	if err != nil {
+		return walkFn(p, info, err)
 	}
 
-	if len(o.Selector) == 0 {
-		return
-	}
+	ancestors.Insert(resolved)
+	defer ancestors.Delete(resolved)
 
-	if job.Spec.NodeSelector == nil {
-		job.Spec.NodeSelector = make(map[string]string)
+	for _, name := range names {
+		if err := walkSymlinks(filepath.Join(p, name), ancestors, walkFn); err != nil && err != filepath.SkipDir {
+			return err
+		}
 	}
 
-	for selK, selV := range o.Selector {
-		job.Spec.NodeSelector[selK] = selV
-	}
+	return nil
 }
 
-// updateEnvs updates the jobs Env fields based on provided inputs.
-func updateEnvs(o options, job *config.JobBase) {
-	if len(o.Env) == 0 {
-		return
+// readDirNames returns the sorted names of the entries of a directory.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
 
-	envKs := util.SortedKeys(o.Env)
-
-	for _, envK := range envKs {
-	container:
-		for i := range job.Spec.Containers {
-
-			for j := range job.Spec.Containers[i].Env {
-				if job.Spec.Containers[i].Env[j].Name == envK {
-					job.Spec.Containers[i].Env[j].Value = o.Env[envK]
-					continue container
-				}
-			}
-
-			job.Spec.Containers[i].Env = append(job.Spec.Containers[i].Env, v1.EnvVar{Name: envK, Value: o.Env[envK]})
-		}
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
 	}
+	sort.Strings(names)
+
+	return names, nil
 }
 
-// updateJobBase updates the jobs JobBase fields based on provided inputs to work with private repositories.
-func updateJobBase(o options, job *config.JobBase, orgrepo string) {
-	if len(o.Annotations) != 0 {
-		job.Annotations = o.Annotations
-	}
+// collectInputFiles walks the input path(s) and returns the yaml files to transform in lexical order per input.
+// Files of glob pattern inputs are matched along with the files of the directories they match.
+func collectInputFiles(o options) []string {
+	var paths []string
+	seen := sets.NewString()
 
-	if o.SSHClone && orgrepo != "" {
-		job.CloneURI = fmt.Sprintf("git@%s:%s.git", gitHost, orgrepo)
-	}
+	for _, in := range o.inputPaths() {
+		base := inputBase(in)
 
-	if o.Cluster != "" && o.Cluster != defaultCluster {
-		job.Cluster = o.Cluster
-	}
+		var glob *regexp.Regexp
+		if base != in {
+			glob = util.MustCompile(strings.TrimSuffix(util.GlobPattern(in), "$") + "(?:/.*)?$")
+		}
 
-	updateJobName(o, job)
-	updateReporterConfig(o, job)
-	updateRerunAuthConfig(o, job)
-	updateLabels(o, job)
-	updateNodeSelector(o, job)
-	updateEnvs(o, job)
-}
+		if err := walkInput(o, base, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				reportInputErr(o, p, err)
+				return nil
+			}
 
-// updateExtraRefs updates the jobs ExtraRefs fields based on provided inputs to work with private repositories.
-func updateExtraRefs(o options, job *config.UtilityConfig) {
-	for i, ref := range job.ExtraRefs {
-		org, repo := ref.Org, ref.Repo
+			absPath, _ := filepath.Abs(p)
 
-		if o.Refs || validateOrgRepo(o, org, repo) {
-			// Try to transform known ref org mappings first.
-			if newOrg, ok := o.RefOrgMap[org]; ok {
-				org = newOrg
-				job.ExtraRefs[i].CloneURI = fmt.Sprintf("https://%s/%s", org, repo)
-				// Then try to transform general org mappings.
-			} else if newOrg, ok := o.OrgMap[org]; ok {
-				org = newOrg
-			}
-			job.ExtraRefs[i].Org = org
-			if o.SSHClone {
-				job.ExtraRefs[i].CloneURI = fmt.Sprintf("git@%s:%s/%s.git", gitHost, org, repo)
+			if !util.HasExtension(absPath, jobConfigExt) || seen.Has(absPath) {
+				return nil
 			}
-			if o.RefBranchOut != "" {
-				job.ExtraRefs[i].BaseRef = o.RefBranchOut
+
+			if (glob != nil && !glob.MatchString(absPath)) || isExcludedInput(o, absPath, base) {
+				return nil
 			}
+
+			seen.Insert(absPath)
+			paths = append(paths, absPath)
+
+			return nil
+		}); err != nil {
+			reportErr(o, err.Error())
 		}
 	}
-	if len(o.ExtraRefs) > 0 {
-		job.ExtraRefs = append(job.ExtraRefs, o.ExtraRefs...)
-	}
+
+	return paths
 }
 
-// sortJobs sorts jobs based on a provided sort order.
-func sortJobs(o options, pre map[string][]config.Presubmit, post map[string][]config.Postsubmit, per []config.Periodic) {
-	if o.Sort == "" {
-		return
-	}
+// transformFiles transforms the input files concurrently, returning the results in input order.
+func transformFiles(o options, paths []string, presets []config.Preset) []*fileResult {
+	results := make([]*fileResult, len(paths))
 
-	choices := strings.Join([]string{string(ascending), string(descending)}, "|")
-	matches := regexp.MustCompile(`^(` + choices + `)(?:ending)?$`).FindStringSubmatch(o.Sort)
-	if len(matches) < 2 {
-		return
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(paths) {
+		workers = len(paths)
 	}
 
-	var comparator func(a, b string) bool
+	var wg sync.WaitGroup
+	queue := make(chan int)
 
-	switch sortOrder(matches[1]) {
-	case ascending:
-		comparator = func(a, b string) bool {
-			return a < b
-		}
-	case descending:
-		comparator = func(a, b string) bool {
-			return a > b
-		}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if failedFast(o) {
+					continue
+				}
+				results[i] = transformFile(o, paths[i], presets)
+			}
+		}()
 	}
 
-	for _, c := range pre {
-		sort.Slice(c, func(a, b int) bool {
-			return comparator(c[a].Name, c[b].Name)
-		})
+	for i := range paths {
+		queue <- i
 	}
+	close(queue)
 
-	for _, c := range post {
-		sort.Slice(c, func(a, b int) bool {
-			return comparator(c[a].Name, c[b].Name)
-		})
-	}
+	wg.Wait()
 
-	sort.Slice(per, func(a, b int) bool {
-		return comparator(per[a].Name, per[b].Name)
-	})
+	return results
 }
 
-// getOutPath derives the output path from the specified input directory and current path.
-func getOutPath(o options, p string, in string) string {
-	segments := strings.FieldsFunc(strings.TrimPrefix(p, in), func(c rune) bool { return c == '/' })
+// collectOutputs transforms the input files and returns the output paths in order with their jobs.
+func collectOutputs(o options) ([]string, map[string]*jobSet) {
+	presets := combinePresets(o, o.Presets)
 
-	var (
-		org  string
-		repo string
-		file string
-	)
+	outPaths := []string{}
+	outJobs := map[string]*jobSet{}
 
-	switch {
-	case util.HasExtension(o.Output, yamlExt):
-		return o.Output
-	case len(segments) >= 3:
-		org = segments[len(segments)-3]
-		repo = segments[len(segments)-2]
-		file = segments[len(segments)-1]
-		if newOrg, ok := o.OrgMap[org]; ok {
-			filename := util.RenameFile(`^`+util.NormalizeOrg(org, filenameSeparator)+`\b`, file, util.NormalizeOrg(newOrg, filenameSeparator))
-			return filepath.Join(o.Output, util.GetTopLevelOrg(newOrg), repo, filename)
-		}
-	case len(segments) == 2:
-		org = segments[len(segments)-2]
-		file = segments[len(segments)-1]
-		if newOrg, ok := o.OrgMap[org]; ok {
-			filename := util.RenameFile(`^`+util.NormalizeOrg(org, filenameSeparator)+`\b`, file, util.NormalizeOrg(newOrg, filenameSeparator))
-			return filepath.Join(o.Output, util.GetTopLevelOrg(newOrg), filename)
+	for _, res := range transformFiles(o, collectInputFiles(o), presets) {
+		if res == nil {
+			continue
 		}
-	case len(segments) == 1:
-		file = segments[len(segments)-1]
-		if !strings.HasPrefix(file, o.Modifier) {
-			return filepath.Join(o.Output, o.Modifier+filenameSeparator+file)
+
+		outputs := res.outputs(o)
+
+		keys := make([]string, 0, len(outputs))
+		for outPath := range outputs {
+			keys = append(keys, outPath)
 		}
-	case len(segments) == 0:
-		file = filepath.Base(in)
-		if !strings.HasPrefix(file, o.Modifier) {
-			return filepath.Join(o.Output, o.Modifier+filenameSeparator+file)
+		sort.Strings(keys)
+
+		for _, outPath := range keys {
+			if _, exists := outJobs[outPath]; !exists {
+				outPaths = append(outPaths, outPath)
+				outJobs[outPath] = newJobSet()
+			}
+			outJobs[outPath].merge(outputs[outPath])
 		}
 	}
 
-	return ""
+	return outPaths, outJobs
 }
 
-// cleanOutFile deletes a path and any children.
-func cleanOutFile(p string) {
-	if err := os.RemoveAll(p); err != nil {
-		util.PrintErr(fmt.Sprintf("unable to clean file %v: %v.", p, err))
-	}
-}
+// generateJobs generates jobs based on the specified options, returning the error that aborted or failed generation.
+func generateJobs(o options) error {
+	o = withRunErrors(o)
 
-func handleRecover() {
-	if r := recover(); r != nil {
-		switch t := r.(type) {
-		case string:
-			util.PrintErrAndExit(errors.New(t))
-		case error:
-			util.PrintErrAndExit(t)
-		default:
-			util.PrintErrAndExit(errors.New("unknown panic"))
-		}
+	if o.Provenance && o.SourceSHA == "" {
+		o.SourceSHA = inputSourceSHA(o)
 	}
-}
 
-// writeOutFile writes all jobs definitions to the designated output path.
-func writeOutFile(o options, p string, pre map[string][]config.Presubmit, post map[string][]config.Postsubmit, per []config.Periodic) {
-	if len(pre) == 0 && len(post) == 0 && len(per) == 0 {
-		return
+	if err := verifyInput(o); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("unable to verify input %v: %v.", o.Input, err), Code: 1}
 	}
 
-	combinedPre := map[string][]config.Presubmit{}
-	combinedPost := map[string][]config.Postsubmit{}
-	combinedPer := []config.Periodic{}
+	outPaths, outJobs := collectOutputs(o)
 
-	existingJobs, err := config.ReadJobConfig(p)
-	if err == nil {
-		if existingJobs.PresubmitsStatic != nil {
-			combinedPre = existingJobs.PresubmitsStatic
-		}
-		if existingJobs.PostsubmitsStatic != nil {
-			combinedPost = existingJobs.PostsubmitsStatic
-		}
-		if existingJobs.Periodics != nil {
-			combinedPer = existingJobs.Periodics
-		}
+	if o.Strict && len(inputErrors(o)) > 0 {
+		return errStrict(o)
 	}
 
-	// Combine presubmits
-	for orgrepo, newPre := range pre {
-		if oldPre, exists := combinedPre[orgrepo]; exists {
-			combinedPre[orgrepo] = append(oldPre, newPre...)
-		} else {
-			combinedPre[orgrepo] = newPre
-		}
+	if failedFast(o) {
+		return errGenerate(o)
 	}
 
-	// Combine postsubmits
-	for orgrepo, newPost := range post {
-		if oldPost, exists := combinedPost[orgrepo]; exists {
-			combinedPost[orgrepo] = append(oldPost, newPost...)
-		} else {
-			combinedPost[orgrepo] = newPost
+	if o.StrictMapping {
+		if unmapped := unmappedRepos(o); len(unmapped) > 0 {
+			return &util.ExitError{Message: fmt.Sprintf("--strict-mapping option: %d org/repo(s) are not in the mapping:\n  %v", len(unmapped), strings.Join(unmapped, "\n  ")), Code: 1}
 		}
 	}
 
-	// Combine periodics
-	combinedPer = append(combinedPer, per...)
+	if o.ValidateCluster {
+		validator := newClusterValidator(o.Kubeconfig, o.ValidateNamespace)
 
-	// Sort presubmits, postsubmits, and periodics
-	sortJobs(o, combinedPre, combinedPost, combinedPer)
+		var errs []error
+		for _, outPath := range outPaths {
+			errs = append(errs, validateJobSet(validator, outJobs[outPath])...)
+		}
 
-	jobConfig := config.JobConfig{}
+		if len(errs) > 0 {
+			for _, err := range errs {
+				util.PrintErr(err.Error())
+			}
 
-	err = jobConfig.SetPresubmits(combinedPre)
-	if err != nil {
-		util.PrintErr(fmt.Sprintf("unable to set presubmits for path %v: %v.", p, err))
+			return &util.ExitError{Message: fmt.Sprintf("%d job(s) failed cluster validation.", len(errs)), Code: 1}
+		}
 	}
 
-	err = jobConfig.SetPostsubmits(combinedPost)
-	if err != nil {
-		util.PrintErr(fmt.Sprintf("unable to set postsubmits for path %v: %v.", p, err))
-	}
+	if o.CheckQuota != "" {
+		all := newJobSet()
+		for _, outPath := range outPaths {
+			all.merge(outJobs[outPath])
+		}
 
-	jobConfig.Periodics = combinedPer
+		if errs := checkQuotas(newClusterValidator(o.Kubeconfig, o.ValidateNamespace), all, o.QuotaConcurrency); len(errs) > 0 {
+			for _, err := range errs {
+				util.PrintErr(err.Error())
+			}
 
-	jobConfigYaml, err := yaml.Marshal(jobConfig)
-	if err != nil {
-		util.PrintErr(fmt.Sprintf("unable to marshal job config output directory: %v.", err))
-		return
+			if o.CheckQuota == quotaFail {
+				return &util.ExitError{Message: fmt.Sprintf("%d resource quota check(s) failed.", len(errs)), Code: 1}
+			}
+		}
 	}
 
-	outBytes := []byte(autogenHeader)
-	outBytes = append(outBytes, jobConfigYaml...)
-
-	dir := filepath.Dir(p)
-
-	err = os.MkdirAll(dir, os.ModePerm)
-	if err != nil {
-		util.PrintErr(fmt.Sprintf("unable to create output directory %v: %v.", dir, err))
+	// Plan the job changes to record before the output tree is overwritten.
+	var plans []filePlan
+	if o.HistoryDB != "" && !o.DryRun {
+		plans = planOutputs(o, outPaths, outJobs)
 	}
 
-	err = ioutil.WriteFile(p, outBytes, 0644)
-	if err != nil {
-		util.PrintErr(fmt.Sprintf("unable to write jobs to path %v: %v.", p, err))
-	}
-}
+	presubmits := map[string][]config.Presubmit{}
+	postsubmits := map[string][]config.Postsubmit{}
+	var periodics []config.Periodic
+	var presets []config.Preset
+	written := map[string]*jobSet{}
 
-// generateJobs generates jobs based on the specified options.
-func generateJobs(o options) {
-	presets := combinePresets(o.Presets)
+	for _, outPath := range outPaths {
+		jobs := outJobs[outPath]
 
-	if err := filepath.Walk(o.Input, func(p string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+		for orgrepo, pre := range jobs.presubmits {
+			presubmits[orgrepo] = append(presubmits[orgrepo], pre...)
 		}
-
-		absPath, _ := filepath.Abs(p)
-
-		if !util.HasExtension(absPath, yamlExt) {
-			return nil
+		for orgrepo, post := range jobs.postsubmits {
+			postsubmits[orgrepo] = append(postsubmits[orgrepo], post...)
 		}
+		periodics = append(periodics, jobs.periodics...)
+		presets = append(presets, jobs.presets...)
 
-		outPath := getOutPath(o, absPath, o.Input)
-		if outPath == "" {
-			return nil
-		}
-		if o.Clean {
-			cleanOutFile(outPath)
+		if o.Clean && outPath != stdio {
+			cleanOutFile(o, outPath)
 		}
 
-		jobs, err := config.ReadJobConfig(absPath)
-		if err != nil {
-			return nil
+		// Keep stdout for the generated job(s) when writing to it.
+		if o.Verbose && outPath != stdio {
+			fmt.Printf("write %d presubmits, %d postsubmits, and %d periodics to path %v\n", len(jobs.presubmits), len(jobs.postsubmits), len(jobs.periodics), outPath)
 		}
 
-		presubmit := map[string][]config.Presubmit{}
-		postsubmit := map[string][]config.Postsubmit{}
-		periodic := []config.Periodic{}
-
-		// Presubmits
-		for orgrepo, pre := range jobs.PresubmitsStatic {
-			orgrepo = convertOrgRepoStr(o, orgrepo)
-			if orgrepo == "" {
-				continue
+		if !o.DryRun {
+			for fp, files := range writeOutFile(o, outPath, jobs) {
+				written[fp] = files
 			}
+		}
 
-			for _, job := range pre {
-				valid := validateJob(o, job.Name, job.Branches, "presubmit")
-				if !valid {
-					continue
-				}
-
-				updateExtraRefs(o, &job.UtilityConfig)
-				updateJobBase(o, &job.JobBase, orgrepo)
-				updateBrancher(o, &job.Brancher)
-				updateUtilityConfig(o, &job.UtilityConfig)
-				updateGerritReportingLabels(o, job.SkipReport, job.Optional, job.Labels)
-				resolvePresets(o, job.Labels, &job.JobBase, append(presets, jobs.Presets...))
-				pruneJobBase(o, &job.JobBase)
-
-				presubmit[orgrepo] = append(presubmit[orgrepo], job)
-			}
+		if failedFast(o) {
+			return errGenerate(o)
 		}
+	}
 
-		// Postsubmits
-		for orgrepo, post := range jobs.PostsubmitsStatic {
-			orgrepo = convertOrgRepoStr(o, orgrepo)
-			if orgrepo == "" {
-				continue
-			}
+	if o.TideConfig != "" {
+		writeTideConfig(o, presubmits)
+	}
 
-			for _, job := range post {
-				valid := validateJob(o, job.Name, job.Branches, "postsubmit")
-				if !valid {
-					continue
-				}
+	if o.BranchProtectionConfig != "" {
+		writeBranchProtectionConfig(o, presubmits)
+	}
 
-				updateExtraRefs(o, &job.UtilityConfig)
-				updateJobBase(o, &job.JobBase, orgrepo)
-				updateBrancher(o, &job.Brancher)
-				updateUtilityConfig(o, &job.UtilityConfig)
-				resolvePresets(o, job.Labels, &job.JobBase, append(presets, jobs.Presets...))
-				pruneJobBase(o, &job.JobBase)
+	if o.ContextsOutput != "" {
+		writeContextsExport(o, presubmits)
+	}
 
-				postsubmit[orgrepo] = append(postsubmit[orgrepo], job)
-			}
-		}
+	if o.Manifest != "" {
+		writeManifest(o, written)
+	}
 
-		// Periodic
-		for _, job := range jobs.Periodics {
-			if len(job.ExtraRefs) == 0 {
-				continue
-			}
+	if o.AlertRules != "" {
+		writeAlertRules(o, postsubmits, periodics)
+	}
 
-			if allRefs(job.ExtraRefs, func(val prowjob.Refs, idx int) bool {
-				return !validateOrgRepo(o, val.Org, val.Repo)
-			}) {
-				continue
-			}
+	if o.SecretsOutput != "" {
+		writeSecretManifests(o, &jobSet{presubmits: presubmits, postsubmits: postsubmits, periodics: periodics, presets: presets})
+	}
 
-			branches := make([]string, 0)
-			for _, ref := range job.ExtraRefs {
-				if validateOrgRepo(o, ref.Org, ref.Repo) {
-					branches = append(branches, ref.BaseRef)
-				}
-			}
-			if !validateJob(o, job.Name, branches, "periodic") {
-				continue
-			}
+	if o.HistoryDB != "" && !o.DryRun {
+		recordHistory(o, plans)
+	}
 
-			updateExtraRefs(o, &job.UtilityConfig)
-			updateJobBase(o, &job.JobBase, "")
-			updateUtilityConfig(o, &job.UtilityConfig)
-			resolvePresets(o, job.Labels, &job.JobBase, append(presets, jobs.Presets...))
-			pruneJobBase(o, &job.JobBase)
+	if (o.FailFast || o.KeepGoing) && len(genErrors(o)) > 0 {
+		return errGenerate(o)
+	}
 
-			periodic = append(periodic, job)
-		}
+	return nil
+}
 
-		if o.Verbose {
-			fmt.Printf("write %d presubmits, %d postsubmits, and %d periodics to path %v\n", len(presubmit), len(postsubmit), len(periodic), outPath)
-		}
+// expandOptions returns the options of the command-line transform, the yaml configuration transforms, and their
+// fanout and tenant copies, with their input(s) resolved, and a function removing the temporary input(s).
+func expandOptions(o options) ([]options, func(), error) {
+	configs, err := o.parseConfiguration()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	optsList := append([]options{o}, configs...)
 
-		if !o.DryRun {
-			writeOutFile(o, outPath, presubmit, postsubmit, periodic)
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
 		}
+	}
 
-		return nil
-	}); err != nil {
-		util.PrintErr(err.Error())
+	for i := range optsList {
+		resolved, done, err := resolveInputs(optsList[i])
+		if err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+		cleanups = append(cleanups, done)
+		optsList[i] = resolved
 	}
+
+	return expandTenants(expandFanout(optsList)), cleanup, nil
 }
 
 // main entry point.
@@ -1151,16 +4029,68 @@ func Main() {
 
 	var o options
 
+	if len(os.Args) > 1 {
+		if _, ok := commands[os.Args[1]]; ok {
+			o.Command = os.Args[1]
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+
 	o.parseOpts()
 
-	if err := o.validateOpts(); err != nil {
-		util.PrintErrAndExit(err)
+	if !commands[o.Command].standalone {
+		if err := o.validateOpts(); err != nil {
+			util.PrintErrAndExit(err)
+		}
+	}
+
+	if o.Command != "" {
+		if err := commands[o.Command].run(o); err != nil {
+			util.PrintErrAndExit(err)
+		}
+		return
 	}
 
-	optsList := []options{o}
-	optsList = append(optsList, o.parseConfiguration()...)
+	// The input(s) are resolved for each generation so that every --interval run reads the current ones.
+	generate := func() {
+		optsList, cleanup, err := expandOptions(o)
+		if err != nil {
+			if o.Interval > 0 {
+				util.PrintErr(err.Error())
+				return
+			}
+			util.PrintErrAndExit(err)
+		}
+		defer cleanup()
+
+		// With --keep-going, the errors of every transform are collected and reported once all have run.
+		var errs []string
+		for _, oc := range optsList {
+			if err := generateJobs(oc); err != nil {
+				// Keep running with --interval so that the next generation is attempted.
+				if o.Interval > 0 {
+					util.PrintErr(err.Error())
+					continue
+				}
+				if o.KeepGoing {
+					errs = append(errs, err.Error())
+					continue
+				}
+				util.PrintErrAndExit(err)
+			}
+		}
+
+		if len(errs) > 0 {
+			util.PrintErrAndExit(&util.ExitError{Message: strings.Join(errs, "\n"), Code: 1})
+		}
+	}
 
-	for _, o := range optsList {
-		generateJobs(o)
+	if o.Interval > 0 {
+		if err := runScheduled(o, generate); err != nil {
+			util.PrintErrAndExit(err)
+		}
+		return
 	}
+
+	generate()
 }
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// manifestJob is a job of a generated file.
+type manifestJob struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Org  string `json:"org,omitempty"`
+	Repo string `json:"repo,omitempty"`
+	// Source is the input file the job was generated from, if known.
+	Source string `json:"source,omitempty"`
+}
+
+// manifestFile is a generated file.
+type manifestFile struct {
+	Path    string        `json:"path"`
+	Jobs    []manifestJob `json:"jobs"`
+	Sources []string      `json:"sources"`
+}
+
+// generationManifest is the machine-readable manifest of the files of a generation run.
+type generationManifest struct {
+	SourceSHA string         `json:"source-sha,omitempty"`
+	Files     []manifestFile `json:"files"`
+}
+
+// buildManifest builds the manifest of the generated files and their jobs.
+func buildManifest(o options, files map[string]*jobSet) generationManifest {
+	manifest := generationManifest{SourceSHA: o.SourceSHA, Files: []manifestFile{}}
+
+	for _, p := range sortedJobSetPaths(files) {
+		jobs := files[p]
+
+		mf := manifestFile{
+			Path:    manifestPath(o, p),
+			Jobs:    []manifestJob{},
+			Sources: append([]string{}, jobs.sourceFiles()...),
+		}
+
+		for _, orgrepo := range sortedOrgRepos(jobs.presubmits) {
+			org, repo := util.SplitOrgRepo(orgrepo)
+			for _, job := range jobs.presubmits[orgrepo] {
+				mf.Jobs = append(mf.Jobs, manifestJob{Name: job.Name, Type: "presubmit", Org: org, Repo: repo, Source: jobs.sources[jobKey("presubmit", orgrepo, job.Name)]})
+			}
+		}
+		for _, orgrepo := range sortedOrgRepos(jobs.postsubmits) {
+			org, repo := util.SplitOrgRepo(orgrepo)
+			for _, job := range jobs.postsubmits[orgrepo] {
+				mf.Jobs = append(mf.Jobs, manifestJob{Name: job.Name, Type: "postsubmit", Org: org, Repo: repo, Source: jobs.sources[jobKey("postsubmit", orgrepo, job.Name)]})
+			}
+		}
+		for _, job := range jobs.periodics {
+			mf.Jobs = append(mf.Jobs, manifestJob{Name: job.Name, Type: "periodic", Source: jobs.sources[jobKey("periodic", "", job.Name)]})
+		}
+
+		manifest.Files = append(manifest.Files, mf)
+	}
+
+	return manifest
+}
+
+// manifestPath returns the path of a generated file relative to the output directory, or to the directory
+// of the output file.
+func manifestPath(o options, p string) string {
+	if p == stdio {
+		return p
+	}
+
+	dir := o.Output
+	if util.HasExtension(o.Output, jobConfigExt) {
+		dir = filepath.Dir(o.Output)
+	}
+
+	if rel, err := filepath.Rel(dir, p); err == nil {
+		return filepath.ToSlash(rel)
+	}
+
+	return p
+}
+
+// writeManifest writes the manifest of the generated files as json.
+func writeManifest(o options, files map[string]*jobSet) {
+	if o.Verbose && o.Output != stdio {
+		fmt.Printf("write manifest of %d files to path %v\n", len(files), o.Manifest)
+	}
+
+	if o.DryRun {
+		return
+	}
+
+	b, err := json.MarshalIndent(buildManifest(o, files), "", "  ")
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to marshal manifest: %v.", err))
+		return
+	}
+
+	writeConfigFile(o, o.Manifest, append(b, '\n'))
+}
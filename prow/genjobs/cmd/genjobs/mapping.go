@@ -0,0 +1,145 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"istio.io/test-infra/prow/genjobs/pkg/github"
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const verifyMappingCommand = "verify-mapping"
+
+// verifyMappingOptions are the command-line flags for the verify-mapping subcommand.
+type verifyMappingOptions struct {
+	GitHubTokenPath string
+	GitHubEndpoint  string
+}
+
+func init() {
+	commands[verifyMappingCommand] = command{
+		flags: addVerifyMappingFlags,
+		run:   runVerifyMapping,
+	}
+}
+
+// addVerifyMappingFlags registers the command-line flags for the verify-mapping subcommand.
+func addVerifyMappingFlags(o *options) {
+	flag.StringVar(&o.verifyMapping.GitHubTokenPath, "github-token-path", "", "Path to file containing the GitHub token of the CI bot used to look up the private repositories.")
+	flag.StringVar(&o.verifyMapping.GitHubEndpoint, "github-endpoint", "", "GitHub API endpoint to look up the private repositories with (e.g. of GitHub Enterprise). Uses the public GitHub API if unset.")
+}
+
+// mappedRepos adds the private org/repo(s) referenced by the generated job(s) to repos.
+// Periodic refs are only private if their org is one of the private orgs.
+func mappedRepos(jobs *jobSet, orgs sets.String, repos sets.String) {
+	for orgrepo := range jobs.presubmits {
+		repos.Insert(orgrepo)
+	}
+	for orgrepo := range jobs.postsubmits {
+		repos.Insert(orgrepo)
+	}
+	for _, job := range jobs.periodics {
+		for _, ref := range job.ExtraRefs {
+			if orgs.Has(ref.Org) {
+				repos.Insert(ref.Org + "/" + ref.Repo)
+			}
+		}
+	}
+}
+
+// runVerifyMapping confirms that every private org/repo referenced by the mapping exists and is accessible by the
+// CI bot, reporting the missing ones.
+func runVerifyMapping(o options) error {
+	if o.verifyMapping.GitHubTokenPath == "" {
+		return &util.ExitError{Message: "--github-token-path option is required.", Code: 1}
+	}
+
+	optsList, cleanup, err := expandOptions(o)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	repos := sets.NewString()
+	failed := false
+
+	for _, o := range optsList {
+		o = withRunErrors(o)
+
+		var results []*jobSet
+		for _, res := range transformFiles(o, collectInputFiles(o), combinePresets(o, o.Presets)) {
+			if res != nil {
+				results = append(results, res.jobs)
+			}
+		}
+
+		// Regex mappings only reveal their private org(s) once expanded by the presubmits and postsubmits.
+		orgs := mappedOrgs(o)
+		for _, jobs := range results {
+			for _, orgrepo := range append(sets.StringKeySet(jobs.presubmits).List(), sets.StringKeySet(jobs.postsubmits).List()...) {
+				org, _ := util.SplitOrgRepo(orgrepo)
+				orgs.Insert(org)
+			}
+		}
+
+		for _, jobs := range results {
+			mappedRepos(jobs, orgs, repos)
+		}
+
+		if len(genErrors(o)) > 0 {
+			failed = true
+		}
+	}
+
+	if failed {
+		return &util.ExitError{Message: "unable to read the input job(s); the mapping was not verified.", Code: 1}
+	}
+
+	missing := 0
+
+	for _, orgrepo := range repos.List() {
+		org, repo := util.SplitOrgRepo(orgrepo)
+
+		client, err := github.NewClient(o.verifyMapping.GitHubTokenPath, o.verifyMapping.GitHubEndpoint, org, repo)
+		if err != nil {
+			return &util.ExitError{Message: err.Error(), Code: 1}
+		}
+
+		exists, err := client.RepositoryExists()
+		if err != nil {
+			return &util.ExitError{Message: err.Error(), Code: 1}
+		}
+		if !exists {
+			fmt.Printf("missing %v\n", orgrepo)
+			missing++
+		} else if o.Verbose {
+			fmt.Printf("ok %v\n", orgrepo)
+		}
+	}
+
+	if missing > 0 {
+		return &util.ExitError{Message: fmt.Sprintf("%d of %d private repo(s) referenced by the mapping are missing or inaccessible.", missing, repos.Len()), Code: 1}
+	}
+
+	fmt.Printf("All %d private repo(s) referenced by the mapping exist.\n", repos.Len())
+
+	return nil
+}
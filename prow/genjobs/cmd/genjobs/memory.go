@@ -0,0 +1,237 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+)
+
+// explanation shows how a single job is transformed.
+type explanation struct {
+	InputPath  string
+	OutputPath string
+	Source     string
+	Generated  string
+}
+
+// checkInputPaths checks that the paths of job config files stay within the directory they are written into.
+func checkInputPaths(inputs map[string][]byte) error {
+	for p := range inputs {
+		clean := filepath.Clean(filepath.FromSlash(p))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("input path must be relative: %v", p)
+		}
+	}
+
+	return nil
+}
+
+// writeInputs writes job config files keyed by relative path into a directory.
+func writeInputs(dir string, inputs map[string][]byte) error {
+	if err := checkInputPaths(inputs); err != nil {
+		return err
+	}
+
+	for p, content := range inputs {
+		path := filepath.Join(dir, filepath.Clean(filepath.FromSlash(p)))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("unable to create input directory %v: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("unable to write input %v: %v", p, err)
+		}
+	}
+
+	return nil
+}
+
+// readOutputs reads all files in a directory keyed by relative path.
+func readOutputs(dir string) (map[string][]byte, error) {
+	outputs := map[string][]byte{}
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		outputs[filepath.ToSlash(rel)] = content
+
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return outputs, nil
+	}
+
+	return outputs, err
+}
+
+// withScratchDir runs fn with the input and output directories of a temporary scratch directory.
+func withScratchDir(inputs map[string][]byte, fn func(in, out string) error) error {
+	dir, err := ioutil.TempDir("", "genjobs")
+	if err != nil {
+		return fmt.Errorf("unable to create scratch directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+
+	if err := os.MkdirAll(in, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create input directory %v: %v", in, err)
+	}
+	if err := writeInputs(in, inputs); err != nil {
+		return err
+	}
+
+	return fn(in, out)
+}
+
+// generateFiles generates jobs from job config files keyed by relative path, returning the generated files keyed by relative path.
+func generateFiles(o options, inputs map[string][]byte) (map[string][]byte, error) {
+	var outputs map[string][]byte
+
+	err := withScratchDir(inputs, func(in, out string) error {
+		o.Input, o.Output = in, out
+		o.inputs = nil
+		o.Clean, o.DryRun = false, false
+
+		// Fail on the per-file error(s) rather than returning the output(s) that were generated despite them.
+		if !o.FailFast {
+			o.KeepGoing = true
+		}
+
+		if err := generateJobs(o); err != nil {
+			return err
+		}
+
+		var err error
+		outputs, err = readOutputs(out)
+		return err
+	})
+
+	return outputs, err
+}
+
+// explainJob shows how the job with the given name is transformed.
+func explainJob(o options, inputs map[string][]byte, name string) (*explanation, error) {
+	var exp *explanation
+
+	err := withScratchDir(inputs, func(in, out string) error {
+		o.Input, o.Output = in, out
+		o.inputs = nil
+
+		paths := collectInputFiles(o)
+		sort.Strings(paths)
+
+		for _, p := range paths {
+			jobs, err := config.ReadJobConfig(p)
+			if err != nil {
+				continue
+			}
+
+			single, source, found := findJob(jobs, name)
+			if !found {
+				continue
+			}
+
+			sourceYaml, err := yaml.Marshal(source)
+			if err != nil {
+				return fmt.Errorf("unable to marshal job %v: %v", name, err)
+			}
+
+			rel, _ := filepath.Rel(in, p)
+			exp = &explanation{InputPath: filepath.ToSlash(rel), Source: string(sourceYaml)}
+
+			presets := append(combinePresets(o, o.Presets), jobs.Presets...)
+			generated := transformJobs(o, single, newPresetIndex(presets))
+			if generated.empty() {
+				return nil
+			}
+
+			var job interface{}
+			for _, pre := range generated.presubmits {
+				job = pre[0]
+			}
+			for _, post := range generated.postsubmits {
+				job = post[0]
+			}
+			for _, per := range generated.periodics {
+				job = per
+			}
+
+			generatedYaml, err := yaml.Marshal(job)
+			if err != nil {
+				return fmt.Errorf("unable to marshal generated job %v: %v", name, err)
+			}
+			exp.Generated = string(generatedYaml)
+
+			if outPath := getOutPath(o, p, in); outPath != "" {
+				rel, _ := filepath.Rel(out, outPath)
+				exp.OutputPath = filepath.ToSlash(rel)
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf("job not found: %v", name)
+	})
+
+	return exp, err
+}
+
+// findJob finds a job by name, returning a job config containing only that job and the job itself.
+func findJob(jobs config.JobConfig, name string) (*config.JobConfig, interface{}, bool) {
+	for orgrepo, pre := range jobs.PresubmitsStatic {
+		for _, job := range pre {
+			if job.Name == name {
+				return &config.JobConfig{PresubmitsStatic: map[string][]config.Presubmit{orgrepo: {job}}}, job, true
+			}
+		}
+	}
+
+	for orgrepo, post := range jobs.PostsubmitsStatic {
+		for _, job := range post {
+			if job.Name == name {
+				return &config.JobConfig{PostsubmitsStatic: map[string][]config.Postsubmit{orgrepo: {job}}}, job, true
+			}
+		}
+	}
+
+	for _, job := range jobs.Periodics {
+		if job.Name == name {
+			return &config.JobConfig{Periodics: []config.Periodic{job}}, job, true
+		}
+	}
+
+	return nil, nil, false
+}
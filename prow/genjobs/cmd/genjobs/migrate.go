@@ -0,0 +1,224 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	migrateCommand = "migrate"
+	gcsScheme      = "gs://"
+	s3Scheme       = "s3://"
+)
+
+// migration rewrites a deprecated Prow job config convention to its replacement.
+type migration struct {
+	name string
+	// apply migrates the job(s) of a jobSet in place and returns the name(s) of the job(s) migrated.
+	apply func(jobs *jobSet) []string
+}
+
+// migrations are the available job config migrations applied in order.
+var migrations = []migration{
+	{name: "periodic interval to cron", apply: migrateIntervalToCron},
+	{name: "gcs bucket scheme", apply: migrateBucketScheme},
+}
+
+func init() {
+	commands[migrateCommand] = command{
+		run:        runMigrate,
+		standalone: true,
+	}
+}
+
+// runMigrate rewrites the previously generated job config file(s) of the output to the current Prow job schema conventions.
+func runMigrate(o options) error {
+	var paths []string
+
+	if err := filepath.Walk(o.Output, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && util.HasExtension(p, yamlExt) && isGenerated(p) {
+			paths = append(paths, p)
+		}
+		return nil
+	}); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("unable to read output %v: %v.", o.Output, err), Code: 1}
+	}
+
+	migrated, files := 0, 0
+
+	for _, p := range paths {
+		n, err := migrateFile(o, p)
+		if err != nil {
+			return &util.ExitError{Message: fmt.Sprintf("unable to migrate file %v: %v.", p, err), Code: 1}
+		}
+		if n > 0 {
+			migrated += n
+			files++
+		}
+	}
+
+	fmt.Printf("Applied %d migration(s) to %d file(s).\n", migrated, files)
+
+	return nil
+}
+
+// isGenerated returns whether a file starts with the genjobs autogenerated header.
+func isGenerated(p string) bool {
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(string(d), autogenHeader)
+}
+
+// migrateFile applies the migrations to the job(s) of a generated file and rewrites it, preserving its provenance.
+// It returns the number of job migrations applied.
+func migrateFile(o options, p string) (int, error) {
+	c, err := config.ReadJobConfig(p)
+	if err != nil {
+		return 0, err
+	}
+
+	jobs := &jobSet{
+		presubmits:  c.PresubmitsStatic,
+		postsubmits: c.PostsubmitsStatic,
+		periodics:   c.Periodics,
+		presets:     c.Presets,
+		slack:       readSlackExtras(p),
+		decoration:  readDecorationExtras(p),
+	}
+
+	n := 0
+	for _, m := range migrations {
+		for _, name := range m.apply(jobs) {
+			fmt.Printf("%v: %v: %v\n", p, name, m.name)
+			n++
+		}
+	}
+
+	if n == 0 || o.DryRun {
+		return n, nil
+	}
+
+	header, err := readHeader(p)
+	if err != nil {
+		return 0, err
+	}
+
+	writeJobSet(o, p, header, jobs, false)
+
+	return n, nil
+}
+
+// migrateIntervalToCron replaces the deprecated interval of periodic(s) with an equivalent cron schedule.
+// Intervals without an equivalent cron schedule are left as is.
+func migrateIntervalToCron(jobs *jobSet) []string {
+	var names []string
+
+	for i := range jobs.periodics {
+		job := &jobs.periodics[i]
+		if job.Interval == "" || job.Cron != "" {
+			continue
+		}
+
+		cron, ok := intervalCron(job.Interval)
+		if !ok {
+			util.PrintErr(fmt.Sprintf("unable to migrate interval %v of periodic %v to cron.", job.Interval, job.Name))
+			continue
+		}
+
+		job.Cron = cron
+		job.Interval = ""
+		names = append(names, job.Name)
+	}
+
+	return names
+}
+
+// intervalCron returns the cron schedule equivalent to an interval evenly dividing an hour, a day, or a week.
+func intervalCron(interval string) (string, bool) {
+	d, err := time.ParseDuration(interval)
+	if err != nil || d < time.Minute || d%time.Minute != 0 {
+		return "", false
+	}
+
+	switch m := int(d / time.Minute); {
+	case m == 1:
+		return "* * * * *", true
+	case m < 60 && 60%m == 0:
+		return fmt.Sprintf("*/%d * * * *", m), true
+	case m == 60:
+		return "0 * * * *", true
+	case m < 24*60 && m%60 == 0 && 24*60%m == 0:
+		return fmt.Sprintf("0 */%d * * *", m/60), true
+	case m == 24*60:
+		return "0 0 * * *", true
+	case m == 7*24*60:
+		return "0 0 * * 0", true
+	}
+
+	return "", false
+}
+
+// migrateBucketScheme adds the gs:// scheme to the GCS bucket(s) of decorated job(s) configured without one.
+func migrateBucketScheme(jobs *jobSet) []string {
+	var names []string
+
+	migrate := func(name string, utility config.UtilityConfig) {
+		dc := utility.DecorationConfig
+		if dc == nil || dc.GCSConfiguration == nil || dc.GCSConfiguration.Bucket == "" {
+			return
+		}
+
+		bucket := dc.GCSConfiguration.Bucket
+		if strings.HasPrefix(bucket, gcsScheme) || strings.HasPrefix(bucket, s3Scheme) {
+			return
+		}
+
+		dc.GCSConfiguration.Bucket = gcsScheme + bucket
+		names = append(names, name)
+	}
+
+	for _, pre := range jobs.presubmits {
+		for _, job := range pre {
+			migrate(job.Name, job.UtilityConfig)
+		}
+	}
+	for _, post := range jobs.postsubmits {
+		for _, job := range post {
+			migrate(job.Name, job.UtilityConfig)
+		}
+	}
+	for _, job := range jobs.periodics {
+		migrate(job.Name, job.UtilityConfig)
+	}
+
+	return names
+}
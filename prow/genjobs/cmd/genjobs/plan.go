@@ -0,0 +1,143 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const planCommand = "plan"
+
+func init() {
+	commands[planCommand] = command{
+		run: runPlan,
+	}
+}
+
+// filePlan is the pending change(s) to the job(s) of an output path.
+type filePlan struct {
+	path string
+	syncSummary
+}
+
+// runPlan prints a summary of the job(s) that generation would add, change and remove without writing anything.
+func runPlan(o options) error {
+	optsList, cleanup, err := expandOptions(o)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var plans []filePlan
+	for _, o := range optsList {
+		plans = append(plans, planJobs(o)...)
+	}
+
+	added, changed, removed := 0, 0, 0
+
+	for _, p := range plans {
+		if len(p.Added)+len(p.Changed)+len(p.Removed) == 0 {
+			continue
+		}
+
+		fmt.Printf("%v: %d to add, %d to change, %d to remove\n", p.path, len(p.Added), len(p.Changed), len(p.Removed))
+		for _, key := range p.Added {
+			fmt.Printf("  + %v\n", key)
+		}
+		for _, key := range p.Changed {
+			fmt.Printf("  ~ %v\n", key)
+		}
+		for _, key := range p.Removed {
+			fmt.Printf("  - %v\n", key)
+		}
+
+		added += len(p.Added)
+		changed += len(p.Changed)
+		removed += len(p.Removed)
+	}
+
+	if added+changed+removed == 0 {
+		fmt.Println("No changes. The generated job(s) are up-to-date.")
+		return nil
+	}
+
+	fmt.Printf("Plan: %d to add, %d to change, %d to remove.\n", added, changed, removed)
+
+	return nil
+}
+
+// planJobs compares the would-be generation against the current output tree for each output path.
+func planJobs(o options) []filePlan {
+	outPaths, outJobs := collectOutputs(o)
+
+	return planOutputs(o, outPaths, outJobs)
+}
+
+// planOutputs compares the job(s) collected for each output path against the current output tree.
+func planOutputs(o options, outPaths []string, outJobs map[string]*jobSet) []filePlan {
+	var plans []filePlan
+
+	for _, outPath := range outPaths {
+		jobs := outJobs[outPath]
+		if jobs.empty() {
+			continue
+		}
+
+		before := map[string]string{}
+		for _, p := range append([]string{outPath}, splitPaths(outPath)...) {
+			if c, err := config.ReadJobConfig(p); err == nil {
+				snapshotJobConfig(before, c)
+			}
+		}
+
+		after := map[string]string{}
+		files, _ := layoutOutFile(o, outPath, jobs, !o.Clean)
+		for _, fp := range sortedJobSetPaths(files) {
+			b, err := renderJobSet(fp, files[fp])
+			if err != nil {
+				util.PrintErr(fmt.Sprintf("%v.", err))
+				continue
+			}
+
+			var c config.JobConfig
+			if err := yaml.Unmarshal(b, &c); err != nil {
+				util.PrintErr(fmt.Sprintf("unable to parse rendered jobs for path %v: %v.", fp, err))
+				continue
+			}
+			snapshotJobConfig(after, c)
+		}
+
+		plans = append(plans, filePlan{path: displayPath(o, outPath), syncSummary: diffJobs(before, after)})
+	}
+
+	return plans
+}
+
+// displayPath returns the output path relative to the output directory when possible.
+func displayPath(o options, p string) string {
+	if rel, err := filepath.Rel(o.Output, p); err == nil && !util.HasExtension(o.Output, jobConfigExt) {
+		return rel
+	}
+
+	return p
+}
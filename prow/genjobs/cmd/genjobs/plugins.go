@@ -0,0 +1,152 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const pluginsCommand = "plugins"
+
+// pluginsOptions are the command-line flags for the plugins subcommand.
+type pluginsOptions struct {
+	PluginsConfig string
+	PluginsOutput string
+}
+
+// pluginsFragment is the plugins.yaml fragment enabling plugins per org or org/repo.
+type pluginsFragment struct {
+	Plugins map[string][]string `json:"plugins"`
+}
+
+func init() {
+	commands[pluginsCommand] = command{
+		flags: addPluginsFlags,
+		run:   runPlugins,
+	}
+}
+
+// addPluginsFlags registers the command-line flags for the plugins subcommand.
+func addPluginsFlags(o *options) {
+	flag.StringVar(&o.plugins.PluginsConfig, "plugins-config", "", "Path to the public plugins.yaml to mirror the plugins of.")
+	flag.StringVar(&o.plugins.PluginsOutput, "plugins-output", "", "Path to write the plugins.yaml fragment for the private org(s) and org/repo(s) to.")
+}
+
+// addPlugins adds plugins to the enabled plugins of an org or org/repo.
+func addPlugins(plugins map[string][]string, key string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	plugins[key] = sets.NewString(plugins[key]...).Insert(names...).List()
+}
+
+// buildPlugins mirrors the plugins enabled for the public org(s) and org/repo(s) onto their private equivalents.
+// Repos mapped to another org than their public org also inherit the plugins enabled for the public org.
+func buildPlugins(o options, public map[string][]string, private map[string][]string) {
+	keys := sets.StringKeySet(public)
+	for from := range o.OrgMap {
+		if isRepoMapping(from) && !isRegexMapping(from) {
+			keys.Insert(from)
+		}
+	}
+
+	for _, key := range keys.List() {
+		if !isRepoMapping(key) {
+			if newOrg, ok := mapOrg(o, key); ok && !isExcluded(o, key, "") {
+				addPlugins(private, newOrg, public[key])
+			}
+			continue
+		}
+
+		orgrepo := convertOrgRepoStr(o, key)
+		if orgrepo == "" {
+			continue
+		}
+
+		org, _ := util.SplitOrgRepo(key)
+		newOrg, _ := util.SplitOrgRepo(orgrepo)
+
+		addPlugins(private, orgrepo, public[key])
+		if mappedOrg, ok := mapOrg(o, org); !ok || mappedOrg != newOrg {
+			addPlugins(private, orgrepo, public[org])
+		}
+	}
+}
+
+// runPlugins writes the plugins.yaml fragment enabling the plugins of the public org(s) and org/repo(s) for
+// their private equivalents.
+func runPlugins(o options) error {
+	if o.plugins.PluginsConfig == "" {
+		return &util.ExitError{Message: "--plugins-config option is required.", Code: 1}
+	}
+	if o.plugins.PluginsOutput == "" {
+		return &util.ExitError{Message: "--plugins-output option is required.", Code: 1}
+	} else if !util.HasExtension(o.plugins.PluginsOutput, yamlExt) {
+		return &util.ExitError{Message: fmt.Sprintf("--plugins-output option path is not a yaml file: %v.", o.plugins.PluginsOutput), Code: 1}
+	}
+
+	b, err := ioutil.ReadFile(o.plugins.PluginsConfig)
+	if err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("unable to read plugins configuration %v: %v.", o.plugins.PluginsConfig, err), Code: 1}
+	}
+
+	var public pluginsFragment
+	if err := yaml.Unmarshal(b, &public); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("unable to parse plugins configuration %v: %v.", o.plugins.PluginsConfig, err), Code: 1}
+	}
+
+	optsList, cleanup, err := expandOptions(o)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	private := pluginsFragment{Plugins: map[string][]string{}}
+	for _, o := range optsList {
+		buildPlugins(o, public.Plugins, private.Plugins)
+	}
+
+	if o.Verbose {
+		fmt.Printf("write plugins for %d private org(s) and org/repo(s) to path %v\n", len(private.Plugins), o.plugins.PluginsOutput)
+	}
+
+	if o.DryRun {
+		return nil
+	}
+
+	if b, err = yaml.Marshal(private); err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("unable to marshal plugins configuration: %v.", err), Code: 1}
+	}
+
+	p, err := filepath.Abs(o.plugins.PluginsOutput)
+	if err != nil {
+		return &util.ExitError{Message: fmt.Sprintf("--plugins-output option invalid: %v.", o.plugins.PluginsOutput), Code: 1}
+	}
+
+	writeConfigFile(o, p, append([]byte(outHeader(o, nil)), b...))
+
+	return nil
+}
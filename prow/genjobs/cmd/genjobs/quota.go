@@ -0,0 +1,195 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+const (
+	quotaWarn = "warn"
+	quotaFail = "fail"
+	// defaultJobTimeout is the Prow default timeout of decorated jobs.
+	defaultJobTimeout = 2 * time.Hour
+)
+
+var quotaModes = []string{quotaWarn, quotaFail}
+
+// quotaResources are the resources summed across the generated jobs and compared against resource quotas.
+var quotaResources = []v1.ResourceName{
+	v1.ResourcePods,
+	v1.ResourceCPU,
+	v1.ResourceMemory,
+	v1.ResourceRequestsCPU,
+	v1.ResourceRequestsMemory,
+	v1.ResourceLimitsCPU,
+	v1.ResourceLimitsMemory,
+}
+
+// quotaLister lists the resource quotas of build clusters.
+type quotaLister interface {
+	quotas(cluster string) ([]v1.ResourceQuota, error)
+}
+
+// quotas lists the resource quotas of the namespace in the cluster.
+func (c *clusterValidator) quotas(cluster string) ([]v1.ResourceQuota, error) {
+	client, err := c.client(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.CoreV1().ResourceQuotas(c.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// jobWeight returns the expected number of concurrent pods of a presubmit or postsubmit, capped by its max_concurrency.
+func jobWeight(job config.JobBase, concurrency int) int64 {
+	weight := int64(concurrency)
+	if job.MaxConcurrency > 0 && weight > int64(job.MaxConcurrency) {
+		weight = int64(job.MaxConcurrency)
+	}
+	return weight
+}
+
+// periodicWeight returns the expected number of concurrent pods of a periodic, capped by its max_concurrency.
+// Interval periodics overlap as many runs as start within their timeout, cron periodics are expected not to overlap.
+func periodicWeight(job config.Periodic) int64 {
+	if job.Interval == "" {
+		return 1
+	}
+
+	d, err := time.ParseDuration(job.Interval)
+	if err != nil || d <= 0 {
+		return 1
+	}
+
+	timeout := defaultJobTimeout
+	if dc := job.DecorationConfig; dc != nil && dc.Timeout != nil {
+		timeout = dc.Timeout.Duration
+	}
+
+	return jobWeight(job.JobBase, int((timeout+d-1)/d))
+}
+
+// addUsage adds the resources of the pod of a job times its weight to the usage of its cluster.
+func addUsage(usage map[string]v1.ResourceList, job config.JobBase, weight int64) {
+	if job.Spec == nil || weight <= 0 {
+		return
+	}
+
+	cluster := job.Cluster
+	if cluster == "" {
+		cluster = defaultCluster
+	}
+
+	if _, ok := usage[cluster]; !ok {
+		usage[cluster] = v1.ResourceList{}
+	}
+
+	add := func(name v1.ResourceName, q resource.Quantity) {
+		total := usage[cluster][name]
+		total.Add(*resource.NewMilliQuantity(q.MilliValue()*weight, q.Format))
+		usage[cluster][name] = total
+	}
+
+	add(v1.ResourcePods, *resource.NewQuantity(1, resource.DecimalSI))
+	for _, c := range job.Spec.Containers {
+		if q, ok := c.Resources.Requests[v1.ResourceCPU]; ok {
+			add(v1.ResourceCPU, q)
+			add(v1.ResourceRequestsCPU, q)
+		}
+		if q, ok := c.Resources.Requests[v1.ResourceMemory]; ok {
+			add(v1.ResourceMemory, q)
+			add(v1.ResourceRequestsMemory, q)
+		}
+		if q, ok := c.Resources.Limits[v1.ResourceCPU]; ok {
+			add(v1.ResourceLimitsCPU, q)
+		}
+		if q, ok := c.Resources.Limits[v1.ResourceMemory]; ok {
+			add(v1.ResourceLimitsMemory, q)
+		}
+	}
+}
+
+// quotaUsage returns the expected resource usage of the jobs keyed by build cluster.
+func quotaUsage(jobs *jobSet, concurrency int) map[string]v1.ResourceList {
+	usage := map[string]v1.ResourceList{}
+
+	for _, pre := range jobs.presubmits {
+		for _, job := range pre {
+			addUsage(usage, job.JobBase, jobWeight(job.JobBase, concurrency))
+		}
+	}
+	for _, post := range jobs.postsubmits {
+		for _, job := range post {
+			addUsage(usage, job.JobBase, jobWeight(job.JobBase, concurrency))
+		}
+	}
+	for _, job := range jobs.periodics {
+		addUsage(usage, job.JobBase, periodicWeight(job))
+	}
+
+	return usage
+}
+
+// checkQuotas compares the expected resource usage of the jobs against the resource quotas of their build clusters
+// and returns the quotas the jobs cannot possibly fit in.
+func checkQuotas(q quotaLister, jobs *jobSet, concurrency int) []error {
+	usage := quotaUsage(jobs, concurrency)
+
+	clusters := make([]string, 0, len(usage))
+	for cluster := range usage {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	var errs []error
+	for _, cluster := range clusters {
+		quotas, err := q.quotas(cluster)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to list resource quotas of cluster %v: %v", cluster, err))
+			continue
+		}
+
+		sort.Slice(quotas, func(i, j int) bool { return quotas[i].Name < quotas[j].Name })
+
+		for _, quota := range quotas {
+			for _, name := range quotaResources {
+				hard, ok := quota.Spec.Hard[name]
+				if !ok {
+					continue
+				}
+				if used, ok := usage[cluster][name]; ok && used.Cmp(hard) > 0 {
+					errs = append(errs, fmt.Errorf("cluster %v quota %v: expected %v of %v exceeds hard limit %v", cluster, quota.Name, name, used.String(), hard.String()))
+				}
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// convertOrgRepoStr translates the provided job org and repo based on the specified org mapping.
+func convertOrgRepoStr(o options, s string) string {
+	org, repo := util.SplitOrgRepo(s)
+
+	valid := validateOrgRepo(o, org, repo)
+
+	if !valid {
+		return ""
+	}
+
+	newOrg, newRepo, _ := mapOrgRepo(o, org, repo)
+
+	return strings.Join([]string{newOrg, newRepo}, "/")
+}
+
+// splitMappingExclusions splits the exclusion entries (e.g. !org/repo) from a mapping.
+func splitMappingExclusions(m map[string]string) (map[string]string, sets.String, error) {
+	mapping := make(map[string]string, len(m))
+	exclusions := sets.NewString()
+
+	for from, to := range m {
+		if !strings.HasPrefix(from, exclusionPrefix) {
+			mapping[from] = to
+			continue
+		}
+
+		excluded := strings.TrimPrefix(from, exclusionPrefix)
+		if excluded == "" || to != "" || isRegexMapping(excluded) {
+			return nil, nil, fmt.Errorf("exclusion must be of the form %sorg or %sorg/repo: %v", exclusionPrefix, exclusionPrefix, from)
+		}
+		exclusions.Insert(excluded)
+	}
+
+	return mapping, exclusions, nil
+}
+
+// isExcluded checks if an org/repo or its org is excluded from the mapping.
+func isExcluded(o options, org string, repo string) bool {
+	return o.MappingExclusions.Has(org+"/"+repo) || o.MappingExclusions.Has(org)
+}
+
+// isRepoMapping checks if a mapping entry is an org/repo rather than an org.
+func isRepoMapping(s string) bool {
+	return strings.Contains(util.RemoveHost(s), "/")
+}
+
+// isRegexMapping checks if a mapping entry is a regex (e.g. ~istio-.*) rather than a literal org or org/repo.
+// Regexes are marked explicitly since literal repo names may contain regex metacharacters (e.g. istio/istio.io).
+func isRegexMapping(s string) bool {
+	return strings.HasPrefix(s, regexPrefix)
+}
+
+// mappingRegex returns the regex of a regex mapping entry.
+func mappingRegex(s string) string {
+	return strings.TrimPrefix(s, regexPrefix)
+}
+
+// splitGitHost splits the git host prefix from a mapping target (e.g. ghe.corp.com/neworg).
+// Git hosts are told apart from orgs by their dots, which org names cannot contain.
+func splitGitHost(to string) (string, string) {
+	if i := strings.Index(to, "/"); i > 0 && !strings.Contains(to, "://") && strings.Contains(to[:i], ".") {
+		return to[:i], to[i+1:]
+	}
+
+	return "", to
+}
+
+// reverseMapping inverts a mapping to translate private org(s) and org/repo(s) back to public ones.
+func reverseMapping(m map[string]string) (map[string]string, error) {
+	reversed := make(map[string]string, len(m))
+
+	for _, from := range util.SortedKeys(m) {
+		if isRegexMapping(from) {
+			return nil, fmt.Errorf("regex mapping cannot be reversed: %v", from)
+		}
+
+		_, to := splitGitHost(m[from])
+		if other, ok := reversed[to]; ok {
+			return nil, fmt.Errorf("%v is mapped from both %v and %v", to, other, from)
+		}
+		reversed[to] = from
+	}
+
+	return reversed, nil
+}
+
+// mapOrgRepo translates an org and repo based on the specified mapping.
+// A mapping of the org/repo takes precedence over a mapping of the org, and literal mappings over regex mappings.
+func mapOrgRepo(o options, org string, repo string) (string, string, bool) {
+	_, newOrg, newRepo, ok := resolveMapping(o, org, repo)
+	return newOrg, newRepo, ok
+}
+
+// resolveMapping translates an org and repo based on the specified mapping, returning the git host of the mapping if any.
+func resolveMapping(o options, org string, repo string) (string, string, string, bool) {
+	to, ok := o.OrgMap[org+"/"+repo]
+	if !ok {
+		to, ok = matchMapping(o, org+"/"+repo, true)
+	}
+	if host, to := splitGitHost(to); ok && isRepoMapping(to) {
+		newOrg, newRepo := util.SplitOrgRepo(to)
+		return host, newOrg, newRepo, true
+	}
+
+	if host, newOrg, ok := resolveOrg(o, org); ok {
+		return host, newOrg, repo, true
+	}
+
+	return "", "", "", false
+}
+
+// mapOrg translates an org based on the specified org mapping, literal mappings taking precedence over regex mappings.
+func mapOrg(o options, org string) (string, bool) {
+	_, newOrg, ok := resolveOrg(o, org)
+	return newOrg, ok
+}
+
+// resolveOrg translates an org based on the specified org mapping, returning the git host of the mapping if any.
+func resolveOrg(o options, org string) (string, string, bool) {
+	to, ok := o.OrgMap[org]
+	if !ok || isRepoMapping(org) {
+		if to, ok = matchMapping(o, org, false); !ok {
+			return "", "", false
+		}
+	}
+
+	host, newOrg := splitGitHost(to)
+	return host, newOrg, true
+}
+
+// mapGitHost returns the git host of the private repository of an org and repo.
+func mapGitHost(o options, org string, repo string) string {
+	if host, _, _, ok := resolveMapping(o, org, repo); ok && host != "" {
+		return host
+	}
+
+	if o.GitHost != "" {
+		return o.GitHost
+	}
+
+	return gitHost
+}
+
+// matchMapping translates an org or org/repo with the first regex mapping in lexical order fully matching it.
+// The replacement may reference the submatches of the regex (e.g. $0, $1).
+func matchMapping(o options, s string, repo bool) (string, bool) {
+	for _, from := range util.SortedKeys(o.OrgMap) {
+		if !isRegexMapping(from) || isRepoMapping(from) != repo {
+			continue
+		}
+
+		re := util.MustCompile(`^(?:` + mappingRegex(from) + `)$`)
+		if m := re.FindStringSubmatchIndex(s); m != nil {
+			return string(re.ExpandString(nil, o.OrgMap[from], s, m)), true
+		}
+	}
+
+	return "", false
+}
+
+// mappedOrgs returns the private org(s) of the specified mapping.
+func mappedOrgs(o options) sets.String {
+	orgs := sets.NewString()
+
+	for _, to := range o.OrgMap {
+		// The private org(s) of regex mappings referencing submatches are only known once expanded.
+		if strings.Contains(to, "$") {
+			continue
+		}
+
+		_, to = splitGitHost(to)
+
+		if isRepoMapping(to) {
+			org, _ := util.SplitOrgRepo(to)
+			orgs.Insert(org)
+		} else {
+			orgs.Insert(to)
+		}
+	}
+
+	return orgs
+}
+
+// validateMappings validates the org, org/repo and per-repo mapping options.
+func validateMappings(o *options) error {
+	for from, to := range o.OrgMap {
+		if _, to := splitGitHost(to); isRepoMapping(from) != isRepoMapping(to) {
+			return &util.ExitError{Message: fmt.Sprintf("-m, --mapping option must map an org to an org or an org/repo to an org/repo: %v=%v.", from, to), Code: 1}
+		}
+		if isRegexMapping(from) {
+			if _, err := regexp.Compile(mappingRegex(from)); err != nil {
+				return &util.ExitError{Message: fmt.Sprintf("-m, --mapping option regex invalid: %v: %v.", from, err), Code: 1}
+			}
+		}
+	}
+
+	for orgrepo, cluster := range o.ClusterMap {
+		if isRegexMapping(orgrepo) || cluster == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--cluster-map option must map an org or org/repo to a cluster: %v=%v.", orgrepo, cluster), Code: 1}
+		}
+	}
+
+	for orgrepo, sa := range o.ServiceAccountMap {
+		if isRegexMapping(orgrepo) || sa == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--service-account-map option must map an org or org/repo to a service account: %v=%v.", orgrepo, sa), Code: 1}
+		}
+	}
+
+	for orgrepo, channel := range o.ChannelMap {
+		if isRegexMapping(orgrepo) || channel == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--channel-map option must map an org or org/repo to a channel: %v=%v.", orgrepo, channel), Code: 1}
+		}
+	}
+
+	for orgrepo, bucket := range o.BucketMap {
+		if isRegexMapping(orgrepo) || bucket == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--bucket-map option must map an org or org/repo to a bucket: %v=%v.", orgrepo, bucket), Code: 1}
+		}
+	}
+
+	for orgrepo, ref := range o.BaseRefMap {
+		if isRegexMapping(orgrepo) || ref == "" {
+			return &util.ExitError{Message: fmt.Sprintf("--base-ref-map option must map an org or org/repo to a base ref: %v=%v.", orgrepo, ref), Code: 1}
+		}
+	}
+
+	for org := range o.ModifierMap {
+		if isRepoMapping(org) {
+			return &util.ExitError{Message: fmt.Sprintf("--modifier-map option key must be an org: %v.", org), Code: 1}
+		}
+	}
+
+	return nil
+}
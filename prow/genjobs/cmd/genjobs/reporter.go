@@ -0,0 +1,156 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// slackStates are the job states that can be reported to Slack.
+var slackStates = []string{
+	string(prowjob.TriggeredState),
+	string(prowjob.PendingState),
+	string(prowjob.SuccessState),
+	string(prowjob.FailureState),
+	string(prowjob.AbortedState),
+	string(prowjob.ErrorState),
+}
+
+// deckReportTemplate is the Slack report template linking a job to its page on the private Deck of the given URL.
+const deckReportTemplate = "Job {{.Spec.Job}} of type {{.Spec.Type}} ended with state {{.Status.State}}. <%v/prowjob?prowjob={{.ObjectMeta.Name}}|View job>"
+
+// slackExtras are the Slack reporter fields of jobs keyed by job.
+// The vendored Prow API only supports the Slack channel, so any other fields
+// (e.g. job_states_to_report, report_template) are patched into the rendered job config.
+type slackExtras map[string]map[string]interface{}
+
+// jobKey returns the key identifying a job of a type within a job config.
+func jobKey(jType, orgrepo, name string) string {
+	return strings.Join([]string{jType, orgrepo, name}, " ")
+}
+
+// slackFields returns the Slack reporter fields to set on the jobs based on provided inputs.
+func slackFields(o options) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	if len(o.SlackJobStates) > 0 {
+		fields["job_states_to_report"] = o.SlackJobStates
+	}
+	if o.SlackReportTemplate != "" {
+		fields["report_template"] = o.SlackReportTemplate
+	} else if o.DeckURL != "" {
+		fields["report_template"] = fmt.Sprintf(deckReportTemplate, strings.TrimSuffix(o.DeckURL, "/"))
+	}
+
+	return fields
+}
+
+// updateSlackExtras records the Slack reporter fields to set on a job based on provided inputs.
+func updateSlackExtras(o options, extras slackExtras, key string, job *prowjob.ReporterConfig) {
+	if job == nil || job.Slack == nil {
+		return
+	}
+
+	if fields := slackFields(o); len(fields) > 0 {
+		extras[key] = fields
+	}
+}
+
+// walkJobs calls fn for each job of a raw job config.
+func walkJobs(raw map[string]interface{}, fn func(key string, job map[string]interface{})) {
+	for _, jType := range []string{"presubmit", "postsubmit"} {
+		repos, _ := raw[jType+"s"].(map[string]interface{})
+		for orgrepo, jobs := range repos {
+			list, _ := jobs.([]interface{})
+			for _, j := range list {
+				if job, ok := j.(map[string]interface{}); ok {
+					name, _ := job["name"].(string)
+					fn(jobKey(jType, orgrepo, name), job)
+				}
+			}
+		}
+	}
+
+	list, _ := raw["periodics"].([]interface{})
+	for _, j := range list {
+		if job, ok := j.(map[string]interface{}); ok {
+			name, _ := job["name"].(string)
+			fn(jobKey("periodic", "", name), job)
+		}
+	}
+}
+
+// slackConfig returns the raw Slack reporter config of a job, if any.
+func slackConfig(job map[string]interface{}) map[string]interface{} {
+	reporter, _ := job["reporter_config"].(map[string]interface{})
+	slack, _ := reporter["slack"].(map[string]interface{})
+	return slack
+}
+
+// readSlackExtras reads the Slack reporter fields unsupported by the vendored Prow API from a job config file.
+func readSlackExtras(p string) slackExtras {
+	extras := slackExtras{}
+
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return extras
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(d, &raw); err != nil {
+		return extras
+	}
+
+	walkJobs(raw, func(key string, job map[string]interface{}) {
+		fields := map[string]interface{}{}
+		for k, v := range slackConfig(job) {
+			if k != "channel" {
+				fields[k] = v
+			}
+		}
+		if len(fields) > 0 {
+			extras[key] = fields
+		}
+	})
+
+	return extras
+}
+
+// patchSlackExtras sets the Slack reporter fields of the jobs in a rendered job config.
+func patchSlackExtras(b []byte, extras slackExtras) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	walkJobs(raw, func(key string, job map[string]interface{}) {
+		slack := slackConfig(job)
+		if slack == nil {
+			return
+		}
+		for k, v := range extras[key] {
+			slack[k] = v
+		}
+	})
+
+	return yaml.Marshal(raw)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	periodicSuffix                = "-periodic"
+	defaultPostsubmitPeriodicCron = "0 0 * * *"
+)
+
+// updateSchedule updates the jobs Cron and Interval fields based on provided inputs.
+func updateSchedule(o options, job *config.Periodic) {
+	if o.IntervalScale > 0 && o.IntervalScale != 1 {
+		if job.Interval != "" {
+			job.Interval = scaleInterval(job.Interval, o.IntervalScale)
+		}
+		if job.Cron != "" {
+			job.Cron = scaleCron(job.Cron, o.IntervalScale)
+		}
+	}
+
+	if o.IntervalToCron && job.Interval != "" {
+		if cron, ok := intervalCron(job.Interval); ok {
+			job.Cron = cron
+			job.Interval = ""
+		} else {
+			util.PrintErr(fmt.Sprintf("unable to convert interval %v of periodic %v to cron.", job.Interval, job.Name))
+		}
+	}
+
+	if o.CronJitter > 0 && job.Cron != "" {
+		job.Cron = jitterCron(job.Cron, cronJitter(job.Name, o.CronJitter))
+	}
+}
+
+// postsubmitPeriodic mirrors a postsubmit matching the postsubmit periodic pattern(s) as a periodic running against its
+// branch on the postsubmit periodic cron schedule.
+func postsubmitPeriodic(o options, job config.Postsubmit, orgrepo string) (config.Periodic, bool) {
+	if !hasMatch(job.Name, o.PostsubmitPeriodics) {
+		return config.Periodic{}, false
+	}
+
+	branch := "master"
+	if len(job.Branches) > 0 {
+		// Branch patterns are commonly written with unescaped dots (e.g. ^release-1.5$), so only reject meta characters
+		// that could match more than one branch.
+		branch = strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(job.Branches[0], "^"), "$"), `\`, "")
+		if strings.ContainsAny(branch, "*+?()[]{}|^$") || !util.MustCompile(job.Branches[0]).MatchString(branch) {
+			util.PrintErr(fmt.Sprintf("unable to mirror postsubmit %v with branch pattern %v as periodic.", job.Name, job.Branches[0]))
+			return config.Periodic{}, false
+		}
+	}
+
+	org, repo := util.SplitOrgRepo(orgrepo)
+	refs := prowjob.Refs{
+		Org:       org,
+		Repo:      repo,
+		BaseRef:   branch,
+		PathAlias: job.PathAlias,
+		CloneURI:  job.CloneURI,
+	}
+
+	periodic := config.Periodic{
+		JobBase: job.JobBase,
+		Cron:    o.PostsubmitPeriodicCron,
+	}
+	periodic.Name = job.Name + periodicSuffix
+	periodic.PathAlias = ""
+	periodic.CloneURI = ""
+	periodic.ExtraRefs = append([]prowjob.Refs{refs}, job.ExtraRefs...)
+
+	updateSchedule(o, &periodic)
+
+	return periodic, true
+}
+
+// cronJitter returns the offset in minutes of a job within [0, jitter], deterministically seeded by its name.
+func cronJitter(name string, jitter int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+
+	return int(h.Sum32() % uint32(jitter+1))
+}
+
+// jitterCron offsets the minute field of a cron schedule by a number of minutes. Fixed minutes wrap within the hour
+// and steps (e.g. */15) start at the offset modulo the step. Other minute fields (e.g. *, ranges) are left as is.
+func jitterCron(cron string, offset int) string {
+	fields := strings.Fields(cron)
+	if len(fields) != 5 || offset == 0 {
+		return cron
+	}
+
+	items := strings.Split(fields[0], ",")
+	for i, item := range items {
+		if m, err := strconv.Atoi(item); err == nil {
+			items[i] = strconv.Itoa((m + offset) % 60)
+		} else if strings.HasPrefix(item, "*/") {
+			if step, err := strconv.Atoi(item[2:]); err == nil && step > 0 && offset%step > 0 {
+				items[i] = fmt.Sprintf("%d-59/%d", offset%step, step)
+			}
+		}
+	}
+	fields[0] = strings.Join(items, ",")
+
+	return strings.Join(fields, " ")
+}
+
+// cronStepMax is the maximum step of the minute, hour, and day of month cron fields.
+var cronStepMax = []int{59, 23, 31}
+
+// scaleInterval multiplies an interval by a factor, rounded to the minute.
+func scaleInterval(interval string, scale float64) string {
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return interval
+	}
+
+	d = time.Duration(float64(d) * scale).Round(time.Minute)
+	if d < time.Minute {
+		d = time.Minute
+	}
+
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// scaleCron stretches the frequency of a cron schedule by a factor, multiplying the step of its most frequent wildcard
+// or stepped field among the minute, hour, and day of month fields (e.g. 0 */2 * * * scaled by 2 becomes 0 */4 * * *).
+// Schedules whose frequency cannot be stretched this way (e.g. lists or steps beyond the field range) are left as is.
+func scaleCron(cron string, scale float64) string {
+	fields := strings.Fields(cron)
+	if len(fields) != 5 {
+		return cron
+	}
+
+	for i, max := range cronStepMax {
+		base, step := fields[i], 1
+		if idx := strings.Index(base, "/"); idx >= 0 {
+			s, err := strconv.Atoi(base[idx+1:])
+			if err != nil || s < 1 {
+				return cron
+			}
+			base, step = base[:idx], s
+		} else if base != "*" {
+			continue
+		}
+
+		// Stepping the day of month is only equivalent when the day of week is unrestricted.
+		if i == 2 && fields[4] != "*" {
+			return cron
+		}
+
+		scaled := int(math.Max(1, math.Round(float64(step)*scale)))
+		if scaled > max {
+			return cron
+		}
+
+		fields[i] = base
+		if scaled > 1 {
+			fields[i] = fmt.Sprintf("%s/%d", base, scaled)
+		}
+
+		return strings.Join(fields, " ")
+	}
+
+	return cron
+}
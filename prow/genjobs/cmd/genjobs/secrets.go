@@ -0,0 +1,233 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"bytes"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	sealedSecretKind   = "SealedSecret"
+	externalSecretKind = "ExternalSecret"
+	// placeholderValue is the value to replace in the generated secret manifests.
+	placeholderValue = "REPLACE_ME"
+	// gcsCredentialsKey is the key of the GCS and S3 credentials secrets expected by the pod utilities.
+	gcsCredentialsKey = "service-account.json"
+)
+
+var secretKinds = []string{sealedSecretKind, externalSecretKind}
+
+// secretRefs are the keys referenced of each secret keyed by secret name.
+type secretRefs map[string]sets.String
+
+// add adds a secret and its referenced key(s).
+func (s secretRefs) add(name string, keys ...string) {
+	if name == "" {
+		return
+	}
+
+	if _, ok := s[name]; !ok {
+		s[name] = sets.NewString()
+	}
+	for _, key := range keys {
+		if key != "" {
+			s[name].Insert(key)
+		}
+	}
+}
+
+// addVolumes adds the secrets referenced by secret and projected volumes.
+func (s secretRefs) addVolumes(volumes []v1.Volume) {
+	for _, volume := range volumes {
+		if volume.Secret != nil {
+			s.add(volume.Secret.SecretName, keyPaths(volume.Secret.Items)...)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					s.add(source.Secret.Name, keyPaths(source.Secret.Items)...)
+				}
+			}
+		}
+	}
+}
+
+// addEnv adds the secrets referenced by envs.
+func (s secretRefs) addEnv(env []v1.EnvVar) {
+	for _, e := range env {
+		if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+			s.add(e.ValueFrom.SecretKeyRef.Name, e.ValueFrom.SecretKeyRef.Key)
+		}
+	}
+}
+
+// addJob adds the secrets referenced by the PodSpec and decoration config of a job.
+func (s secretRefs) addJob(job config.JobBase, utility config.UtilityConfig) {
+	if job.Spec != nil {
+		s.addVolumes(job.Spec.Volumes)
+		for _, containers := range [][]v1.Container{job.Spec.InitContainers, job.Spec.Containers} {
+			for _, c := range containers {
+				s.addEnv(c.Env)
+				for _, from := range c.EnvFrom {
+					if from.SecretRef != nil {
+						s.add(from.SecretRef.Name)
+					}
+				}
+			}
+		}
+	}
+
+	s.addDecoration(utility.DecorationConfig)
+}
+
+// addDecoration adds the secrets referenced by a decoration config.
+func (s secretRefs) addDecoration(dc *prowjob.DecorationConfig) {
+	if dc == nil {
+		return
+	}
+
+	s.add(dc.GCSCredentialsSecret, gcsCredentialsKey)
+	s.add(dc.S3CredentialsSecret, gcsCredentialsKey)
+	s.add(dc.CookiefileSecret)
+	for _, name := range dc.SSHKeySecrets {
+		s.add(name)
+	}
+	if dc.OauthTokenSecret != nil {
+		s.add(dc.OauthTokenSecret.Name, dc.OauthTokenSecret.Key)
+	}
+}
+
+// keyPaths returns the keys of the projected secret items.
+func keyPaths(items []v1.KeyToPath) []string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+// collectSecretRefs collects the secrets referenced by the jobs and presets of a jobSet.
+func collectSecretRefs(jobs *jobSet) secretRefs {
+	refs := secretRefs{}
+
+	for _, pre := range jobs.presubmits {
+		for _, job := range pre {
+			refs.addJob(job.JobBase, job.UtilityConfig)
+		}
+	}
+	for _, post := range jobs.postsubmits {
+		for _, job := range post {
+			refs.addJob(job.JobBase, job.UtilityConfig)
+		}
+	}
+	for _, job := range jobs.periodics {
+		refs.addJob(job.JobBase, job.UtilityConfig)
+	}
+	for _, preset := range jobs.presets {
+		refs.addVolumes(preset.Volumes)
+		refs.addEnv(preset.Env)
+	}
+
+	return refs
+}
+
+// secretManifest returns the template SealedSecret or ExternalSecret manifest of a secret with placeholder values.
+func secretManifest(o options, name string, keys []string) map[string]interface{} {
+	if len(keys) == 0 {
+		keys = []string{placeholderValue}
+	}
+
+	metadata := map[string]interface{}{"name": name, "namespace": o.SecretsNamespace}
+
+	if o.SecretsKind == externalSecretKind {
+		data := make([]map[string]string, 0, len(keys))
+		for _, key := range keys {
+			data = append(data, map[string]string{"key": placeholderValue, "name": key})
+		}
+
+		return map[string]interface{}{
+			"apiVersion": "kubernetes-client.io/v1",
+			"kind":       externalSecretKind,
+			"metadata":   metadata,
+			"spec": map[string]interface{}{
+				"backendType": placeholderValue,
+				"data":        data,
+			},
+		}
+	}
+
+	encryptedData := make(map[string]string, len(keys))
+	for _, key := range keys {
+		encryptedData[key] = placeholderValue
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "bitnami.com/v1alpha1",
+		"kind":       sealedSecretKind,
+		"metadata":   metadata,
+		"spec": map[string]interface{}{
+			"encryptedData": encryptedData,
+			"template":      map[string]interface{}{"metadata": metadata},
+		},
+	}
+}
+
+// writeSecretManifests writes template secret manifests for every secret referenced by the jobs.
+func writeSecretManifests(o options, jobs *jobSet) {
+	refs := collectSecretRefs(jobs)
+	if len(refs) == 0 {
+		return
+	}
+
+	if o.Verbose {
+		fmt.Printf("write %d secret manifests to path %v\n", len(refs), o.SecretsOutput)
+	}
+
+	if o.DryRun {
+		return
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(outHeader(o, nil))
+
+	for i, name := range sets.NewString(names...).List() {
+		b, err := yaml.Marshal(secretManifest(o, name, refs[name].List()))
+		if err != nil {
+			reportErr(o, fmt.Sprintf("unable to marshal secret manifest %v: %v.", name, err))
+			return
+		}
+
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(b)
+	}
+
+	writeConfigFile(o, o.SecretsOutput, buf.Bytes())
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	defaultHealthPort = 8081
+	healthPath        = "/healthz"
+	readyPath         = "/healthz/ready"
+	shutdownTimeout   = 5 * time.Second
+)
+
+// health tracks the liveness and readiness of a long-running genjobs process.
+// The process is ready once the first generation has completed.
+type health struct {
+	ready int32
+}
+
+// setReady marks the process as ready.
+func (h *health) setReady() {
+	atomic.StoreInt32(&h.ready, 1)
+}
+
+// register registers the health and readiness handlers on a mux.
+func (h *health) register(mux *http.ServeMux) {
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "OK")
+	})
+	mux.HandleFunc(readyPath, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&h.ready) == 0 {
+			http.Error(w, "generation pending", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "OK")
+	})
+}
+
+// startServer serves an HTTP handler on a port in the background.
+// Errors serving the handler are sent on the returned channel.
+func startServer(port int, handler http.Handler) (*http.Server, <-chan error) {
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- fmt.Errorf("unable to serve on port %d: %v", port, err)
+		}
+	}()
+
+	return server, errCh
+}
+
+// startHealthServer serves the health endpoints in the background.
+func startHealthServer(port int, h *health) (*http.Server, <-chan error) {
+	mux := http.NewServeMux()
+	h.register(mux)
+
+	return startServer(port, mux)
+}
+
+// shutdownServer gracefully shuts down an HTTP server.
+func shutdownServer(server *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return server.Shutdown(ctx)
+}
+
+// terminationSignals returns a channel notified on SIGINT and SIGTERM, and a function to stop notifications.
+func terminationSignals() (<-chan os.Signal, func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	return sigCh, func() { signal.Stop(sigCh) }
+}
+
+// runScheduled runs generate every interval and serves the health endpoints until terminated.
+func runScheduled(o options, generate func()) error {
+	var h health
+
+	server, errCh := startHealthServer(o.HealthPort, &h)
+
+	sigCh, stop := terminationSignals()
+	defer stop()
+
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		generate()
+		h.setReady()
+
+		if o.Verbose {
+			fmt.Printf("generated job(s) in %v, next generation in %v\n", time.Since(start), o.Interval)
+		}
+
+		select {
+		case <-ticker.C:
+		case err := <-errCh:
+			return err
+		case sig := <-sigCh:
+			util.PrintErr(fmt.Sprintf("received %v, shutting down.", sig))
+			return shutdownServer(server)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"sort"
+
+	prowjob "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// inTenant returns whether the public org belongs to the tenant of the options.
+// Orgs without a tenant belong to the default tenant.
+func inTenant(o options, org string) bool {
+	return o.Tenants[org] == o.tenant
+}
+
+// refsInTenant returns whether the first converted ref of a periodic belongs to the tenant of the options.
+func refsInTenant(o options, refs []prowjob.Refs) bool {
+	if org := refsOrg(o, refs); org != "" {
+		return inTenant(o, org)
+	}
+
+	return false
+}
+
+// refsOrg returns the public org of the first mapped ref, which is the org a periodic belongs to.
+func refsOrg(o options, refs []prowjob.Refs) string {
+	org, _ := refsOrgRepo(o, refs)
+	return org
+}
+
+// refsOrgRepo returns the public org and repo of the first mapped ref, which is the repo a periodic belongs to.
+func refsOrgRepo(o options, refs []prowjob.Refs) (string, string) {
+	for _, ref := range refs {
+		if validateOrgRepo(o, ref.Org, ref.Repo) {
+			return ref.Org, ref.Repo
+		}
+	}
+
+	return "", ""
+}
+
+// expandTenants returns the options of each tenant of the options, routed to the tenants output root,
+// cluster and bucket. The default tenant keeps the original output root, cluster and bucket.
+func expandTenants(optsList []options) []options {
+	var expanded []options
+
+	for _, o := range optsList {
+		expanded = append(expanded, o)
+
+		if len(o.Tenants) == 0 {
+			continue
+		}
+
+		tenants := make([]string, 0, len(o.TenantOutputs))
+		for tenant := range o.TenantOutputs {
+			tenants = append(tenants, tenant)
+		}
+		sort.Strings(tenants)
+
+		for _, tenant := range tenants {
+			to := o
+			to.tenant = tenant
+			to.Output = o.TenantOutputs[tenant]
+			if cluster, ok := o.TenantClusters[tenant]; ok {
+				to.Cluster = cluster
+			}
+			if bucket, ok := o.TenantBuckets[tenant]; ok {
+				to.Bucket = bucket
+			}
+
+			expanded = append(expanded, to)
+		}
+	}
+
+	return expanded
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+var (
+	defaultTideLabels        = []string{"lgtm", "approved"}
+	defaultTideMissingLabels = []string{"do-not-merge", "do-not-merge/hold", "do-not-merge/work-in-progress", "needs-rebase"}
+	tideMergeMethods         = []string{string(github.MergeMerge), string(github.MergeSquash), string(github.MergeRebase)}
+)
+
+// tideFragment is the Prow config fragment containing the generated Tide configuration.
+type tideFragment struct {
+	Tide config.Tide `json:"tide"`
+}
+
+// presubmitContext returns the GitHub status context of a presubmit, defaulting to its name.
+func presubmitContext(job config.Presubmit) string {
+	if job.Context != "" {
+		return job.Context
+	}
+	return job.Name
+}
+
+// branchName returns the literal branch name of a branch pattern (e.g. ^master$), if it matches a single branch.
+func branchName(pattern string) (string, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	if name == "" || regexp.QuoteMeta(name) != name {
+		return "", false
+	}
+	return name, true
+}
+
+// contextPolicies derives the context policy of each org/repo from its presubmits.
+// Contexts of presubmits restricted to literal branches are required on those branches only, while
+// contexts of presubmits restricted by other branch patterns are required if present.
+func contextPolicies(presubmits map[string][]config.Presubmit) map[string]config.TideRepoContextPolicy {
+	policies := map[string]config.TideRepoContextPolicy{}
+
+	for orgrepo, pre := range presubmits {
+		var (
+			required          = sets.NewString()
+			requiredIfPresent = sets.NewString()
+			optional          = sets.NewString()
+			branches          = map[string]sets.String{}
+		)
+
+		for _, job := range pre {
+			context := presubmitContext(job)
+
+			if !job.ContextRequired() {
+				optional.Insert(context)
+				continue
+			}
+
+			if len(job.Branches) == 0 {
+				required.Insert(context)
+				continue
+			}
+
+			for _, pattern := range job.Branches {
+				branch, ok := branchName(pattern)
+				if !ok {
+					requiredIfPresent.Insert(context)
+					continue
+				}
+				if _, exists := branches[branch]; !exists {
+					branches[branch] = sets.NewString()
+				}
+				branches[branch].Insert(context)
+			}
+		}
+
+		policy := config.TideRepoContextPolicy{
+			TideContextPolicy: config.TideContextPolicy{
+				RequiredContexts:          required.List(),
+				RequiredIfPresentContexts: requiredIfPresent.List(),
+				OptionalContexts:          optional.List(),
+			},
+		}
+
+		if len(branches) > 0 {
+			policy.Branches = map[string]config.TideContextPolicy{}
+			for branch, contexts := range branches {
+				policy.Branches[branch] = config.TideContextPolicy{RequiredContexts: contexts.List()}
+			}
+		}
+
+		policies[orgrepo] = policy
+	}
+
+	return policies
+}
+
+// buildTideConfig builds the Tide configuration merging the pull requests of the org/repos with presubmits.
+func buildTideConfig(o options, presubmits map[string][]config.Presubmit) config.Tide {
+	labels, missingLabels := o.TideLabels, o.TideMissingLabels
+	if len(labels) == 0 {
+		labels = defaultTideLabels
+	}
+	if len(missingLabels) == 0 {
+		missingLabels = defaultTideMissingLabels
+	}
+
+	repos := sortedOrgRepos(presubmits)
+
+	skipUnknown := true
+	tide := config.Tide{
+		Queries: config.TideQueries{{
+			Repos:         repos,
+			Labels:        labels,
+			MissingLabels: missingLabels,
+		}},
+		ContextOptions: config.TideContextPolicyOptions{
+			TideContextPolicy: config.TideContextPolicy{SkipUnknownContexts: &skipUnknown},
+			Orgs:              map[string]config.TideOrgContextPolicy{},
+		},
+	}
+
+	if o.TideMergeMethod != "" {
+		tide.MergeType = map[string]github.PullRequestMergeType{}
+	}
+
+	for orgrepo, policy := range contextPolicies(presubmits) {
+		org, repo := util.SplitOrgRepo(orgrepo)
+
+		orgPolicy, exists := tide.ContextOptions.Orgs[org]
+		if !exists {
+			orgPolicy = config.TideOrgContextPolicy{Repos: map[string]config.TideRepoContextPolicy{}}
+		}
+		orgPolicy.Repos[repo] = policy
+		tide.ContextOptions.Orgs[org] = orgPolicy
+
+		if o.TideMergeMethod != "" {
+			tide.MergeType[orgrepo] = github.PullRequestMergeType(o.TideMergeMethod)
+		}
+	}
+
+	return tide
+}
+
+// writeTideConfig writes the Tide configuration fragment for the org/repos with presubmits.
+func writeTideConfig(o options, presubmits map[string][]config.Presubmit) {
+	if len(presubmits) == 0 {
+		return
+	}
+
+	if o.Verbose {
+		fmt.Printf("write tide configuration for %d repositories to path %v\n", len(presubmits), o.TideConfig)
+	}
+
+	if o.DryRun {
+		return
+	}
+
+	b, err := yaml.Marshal(tideFragment{Tide: buildTideConfig(o, presubmits)})
+	if err != nil {
+		reportErr(o, fmt.Sprintf("unable to marshal tide configuration: %v.", err))
+		return
+	}
+
+	writeConfigFile(o, o.TideConfig, append([]byte(outHeader(o, nil)), b...))
+}
+
+// writeConfigFile writes a generated configuration file, creating its directory if needed.
+func writeConfigFile(o options, p string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		reportErr(o, fmt.Sprintf("unable to create output directory %v: %v.", filepath.Dir(p), err))
+	}
+
+	if err := ioutil.WriteFile(p, data, 0644); err != nil {
+		reportErr(o, fmt.Sprintf("unable to write configuration to path %v: %v.", p, err))
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/test-infra/prow/config"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+// updateTriggers updates the jobs Trigger, RerunCommand, and Context fields to match the modified job name.
+// Prow defaults them from the job name when unset, so only explicit values are rewritten.
+func updateTriggers(o options, job *config.Presubmit, name string) {
+	if !o.RewriteTriggers || name == job.Name {
+		return
+	}
+
+	re := util.MustCompile(`(^|[^\w.-])` + regexp.QuoteMeta(name) + `($|[^\w.-])`)
+	repl := "${1}" + job.Name + "${2}"
+
+	job.Trigger = re.ReplaceAllString(job.Trigger, repl)
+	job.RerunCommand = re.ReplaceAllString(job.RerunCommand, repl)
+
+	if job.Context == "" || o.Modifier == "" {
+		return
+	}
+
+	suffix := jobnameSeparator + o.Modifier
+	if o.Reverse {
+		job.Context = strings.TrimSuffix(job.Context, suffix)
+	} else if !strings.HasSuffix(job.Context, suffix) {
+		job.Context += suffix
+	}
+}
+
+// updateBrancher updates the jobs Brancher fields based on provided inputs.
+func updateBrancher(o options, job *config.Brancher) {
+	if len(o.BranchesOut) > 0 {
+		job.Branches = o.BranchesOut
+	} else {
+		job.Branches = mapBranchPatterns(o, job.Branches)
+	}
+	job.SkipBranches = mapBranchPatterns(o, job.SkipBranches)
+}
+
+// mapBranch returns the private branch name of a public branch name.
+func mapBranch(o options, branch string) string {
+	if newBranch, ok := o.BranchMap[branch]; ok {
+		return newBranch
+	}
+
+	return branch
+}
+
+// mapBranchPatterns returns the branch patterns with the mapped branch name(s) they reference renamed.
+func mapBranchPatterns(o options, patterns []string) []string {
+	if len(o.BranchMap) == 0 || len(patterns) == 0 {
+		return patterns
+	}
+
+	mapped := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		mapped = append(mapped, branchNameRegex.ReplaceAllStringFunc(pattern, func(name string) string {
+			newBranch, ok := o.BranchMap[strings.ReplaceAll(name, `\.`, ".")]
+			if !ok {
+				return name
+			}
+			if strings.Contains(name, `\.`) {
+				return regexp.QuoteMeta(newBranch)
+			}
+			return newBranch
+		}))
+	}
+
+	return mapped
+}
+
+// updateGerritReportingLabels updates the gerrit reporting labels based on provided inputs.
+func updateGerritReportingLabels(o options, skipReport, optional bool, labels map[string]string) {
+	if o.SupportGerritReporting && !skipReport {
+		if !optional {
+			// For non-optional jobs, only add the label if it's not configured,
+			// this allows us defining internal jobs that report to a different label.
+			if _, ok := labels[gerritReportLabel]; !ok {
+				labels[gerritReportLabel] = "Verified"
+			}
+		} else {
+			labels[gerritReportLabel] = "Advisory"
+		}
+	} else {
+		delete(labels, gerritReportLabel)
+	}
+}
+
+// updateReporter updates the jobs Reporter fields based on provided inputs.
+func updateReporter(o options, job *config.Reporter) {
+	if o.SkipReport {
+		job.SkipReport = true
+	}
+}
+
+// updateChangeMatcher updates the jobs RunIfChanged fields based on provided inputs.
+func updateChangeMatcher(o options, job *config.RegexpChangeMatcher) {
+	if o.RunIfChangedPrefix == "" || job.RunIfChanged == "" {
+		return
+	}
+
+	job.RunIfChanged = prefixAnchors(job.RunIfChanged, regexp.QuoteMeta(o.RunIfChangedPrefix))
+}
+
+// prefixAnchors inserts a prefix after each ^ anchor of a regex, skipping escaped characters and negated
+// character classes. Unanchored regexes already match files within a subdirectory and are returned as-is.
+func prefixAnchors(pattern, prefix string) string {
+	var b strings.Builder
+
+	escaped, inClass := false, false
+	for i, c := range pattern {
+		b.WriteRune(c)
+
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case inClass:
+			inClass = c != ']' || strings.HasSuffix(pattern[:i], "[") || strings.HasSuffix(pattern[:i], "[^")
+		case c == '[':
+			inClass = true
+		case c == '^':
+			b.WriteString(prefix)
+		}
+	}
+
+	return b.String()
+}
+
+// updateRunPolicy updates the jobs Optional and AlwaysRun fields based on provided inputs.
+// Always running jobs cannot also run if changed, so their RunIfChanged field is cleared.
+func updateRunPolicy(o options, job *config.Presubmit) {
+	if o.Optional {
+		job.Optional = true
+	}
+
+	if o.AlwaysRun == "" {
+		return
+	}
+
+	alwaysRun, err := strconv.ParseBool(o.AlwaysRun)
+	if err != nil {
+		return
+	}
+
+	job.AlwaysRun = alwaysRun
+	if alwaysRun {
+		job.RunIfChanged = ""
+	}
+}
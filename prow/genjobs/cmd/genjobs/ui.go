@@ -0,0 +1,325 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const (
+	defaultUIPort      = 8080
+	defaultProfileName = "default"
+)
+
+// profile is a named set of generation options selectable in the web UI.
+type profile struct {
+	Name string `json:"name"`
+	opts options
+}
+
+// sourceJob is a public job selectable in the web UI.
+type sourceJob struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// previewRequest is the request to preview the transformation of a job.
+type previewRequest struct {
+	Profile string `json:"profile"`
+	// Config is a pasted job config (yaml). If empty, Path is read from the jobs directory.
+	Config string `json:"config"`
+	Path   string `json:"path"`
+	Job    string `json:"job"`
+}
+
+// previewResponse is the preview of the transformation of a job.
+type previewResponse struct {
+	OutputPath string           `json:"outputPath"`
+	Source     string           `json:"source"`
+	Generated  string           `json:"generated"`
+	Diff       []util.FieldDiff `json:"diff"`
+}
+
+// ui serves the web UI for previewing transformations.
+type ui struct {
+	profiles []profile
+	jobsDir  string
+}
+
+// newUI creates the web UI from the command-line and configuration file transforms.
+func newUI(o options) (*ui, error) {
+	u := &ui{}
+
+	if o.serve.JobsDir != "" {
+		if dir, err := filepath.Abs(o.serve.JobsDir); err == nil {
+			u.jobsDir = dir
+		}
+	}
+
+	if len(o.OrgMap) > 0 {
+		cli := o
+		cli.Name = defaultProfileName
+		u.profiles = append(u.profiles, profile{Name: cli.Name, opts: cli})
+	}
+
+	configs, err := o.parseConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range configs {
+		u.profiles = append(u.profiles, profile{Name: c.Name, opts: c})
+	}
+
+	return u, nil
+}
+
+// register registers the web UI handlers on a mux.
+func (u *ui) register(mux *http.ServeMux) {
+	mux.HandleFunc("/", u.handleIndex)
+	mux.HandleFunc("/api/profiles", u.handleProfiles)
+	mux.HandleFunc("/api/jobs", u.handleJobs)
+	mux.HandleFunc("/api/preview", u.handlePreview)
+}
+
+func (u *ui) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, uiPage)
+}
+
+func (u *ui) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, u.profiles)
+}
+
+func (u *ui) handleJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := []sourceJob{}
+
+	if u.jobsDir != "" {
+		for _, p := range collectInputFiles(options{transform: transform{Input: u.jobsDir}}) {
+			jc, err := config.ReadJobConfig(p)
+			if err != nil {
+				continue
+			}
+
+			rel, _ := filepath.Rel(u.jobsDir, p)
+			for _, name := range jobNames(jc) {
+				jobs = append(jobs, sourceJob{Name: name, Path: filepath.ToSlash(rel)})
+			}
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+
+	writeJSON(w, jobs)
+}
+
+func (u *ui) handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req previewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var prof *profile
+	for i := range u.profiles {
+		if u.profiles[i].Name == req.Profile {
+			prof = &u.profiles[i]
+		}
+	}
+	if prof == nil {
+		http.Error(w, fmt.Sprintf("unknown profile: %v", req.Profile), http.StatusBadRequest)
+		return
+	}
+
+	inputs, err := u.previewInputs(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exp, err := explainJob(prof.opts, inputs, req.Job)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var source, generated interface{}
+	_ = yaml.Unmarshal([]byte(exp.Source), &source)
+	_ = yaml.Unmarshal([]byte(exp.Generated), &generated)
+
+	diff, err := util.DiffFields(source, generated)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, previewResponse{
+		OutputPath: exp.OutputPath,
+		Source:     exp.Source,
+		Generated:  exp.Generated,
+		Diff:       diff,
+	})
+}
+
+// previewInputs returns the job config files for a preview request, either pasted or read from the jobs directory.
+func (u *ui) previewInputs(req previewRequest) (map[string][]byte, error) {
+	if req.Config == "" {
+		if u.jobsDir == "" || req.Path == "" {
+			return nil, fmt.Errorf("either a job config or a job path is required")
+		}
+
+		inputs := map[string][]byte{}
+		content, err := ioutil.ReadFile(filepath.Join(u.jobsDir, filepath.Clean(string(filepath.Separator)+filepath.FromSlash(req.Path))))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read job config %v: %v", req.Path, err)
+		}
+		inputs[req.Path] = content
+
+		return inputs, nil
+	}
+
+	return map[string][]byte{"jobs.yaml": []byte(req.Config)}, nil
+}
+
+// jobNames returns the names of all jobs in a job config.
+func jobNames(jc config.JobConfig) []string {
+	var names []string
+
+	for _, pre := range jc.PresubmitsStatic {
+		for _, job := range pre {
+			names = append(names, job.Name)
+		}
+	}
+	for _, post := range jc.PostsubmitsStatic {
+		for _, job := range post {
+			names = append(names, job.Name)
+		}
+	}
+	for _, job := range jc.Periodics {
+		names = append(names, job.Name)
+	}
+
+	return names
+}
+
+// writeJSON writes a value as a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// uiPage is the single page of the web UI.
+const uiPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>genjobs preview</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+textarea, pre { width: 100%; font-family: monospace; font-size: 12px; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+.columns { display: flex; gap: 1em; }
+.columns > div { flex: 1; min-width: 0; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: 4px 8px; font-family: monospace; font-size: 12px; text-align: left; }
+.before { background: #ffeef0; }
+.after { background: #e6ffed; }
+</style>
+</head>
+<body>
+<h1>genjobs preview</h1>
+<p>
+  <label>Profile <select id="profile"></select></label>
+  <label>Job <select id="job"><option value="">(paste below)</option></select></label>
+</p>
+<p><label>Job name <input id="name" placeholder="name of the job to preview"></label></p>
+<textarea id="config" rows="16" placeholder="Paste a public job config (yaml), e.g. presubmits: {istio/istio: [...]}"></textarea>
+<p><button id="preview">Preview</button> <span id="error" style="color: red"></span></p>
+<p id="output"></p>
+<div class="columns">
+  <div><h3>Public</h3><pre id="source"></pre></div>
+  <div><h3>Private</h3><pre id="generated"></pre></div>
+</div>
+<h3>Field changes</h3>
+<table id="diff"><thead><tr><th>Field</th><th>Public</th><th>Private</th></tr></thead><tbody></tbody></table>
+<script>
+const $ = (id) => document.getElementById(id);
+const fmt = (v) => v === undefined ? "" : JSON.stringify(v);
+
+fetch("/api/profiles").then((r) => r.json()).then((profiles) => {
+  for (const p of profiles || []) $("profile").add(new Option(p.name, p.name));
+});
+fetch("/api/jobs").then((r) => r.json()).then((jobs) => {
+  for (const j of jobs || []) $("job").add(new Option(j.name + " (" + j.path + ")", JSON.stringify(j)));
+});
+$("job").onchange = () => {
+  if ($("job").value) $("name").value = JSON.parse($("job").value).name;
+};
+$("preview").onclick = async () => {
+  $("error").textContent = "";
+  const picked = $("job").value ? JSON.parse($("job").value) : {};
+  const resp = await fetch("/api/preview", {
+    method: "POST",
+    body: JSON.stringify({profile: $("profile").value, config: picked.path ? "" : $("config").value, path: picked.path || "", job: $("name").value}),
+  });
+  if (!resp.ok) {
+    $("error").textContent = await resp.text();
+    return;
+  }
+  const res = await resp.json();
+  $("output").textContent = res.outputPath ? "Output: " + res.outputPath : "";
+  $("source").textContent = res.source;
+  $("generated").textContent = res.generated || "(filtered out)";
+  const body = $("diff").tBodies[0];
+  body.innerHTML = "";
+  for (const d of res.diff || []) {
+    const row = body.insertRow();
+    row.insertCell().textContent = d.path;
+    const before = row.insertCell(), after = row.insertCell();
+    before.textContent = fmt(d.before);
+    after.textContent = fmt(d.after);
+    if (d.before !== undefined) before.className = "before";
+    if (d.after !== undefined) after.className = "after";
+  }
+};
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth" // Enable all auth provider plugins
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/test-infra/prow/config"
+)
+
+const (
+	defaultPodNamespace  = "test-pods"
+	defaultContainerName = "test"
+	validatePodPrefix    = "genjobs-validate-"
+)
+
+// podValidator validates pods against a build cluster.
+type podValidator interface {
+	validate(cluster string, pod *v1.Pod) error
+}
+
+// clusterValidator validates pods with a server-side dry-run create against the build clusters of a kubeconfig.
+// The build cluster aliases of the jobs are used as kubeconfig context names, the default cluster using the current context.
+type clusterValidator struct {
+	kubeconfig string
+	namespace  string
+	clients    map[string]kubernetes.Interface
+}
+
+// newClusterValidator returns a clusterValidator for the kubeconfig.
+func newClusterValidator(kubeconfig, namespace string) *clusterValidator {
+	return &clusterValidator{
+		kubeconfig: kubeconfig,
+		namespace:  namespace,
+		clients:    map[string]kubernetes.Interface{},
+	}
+}
+
+// client returns the client for the cluster, creating it if needed.
+func (c *clusterValidator) client(cluster string) (kubernetes.Interface, error) {
+	if client, ok := c.clients[cluster]; ok {
+		return client, nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{}
+	if cluster != "" && cluster != defaultCluster {
+		overrides.CurrentContext = cluster
+	}
+
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.clients[cluster] = client
+
+	return client, nil
+}
+
+// validate performs a server-side dry-run create of the pod in the cluster.
+func (c *clusterValidator) validate(cluster string, pod *v1.Pod) error {
+	client, err := c.client(cluster)
+	if err != nil {
+		return err
+	}
+
+	// The typed client of the vendored client-go does not support create options, so use the REST client.
+	return client.CoreV1().RESTClient().Post().
+		Namespace(c.namespace).
+		Resource("pods").
+		Param("dryRun", metav1.DryRunAll).
+		Body(pod).
+		Do().
+		Error()
+}
+
+// representativePod returns a pod approximating the one Prow would create for the job.
+func representativePod(job config.JobBase) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: validatePodPrefix,
+			Labels:       job.Labels,
+			Annotations:  job.Annotations,
+		},
+		Spec: *job.Spec.DeepCopy(),
+	}
+
+	if pod.Spec.RestartPolicy == "" {
+		pod.Spec.RestartPolicy = v1.RestartPolicyNever
+	}
+
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == "" {
+			pod.Spec.Containers[i].Name = defaultContainerName
+		}
+	}
+
+	return pod
+}
+
+// validateJobSet validates representative pods of the jobs against their build clusters and returns the rejections.
+func validateJobSet(v podValidator, jobs *jobSet) []error {
+	var bases []config.JobBase
+
+	orgrepos := make([]string, 0, len(jobs.presubmits))
+	for orgrepo := range jobs.presubmits {
+		orgrepos = append(orgrepos, orgrepo)
+	}
+	sort.Strings(orgrepos)
+	for _, orgrepo := range orgrepos {
+		for _, job := range jobs.presubmits[orgrepo] {
+			bases = append(bases, job.JobBase)
+		}
+	}
+
+	orgrepos = orgrepos[:0]
+	for orgrepo := range jobs.postsubmits {
+		orgrepos = append(orgrepos, orgrepo)
+	}
+	sort.Strings(orgrepos)
+	for _, orgrepo := range orgrepos {
+		for _, job := range jobs.postsubmits[orgrepo] {
+			bases = append(bases, job.JobBase)
+		}
+	}
+
+	for _, job := range jobs.periodics {
+		bases = append(bases, job.JobBase)
+	}
+
+	var errs []error
+	for _, job := range bases {
+		if job.Spec == nil {
+			continue
+		}
+		cluster := job.Cluster
+		if cluster == "" {
+			cluster = defaultCluster
+		}
+		if err := v.validate(cluster, representativePod(job)); err != nil {
+			errs = append(errs, fmt.Errorf("job %v rejected by cluster %v: %v", job.Name, cluster, err))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genjobs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+
+	"istio.io/test-infra/prow/genjobs/pkg/git"
+	"istio.io/test-infra/prow/genjobs/pkg/util"
+)
+
+const armoredPrefix = "-----BEGIN "
+
+// readKeyring reads an armored or binary OpenPGP keyring of trusted public keys.
+func readKeyring(p string) (openpgp.EntityList, error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte(armoredPrefix)) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	}
+
+	return openpgp.ReadKeyRing(bytes.NewReader(b))
+}
+
+// checkSignature checks an armored or binary detached signature of the signed content against the keyring.
+func checkSignature(keyring openpgp.EntityList, signed io.Reader, signature []byte) error {
+	var err error
+
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(armoredPrefix)) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, signed, bytes.NewReader(signature))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, signed, bytes.NewReader(signature))
+	}
+
+	return err
+}
+
+// readManifest reads a checksum manifest in the sha256sum format into checksums keyed by path.
+func readManifest(b []byte) map[string]string {
+	checksums := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		checksums[filepath.Clean(strings.TrimPrefix(fields[1], "*"))] = strings.ToLower(fields[0])
+	}
+
+	return checksums
+}
+
+// verifyManifest verifies that every input file is listed with a matching checksum in the manifest.
+func verifyManifest(o options, manifest []byte) error {
+	checksums := readManifest(manifest)
+
+	dir := o.Input
+	if util.IsFile(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	for _, p := range collectInputFiles(o) {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		expected, ok := checksums[rel]
+		if !ok {
+			return fmt.Errorf("input file %v is not listed in the signed manifest", rel)
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(b)
+		if actual := hex.EncodeToString(sum[:]); actual != expected {
+			return fmt.Errorf("input file %v checksum %v does not match the signed manifest", rel, actual)
+		}
+	}
+
+	return nil
+}
+
+// verifyDetachedSignature verifies the detached signature of the input file, or of the signed manifest listing the input files.
+func verifyDetachedSignature(o options, keyring openpgp.EntityList) error {
+	signature, err := ioutil.ReadFile(o.Signature)
+	if err != nil {
+		return err
+	}
+
+	signed := o.Input
+	if o.SignedManifest != "" {
+		signed = o.SignedManifest
+	}
+
+	content, err := ioutil.ReadFile(signed)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSignature(keyring, bytes.NewReader(content), signature); err != nil {
+		return fmt.Errorf("invalid signature of %v: %v", signed, err)
+	}
+
+	if o.SignedManifest != "" {
+		return verifyManifest(o, content)
+	}
+
+	return nil
+}
+
+// verifyCommitSignature verifies the signature of the commit checked out in the input tree,
+// and that the input tree has no uncommitted changes.
+func verifyCommitSignature(o options, keyring openpgp.EntityList) error {
+	dir := o.Input
+	if util.IsFile(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	repo, err := git.Open(dir)
+	if err != nil {
+		return fmt.Errorf("input is not in a git repository: %v", err)
+	}
+
+	if changed, err := repo.HasChanges(o.Input); err != nil {
+		return err
+	} else if changed {
+		return fmt.Errorf("input has uncommitted changes")
+	}
+
+	payload, signature, err := repo.CommitSignature("HEAD")
+	if err != nil {
+		return err
+	}
+	if signature == "" {
+		return fmt.Errorf("input commit is not signed")
+	}
+
+	if err := checkSignature(keyring, strings.NewReader(payload), []byte(signature)); err != nil {
+		return fmt.Errorf("invalid signature of input commit: %v", err)
+	}
+
+	return nil
+}
+
+// verifyInput verifies the detached or commit signature of the input based on provided inputs.
+func verifyInput(o options) error {
+	if o.Signature == "" && !o.VerifyCommit {
+		return nil
+	}
+
+	keyring, err := readKeyring(o.TrustedKeys)
+	if err != nil {
+		return fmt.Errorf("unable to read trusted keys %v: %v", o.TrustedKeys, err)
+	}
+
+	if o.Signature != "" {
+		if err := verifyDetachedSignature(o, keyring); err != nil {
+			return err
+		}
+	}
+
+	if o.VerifyCommit {
+		if err := verifyCommitSignature(o, keyring); err != nil {
+			return err
+		}
+	}
+
+	if o.Verbose {
+		fmt.Printf("verified signature of input %v\n", o.Input)
+	}
+
+	return nil
+}
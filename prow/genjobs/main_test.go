@@ -18,22 +18,39 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"syscall"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/spf13/pflag"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"istio.io/test-infra/prow/genjobs/cmd/genjobs"
+	"istio.io/test-infra/prow/genjobs/pkg/api"
+	"istio.io/test-infra/prow/genjobs/pkg/git"
 )
 
 const (
 	testDir = "testdata"
+
+	benchRepos       = 100
+	benchJobsPerRepo = 100
 )
 
 func resolvePath(t *testing.T, filename string) string {
@@ -73,12 +90,41 @@ func parseConfigTmpl(input, output, config, dir string) (string, error) {
 	return cfgO, nil
 }
 
+// compareGolden compares an actual output file with its expected golden file.
+func compareGolden(t *testing.T, actualPath, expectedPath string) {
+	actual, err := ioutil.ReadFile(actualPath)
+	if err != nil {
+		t.Fatalf("failed reading actual output file %v: %v", actualPath, err)
+	}
+
+	if os.Getenv("REFRESH_GOLDEN") == "true" {
+		if err = ioutil.WriteFile(expectedPath, actual, 0644); err != nil {
+			t.Fatalf("failed writing expected output file %v: %v", expectedPath, err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(expectedPath)
+	if err != nil {
+		t.Fatalf("failed reading expected output file %v: %v", expectedPath, err)
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error("TestGenjobs (-want, +got):", diff)
+	}
+}
+
 func TestGenjobs(t *testing.T) {
 	tests := []struct {
-		name    string
-		output  string
-		args    []string
-		configs bool
+		name             string
+		output           string
+		args             []string
+		configs          bool
+		tide             bool
+		branchProtection bool
+		contexts         bool
+		alerts           bool
+		secrets          bool
+		manifest         bool
 	}{
 		{
 			name: "simple transform",
@@ -88,6 +134,43 @@ func TestGenjobs(t *testing.T) {
 			name: "branches-out",
 			args: []string{"--mapping=istio=istio-private", "--branches-out=custom-1,^custom-2$"},
 		},
+		{
+			name: "repo mapping",
+			args: []string{"--mapping=istio=istio-private,istio/proxy=istio-private/envoy,istio/api=istio-api/api-private"},
+		},
+		{
+			name: "regex mapping",
+			args: []string{"--mapping=~istio.*=private-$0,~envoyproxy/(.*)=private-envoy/${1}-fork"},
+		},
+		{
+			// Literal repo names containing regex metacharacters are not mistaken for regex mappings.
+			name: "dotted repo mapping",
+			args: []string{"--mapping=istio=istio-private,envoyproxy=istio-private,!envoyproxy/envoy.wasm,istio/istio.io=istio-private/docs", "--cluster-map=istio/istio.io=docs", "--service-account-map=istio/istio.io=docs-sa"},
+		},
+		{
+			name: "dotted repo reverse",
+			args: []string{"--mapping=istio/istio.io=istio-private/istio.io", "--reverse"},
+		},
+		{
+			name: "options file",
+			args: []string{"--config=testdata/options_file/options_file_options.yaml", "--modifier=custom"},
+		},
+		{
+			name: "git host",
+			args: []string{"--mapping=istio=istio-private,istio/proxy=ghe.corp.com/istio-private/envoy"},
+		},
+		{
+			name: "git host ssh",
+			args: []string{"--mapping=istio=istio-private,istio/proxy=gitlab.corp.com/istio-private/envoy", "--git-host=ghe.corp.com", "--ssh-clone"},
+		},
+		{
+			name: "reverse",
+			args: []string{"--mapping=istio=istio-private,istio/proxy=gitlab.corp.com/istio-private/envoy", "--reverse"},
+		},
+		{
+			name: "modifier map",
+			args: []string{"--mapping=istio=istio-private,istio-ecosystem=istio-ecosystem-private,envoyproxy=envoy-private", "--modifier-map=istio-ecosystem=internal,envoyproxy=fork"},
+		},
 		{
 			name: "refs exists",
 			args: []string{"--mapping=istio=istio-private", "--refs"},
@@ -104,6 +187,10 @@ func TestGenjobs(t *testing.T) {
 			name: "rerun-users",
 			args: []string{"--mapping=istio=istio-private", "--rerun-users=clarketm,scoobydoo"},
 		},
+		{
+			name: "rerun-teams",
+			args: []string{"--mapping=istio=istio-private", "--rerun-teams=istio-private/oncall", "--rerun-team-ids=42"},
+		},
 		{
 			name: "override annotations",
 			args: []string{"--mapping=istio=istio-private", "--annotations=testgrid-create-test-group=false"},
@@ -124,6 +211,306 @@ func TestGenjobs(t *testing.T) {
 			name: "volume denylist",
 			args: []string{"--mapping=istio=istio-private", "--volume-denylist=bad-volume"},
 		},
+		{
+			name: "emit presets",
+			args: []string{"--mapping=istio=istio-private", "--emit-presets", "--env-denylist=bad-env", "--volume-denylist=bad-volume", "--env=override-env=private"},
+		},
+		{
+			name: "slack deck url",
+			args: []string{"--mapping=istio=istio-private", "--channel=istio-private-oncall", "--slack-job-states=failure,error", "--deck-url=https://prow-private.istio.io/"},
+		},
+		{
+			name: "slack reporter",
+			args: []string{"--mapping=istio=istio-private", "--channel=istio-private-oncall", "--slack-job-states=failure,error", "--slack-report-template=Job {{.Spec.Job}} ended with state {{.Status.State}}."},
+		},
+		{
+			name: "no reporter",
+			args: []string{"--mapping=istio=istio-private", "--no-reporter"},
+		},
+		{
+			name: "bot token secrets",
+			args: []string{"--mapping=istio=istio-private", "--bot-token-secrets=oauth-token=private-oauth-token,hmac-token=private-hmac-token"},
+		},
+		{
+			name: "active deadline",
+			args: []string{"--mapping=istio=istio-private", "--active-deadline=^example_periodic$=3h,presubmit=90m"},
+		},
+		{
+			name: "cache volume",
+			args: []string{"--mapping=istio=istio-private", "--cache-volume=emptyDir:10Gi", "--cache-jobs=presubmit", "--cache-env=GOCACHE=go-build,GOMODCACHE=go-mod"},
+		},
+		{
+			name: "remote cache",
+			args: []string{"--mapping=istio=istio-private", "--remote-cache=grpcs://cache.example.com:443", "--remote-cache-secret=remote-cache-sa:key.json"},
+		},
+		{
+			name: "migrate bootstrap",
+			args: []string{"--mapping=istio=istio-private", "--migrate-bootstrap"},
+		},
+		{
+			name:   "alert rules",
+			args:   []string{"--mapping=istio=istio-private", "--alert-stale-window=48h", "--alert-labels=severity=warning,team=istio"},
+			alerts: true,
+		},
+		{
+			name: "alert annotations",
+			args: []string{"--mapping=istio=istio-private", "--alert-severity=critical", "--alert-email=oncall@istio.io", "--num-failures-to-alert=3", "--alert-stale-results-hours=24"},
+		},
+		{
+			name:    "tenants",
+			configs: true,
+		},
+		{
+			name:    "fanout",
+			configs: true,
+		},
+		{
+			name: "branch map",
+			args: []string{"--mapping=istio=istio-private", "--branch-map=master=main,release-1.4=release-1.4-private"},
+		},
+		{
+			name: "cluster map",
+			args: []string{"--mapping=istio=istio-private,istio-ecosystem=istio-ecosystem-private", "--cluster=private", "--cluster-map=istio/release-builder=isolated,istio-ecosystem=ecosystem"},
+		},
+		{
+			name: "bucket map",
+			args: []string{"--mapping=istio=istio-private,istio-ecosystem=istio-ecosystem-private", "--bucket=private-logs", "--bucket-map=istio-private/release-builder=release-logs,istio-ecosystem-private=ecosystem-logs"},
+		},
+		{
+			name: "path alias",
+			args: []string{"--mapping=istio=istio-private", "--path-alias-map=istio.io/api=istio.io/api-private", "--path-alias-mode=repo"},
+		},
+		{
+			name: "mapping exclusion",
+			args: []string{"--mapping=istio=istio-private,!istio/community"},
+		},
+		{
+			name: "strict mapping",
+			args: []string{"--mapping=istio=istio-private,!istio/community", "--strict-mapping"},
+		},
+		{
+			name: "strict",
+			args: []string{"--mapping=istio=istio-private", "--strict"},
+		},
+		{
+			name: "copy unmapped",
+			args: []string{"--mapping=istio=istio-private", "--copy-unmapped"},
+		},
+		{
+			name: "resources",
+			args: []string{"--mapping=istio=istio-private", "--resources=requests.cpu=2,limits.memory=8Gi"},
+		},
+		{
+			name: "resources if unset",
+			args: []string{"--mapping=istio=istio-private", "--resources=requests.cpu=2,limits.memory=8Gi", "--resources-if-unset"},
+		},
+		{
+			name: "tolerations",
+			args: []string{"--mapping=istio=istio-private", "--tolerations=dedicated=build:NoSchedule,preemptible:NoExecute", "--tolerations-file=testdata/tolerations/tolerations_file.yaml"},
+		},
+		{
+			name: "max concurrency",
+			args: []string{"--mapping=istio=istio-private", "--concurrency-scale=0.5", "--max-concurrency=4"},
+		},
+		{
+			name: "service account",
+			args: []string{"--mapping=istio=istio-private,istio-ecosystem=istio-ecosystem-private", "--service-account=private-sa", "--cluster-map=istio/release-builder=isolated", "--cluster-service-accounts=isolated=isolated-sa", "--service-account-map=istio-ecosystem=ecosystem-sa"},
+		},
+		{
+			name: "priority class",
+			args: []string{"--mapping=istio=istio-private", "--priority-class=release-critical", "--priority-class-jobs=^e2e-.*"},
+		},
+		{
+			name: "pod annotations",
+			args: []string{"--mapping=istio=istio-private", "--pod-annotations=sidecar.istio.io/inject=false,cluster-autoscaler.kubernetes.io/safe-to-evict=false"},
+		},
+		{
+			name: "security context",
+			args: []string{"--mapping=istio=istio-private", "--security-context=runAsUser=1000,runAsNonRoot=true,privileged=false"},
+		},
+		{
+			name: "volumes file",
+			args: []string{"--mapping=istio=istio-private", "--volumes-file=testdata/volumes_file/volumes_file_volumes.yaml"},
+		},
+		{
+			name: "env removal",
+			args: []string{"--mapping=istio=istio-private", "--env=GOPROXY-,GOFLAGS=-mod=vendor"},
+		},
+		{
+			name: "remove labels",
+			args: []string{"--mapping=istio=istio-private", "--remove-labels=preset-service-account,preset-enable-.*"},
+		},
+		{
+			name: "secret map",
+			args: []string{"--mapping=istio=istio-private", "--secret-map=gcp-secret=private-gcp-secret,docker-config=private-docker-config,ssh-key-secret=private-ssh-key-secret"},
+		},
+		{
+			name: "runtime class",
+			args: []string{"--mapping=istio=istio-private", "--runtime-class=gvisor", "--runtime-class-selector=untrusted=true"},
+		},
+		{
+			name: "termination grace period",
+			args: []string{"--mapping=istio=istio-private", "--termination-grace-period=15m"},
+		},
+		{
+			name: "resource scale",
+			args: []string{"--mapping=istio=istio-private", "--resource-scale=0.5"},
+		},
+		{
+			name: "utility images",
+			args: []string{"--mapping=istio=istio-private", "--utility-images=clonerefs=gcr.io/istio-private/clonerefs:v20200101-abcdef,entrypoint=gcr.io/istio-private/entrypoint:v20200101-abcdef"},
+		},
+		{
+			name: "oauth token secret",
+			args: []string{"--mapping=istio=istio-private", "--oauth-token-secret=github-app-token:oauth"},
+		},
+		{
+			name: "clone options",
+			args: []string{"--mapping=istio=istio-private", "--clone-depth=1", "--skip-submodules"},
+		},
+		{
+			name: "censor secrets",
+			args: []string{"--mapping=istio=istio-private", "--censor-secrets"},
+		},
+		{
+			name: "skip cloning",
+			args: []string{"--mapping=istio=istio-private", "--skip-cloning"},
+		},
+		{
+			name: "github app",
+			args: []string{"--mapping=istio=istio-private", "--github-app-id=123456", "--github-app-private-key-secret=github-app:private-key"},
+		},
+		{
+			name: "default service account",
+			args: []string{"--mapping=istio=istio-private", "--bucket=istio-private-build", "--default-service-account-name=private-uploader"},
+		},
+		{
+			name: "gcs credentials secret",
+			args: []string{"--mapping=istio=istio-private", "--bucket=istio-private-build", "--gcs-credentials-secret=private-service-account"},
+		},
+		{
+			name: "ssh host fingerprints",
+			args: []string{"--mapping=istio=istio-private", "--ssh-clone", "--ssh-key-secret=ssh-key-secret", "--ssh-host-fingerprints=github.com ssh-rsa AAAAB3NzaC1yc2EAAAABIwAAAQEAq2A7hRGmdnm9tUDbO9IDSwBK6TbQa+PXYPCPy6rbTrTtw7PHkccKrpp0yVhp5HdEIcKr6pLlVDBfOLX9QUsyCOV0wzfjIJNlGEYsdlLJizHhbn2mUjvSAHQqZETYP81eFzLQNnPHt4EVVUh7VfDESU84KezmD5QlWpXLmvU31/yMf+Se8xhHTvKSCZIFImWwoG6mbUoWf9nzpIoaSjB+weqqUUmpaaasXVal72J+UX2B+2RPW3RcT0eOzQgqlJL3RKrTJvdsjE3JEAvGq3lGHSZXy28G3skua2SmVi/w4yCE6gbODqnTWlg7+wC604ydGXA8VJiS5ap43JXiUFFAaQ=="},
+		},
+		{
+			name: "force decorate",
+			args: []string{"--mapping=istio=istio-private", "--force-decorate"},
+		},
+		{
+			name: "channel map",
+			args: []string{"--mapping=istio=istio-private,istio-ecosystem=istio-ecosystem-private", "--channel=istio-private-ci", "--channel-map=istio/release-builder=release-private-ci,istio-ecosystem=ecosystem-private-ci"},
+		},
+		{
+			name: "pubsub",
+			args: []string{"--mapping=istio=istio-private", "--pubsub-project=istio-private-testing", "--pubsub-topic=prow-results"},
+		},
+		{
+			name: "skip report",
+			args: []string{"--mapping=istio=istio-private", "--skip-report"},
+		},
+		{
+			name: "rewrite triggers",
+			args: []string{"--mapping=istio=istio-private", "--rewrite-triggers"},
+		},
+		{
+			name: "run policy",
+			args: []string{"--mapping=istio=istio-private", "--optional", "--always-run=false"},
+		},
+		{
+			name: "run if changed prefix",
+			args: []string{"--mapping=istio=istio-private", "--run-if-changed-prefix=third_party/istio/"},
+		},
+		{
+			name: "cron jitter",
+			args: []string{"--mapping=istio=istio-private", "--cron-jitter=30"},
+		},
+		{
+			name: "interval scale",
+			args: []string{"--mapping=istio=istio-private", "--interval-scale=2"},
+		},
+		{
+			name: "interval to cron",
+			args: []string{"--mapping=istio=istio-private", "--interval-to-cron", "--cron-jitter=10"},
+		},
+		{
+			name: "postsubmit periodics",
+			args: []string{"--mapping=istio=istio-private", "--postsubmit-periodics=^build", "--postsubmit-periodic-cron=0 6 * * *"},
+		},
+		{
+			name: "base ref map",
+			args: []string{"--mapping=istio=istio-private,istio-ecosystem=istio-ecosystem-private", "--base-ref-map=istio-private=private-main,istio-private/proxy=private-proxy"},
+		},
+		{
+			name: "annotation selector",
+			args: []string{"--mapping=istio=istio-private", "--annotation-selector=private.istio.io/generate!=false"},
+		},
+		{
+			name: "skip optional",
+			args: []string{"--mapping=istio=istio-private", "--skip-optional"},
+		},
+		{
+			name: "branches",
+			args: []string{"--mapping=istio=istio-private", "--branches=master"},
+		},
+		{
+			name: "skip branches",
+			args: []string{"--mapping=istio=istio-private", "--skip-branches=master"},
+		},
+		{
+			name: "multiple inputs",
+			args: []string{"--mapping=istio=istio-private,istio-ecosystem=istio-ecosystem-private", "--input=testdata/multiple_inputs/multiple_inputs_release_in.yaml,testdata/multiple_inputs/multiple_inputs_ecosystem_in.yaml"},
+		},
+		{
+			name: "input glob",
+			args: []string{"--mapping=istio=istio-private", "--input=testdata/input_glob/jobs/**/*.gen.yaml", "--exclude=**/experimental/**"},
+		},
+		{
+			name: "header file",
+			args: []string{"--mapping=istio=istio-private", "--header-file=testdata/header_file/header_file_header.txt"},
+		},
+		{
+			name: "provenance",
+			args: []string{"--mapping=istio=istio-private", "--provenance", "--source-sha=0123456789abcdef0123456789abcdef01234567"},
+		},
+		{
+			name:    "secrets output",
+			args:    []string{"--mapping=istio=istio-private"},
+			secrets: true,
+		},
+		{
+			name:    "external secrets",
+			args:    []string{"--mapping=istio=istio-private", "--secrets-kind=ExternalSecret", "--secrets-namespace=private-test-pods"},
+			secrets: true,
+		},
+		{
+			name: "decoration resources",
+			args: []string{"--mapping=istio=istio-private", "--decoration-resources=sidecar.requests.memory=100Mi,sidecar.limits.memory=1Gi,clonerefs.limits.cpu=500m"},
+		},
+		{
+			name: "tide config",
+			args: []string{"--mapping=istio=istio-private", "--tide-merge-method=squash"},
+			tide: true,
+		},
+		{
+			name: "tide labels",
+			args: []string{"--mapping=istio=istio-private", "--tide-labels=lgtm,approved,ok-to-merge", "--tide-missing-labels=do-not-merge/hold,needs-rebase"},
+			tide: true,
+		},
+		{
+			name:             "branch protection config",
+			args:             []string{"--mapping=istio=istio-private"},
+			branchProtection: true,
+		},
+		{
+			name:     "contexts output",
+			args:     []string{"--mapping=istio=istio-private"},
+			contexts: true,
+		},
+		{
+			name:     "manifest",
+			args:     []string{"--mapping=istio=istio-private", "--source-sha=0123456789abcdef0123456789abcdef01234567"},
+			manifest: true,
+		},
 		{
 			name:    "config file",
 			configs: true,
@@ -159,6 +546,30 @@ func TestGenjobs(t *testing.T) {
 			} else {
 				os.Args = append(os.Args, "--input="+in, "--output="+outA)
 			}
+			tideA := filepath.Join(tmpDir, "tide.yaml")
+			if test.tide {
+				os.Args = append(os.Args, "--tide-config="+tideA)
+			}
+			branchProtectionA := filepath.Join(tmpDir, "branch-protection.yaml")
+			if test.branchProtection {
+				os.Args = append(os.Args, "--branch-protection-config="+branchProtectionA)
+			}
+			contextsA := filepath.Join(tmpDir, "contexts.json")
+			if test.contexts {
+				os.Args = append(os.Args, "--contexts-output="+contextsA)
+			}
+			alertsA := filepath.Join(tmpDir, "alerts.yaml")
+			if test.alerts {
+				os.Args = append(os.Args, "--alert-rules="+alertsA)
+			}
+			secretsA := filepath.Join(tmpDir, "secrets.yaml")
+			if test.secrets {
+				os.Args = append(os.Args, "--secrets-output="+secretsA)
+			}
+			manifestA := filepath.Join(tmpDir, "manifest.json")
+			if test.manifest {
+				os.Args = append(os.Args, "--manifest="+manifestA)
+			}
 			genjobs.Main()
 
 			actual, err := ioutil.ReadFile(outA)
@@ -176,6 +587,909 @@ func TestGenjobs(t *testing.T) {
 			if diff := cmp.Diff(expected, actual); diff != "" {
 				t.Error("TestGenjobs (-want, +got):", diff)
 			}
+
+			if test.tide {
+				compareGolden(t, tideA, resolvePath(t, "_tide.yaml"))
+			}
+			if test.branchProtection {
+				compareGolden(t, branchProtectionA, resolvePath(t, "_branch_protection.yaml"))
+			}
+			if test.contexts {
+				compareGolden(t, contextsA, resolvePath(t, "_contexts.json"))
+			}
+			if test.alerts {
+				compareGolden(t, alertsA, resolvePath(t, "_alerts.yaml"))
+			}
+			if test.secrets {
+				compareGolden(t, secretsA, resolvePath(t, "_secrets.yaml"))
+			}
+			if test.manifest {
+				compareGolden(t, manifestA, resolvePath(t, "_manifest.json"))
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "history",
+			args: []string{"history"},
+		},
+		{
+			name: "blame",
+			args: []string{"blame", "example_presubmit_private"},
+		},
+	}
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed creating temp file: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	in, out, db := filepath.Join(tmpDir, "in.yaml"), filepath.Join(tmpDir, "out.yaml"), filepath.Join(tmpDir, "history.db")
+
+	d, err := ioutil.ReadFile(filepath.Join(testDir, "history", "history_in.yaml"))
+	if err != nil {
+		t.Fatalf("failed reading input file: %v", err)
+	}
+
+	// Record a run adding the jobs, a run changing them, and a run removing the postsubmit.
+	changed := bytes.Replace(d, []byte("privileged: true"), []byte("privileged: false"), -1)
+	runs := []struct {
+		sha   string
+		input []byte
+	}{
+		{sha: "1111111111111111111111111111111111111111", input: d},
+		{sha: "2222222222222222222222222222222222222222", input: changed},
+		{sha: "3333333333333333333333333333333333333333", input: changed[bytes.Index(changed, []byte("presubmits:")):]},
+	}
+	for _, run := range runs {
+		if err := ioutil.WriteFile(in, run.input, 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Args = []string{"genjobs"}
+		pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+		os.Args = append(os.Args, "--mapping=istio=istio-private", "--clean", "--source-sha="+run.sha, "--history-db="+db, "--input="+in, "--output="+out)
+		genjobs.Main()
+	}
+
+	// An entry that cannot be decoded is skipped rather than failing the whole database.
+	boltDB, err := bolt.Open(db, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := boltDB.Update(func(tx *bolt.Tx) error {
+		key := []byte{0, 0, 0, 0, 0, 0, 0, 4}
+		if err := tx.Bucket([]byte("runs")).Put(key, []byte("{")); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("jobs")).Put(append([]byte("example_presubmit_private\x00"), key...), nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := boltDB.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`)
+
+	// The recorded times and input paths differ between runs of the test.
+	normalize := func(s string) string {
+		return strings.Replace(timestamp.ReplaceAllString(s, "TIME"), tmpDir, "TMP", -1)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkMainProcess(t, append([]string{test.args[0], "--history-db=" + db}, test.args[1:]...), 0, normalize)
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "migrate",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+			outE := resolvePath(t, "_out.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+			outA := filepath.Join(tmpDir, "out.yaml")
+
+			d, err := ioutil.ReadFile(in)
+			if err != nil {
+				t.Fatalf("failed reading input file %v: %v", in, err)
+			}
+			if err := ioutil.WriteFile(outA, d, 0644); err != nil {
+				t.Fatalf("failed writing output file %v: %v", outA, err)
+			}
+
+			os.Args = []string{"genjobs", "migrate"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--output="+tmpDir)
+			genjobs.Main()
+
+			compareGolden(t, outA, outE)
+		})
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "plugins",
+			args: []string{"--mapping=istio=istio-private,istio/proxy=istio-envoy/envoy,!istio/excluded"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+			outE := resolvePath(t, "_out.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+			outA := filepath.Join(tmpDir, "plugins.yaml")
+
+			os.Args = []string{"genjobs", "plugins"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--plugins-config="+in, "--plugins-output="+outA, "--output="+tmpDir)
+			genjobs.Main()
+
+			compareGolden(t, outA, outE)
+		})
+	}
+}
+
+func TestStdio(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "stdio",
+			args: []string{"--mapping=istio=istio-private"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+			outE := resolvePath(t, "_out.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+			outA := filepath.Join(tmpDir, "out.yaml")
+
+			stdin, err := os.Open(in)
+			if err != nil {
+				t.Fatalf("failed opening input file %v: %v", in, err)
+			}
+			defer stdin.Close()
+
+			stdout, err := os.Create(outA)
+			if err != nil {
+				t.Fatalf("failed creating output file %v: %v", outA, err)
+			}
+			defer stdout.Close()
+
+			origStdin, origStdout := os.Stdin, os.Stdout
+			defer func() { os.Stdin, os.Stdout = origStdin, origStdout }()
+			os.Stdin, os.Stdout = stdin, stdout
+
+			os.Args = []string{"genjobs"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--input=-", "--output=-")
+			genjobs.Main()
+
+			compareGolden(t, outA, outE)
+		})
+	}
+}
+
+func TestInputRepo(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "input repo",
+			args: []string{"--mapping=istio=istio-private"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+			outE := resolvePath(t, "_out.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+			outA := filepath.Join(tmpDir, "out.yaml")
+
+			// Commit the input to a repo and remove it afterwards, so only the pinned commit has the job(s).
+			remote := filepath.Join(tmpDir, "remote")
+			if err := os.MkdirAll(filepath.Join(remote, "jobs"), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			r := &git.Repo{Dir: remote}
+			if _, err := r.Run("init", "--quiet"); err != nil {
+				t.Fatal(err)
+			}
+			d, err := ioutil.ReadFile(in)
+			if err != nil {
+				t.Fatalf("failed reading input file %v: %v", in, err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(remote, "jobs", "istio.istio.master.yaml"), d, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.CommitAll("master", "test", "test@istio.io", "initial"); err != nil {
+				t.Fatal(err)
+			}
+			sha, err := r.HeadSHA()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.RemoveAll(filepath.Join(remote, "jobs")); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.CommitAll("master", "test", "test@istio.io", "remove"); err != nil {
+				t.Fatal(err)
+			}
+
+			os.Args = []string{"genjobs"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--input-repo="+remote, "--input-ref="+sha, "--input=jobs", "--output="+outA)
+			genjobs.Main()
+
+			compareGolden(t, outA, outE)
+		})
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed creating temp file: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	outA := filepath.Join(tmpDir, "out.yaml")
+
+	remote := filepath.Join(tmpDir, "remote")
+	if err := os.MkdirAll(filepath.Join(remote, "jobs"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	r := &git.Repo{Dir: remote}
+	if _, err := r.Run("init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ioutil.ReadFile(filepath.Join(testDir, "input_repo", "input_repo_in.yaml"))
+	if err != nil {
+		t.Fatalf("failed reading input file: %v", err)
+	}
+	commit := func(d []byte, message string) {
+		if err := ioutil.WriteFile(filepath.Join(remote, "jobs", "istio.istio.master.yaml"), d, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CommitAll("master", "test", "test@istio.io", message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(name string) {
+		for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if out, err := ioutil.ReadFile(outA); err == nil && strings.Contains(string(out), "name: "+name+"\n") {
+				return
+			}
+		}
+		t.Fatalf("timed out waiting for job %v in output %v", name, outA)
+	}
+	commit(d, "initial")
+
+	os.Args = []string{"genjobs"}
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = append(os.Args, "--mapping=istio=istio-private", "--interval=100ms", "--health-port=0",
+		"--input-repo="+remote, "--input-ref=master", "--input=jobs", "--output="+outA)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		genjobs.Main()
+	}()
+
+	// Every generation checks out the input repo again, so a later commit is picked up by the next run.
+	waitFor("example_presubmit_private")
+	commit(bytes.Replace(d, []byte("name: example_presubmit"), []byte("name: updated_presubmit"), 1), "update")
+	waitFor("updated_presubmit_private")
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for the scheduled generation to shut down")
+	}
+}
+
+func TestIntervalInvalidConfig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed creating temp file: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	outA := filepath.Join(tmpDir, "out.yaml")
+	empty := filepath.Join(tmpDir, "empty")
+	cfgDir := filepath.Join(tmpDir, "configs")
+	for _, dir := range []string{empty, cfgDir} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig := func(maxConcurrency int) {
+		cfg := fmt.Sprintf("transforms:\n- mapping:\n    istio: istio-private\n  input: %v\n  output: %v\n  max-concurrency: %d\n",
+			filepath.Join(testDir, "input_repo", "input_repo_in.yaml"), outA, maxConcurrency)
+		if err := ioutil.WriteFile(filepath.Join(cfgDir, "config.yaml"), []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(-1)
+
+	os.Args = []string{"genjobs"}
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = append(os.Args, "--configs="+cfgDir, "--interval=100ms", "--health-port=0",
+		"--input="+empty, "--output="+empty)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		genjobs.Main()
+	}()
+
+	// An invalid transform fails the generation but keeps running, so a fixed configuration is picked up by the next run.
+	time.Sleep(500 * time.Millisecond)
+	if _, err := os.Stat(outA); !os.IsNotExist(err) {
+		t.Fatalf("expected no output for the invalid configuration, got: %v", err)
+	}
+	writeConfig(1)
+	for deadline := time.Now().Add(30 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if _, err := os.Stat(outA); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for output %v", outA)
+		}
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for the scheduled generation to shut down")
+	}
+}
+
+func TestServe(t *testing.T) {
+	valid, err := ioutil.ReadFile(filepath.Join(testDir, "input_repo", "input_repo_in.yaml"))
+	if err != nil {
+		t.Fatalf("failed reading input file: %v", err)
+	}
+	invalid := []byte("presubmits: [")
+
+	tests := []struct {
+		name   string
+		verify bool
+		config string
+		inputs map[string][]byte
+		code   codes.Code
+	}{
+		{
+			name:   "generate",
+			inputs: map[string][]byte{"istio/istio/istio.istio.master.yaml": valid},
+			code:   codes.OK,
+		},
+		{
+			name:   "verify",
+			verify: true,
+			inputs: map[string][]byte{"istio/istio/istio.istio.master.yaml": valid},
+			code:   codes.OK,
+		},
+		{
+			name:   "invalid input file",
+			inputs: map[string][]byte{"istio/istio/istio.istio.master.yaml": valid, "istio/proxy/istio.proxy.master.yaml": invalid},
+			code:   codes.Internal,
+		},
+		{
+			name:   "verify invalid input file",
+			verify: true,
+			inputs: map[string][]byte{"istio/istio/istio.istio.master.yaml": valid, "istio/proxy/istio.proxy.master.yaml": invalid},
+			code:   codes.Internal,
+		},
+		{
+			name:   "fail fast",
+			config: "fail-fast: true",
+			inputs: map[string][]byte{"istio/proxy/istio.proxy.master.yaml": invalid},
+			code:   codes.Internal,
+		},
+		{
+			name:   "strict",
+			config: "strict: true",
+			inputs: map[string][]byte{"istio/proxy/istio.proxy.master.yaml": invalid},
+			code:   codes.Internal,
+		},
+		{
+			name:   "strict mapping",
+			config: "strict-mapping: true\nmapping:\n  kubernetes: kubernetes-private",
+			inputs: map[string][]byte{"istio/istio/istio.istio.master.yaml": valid},
+			code:   codes.Internal,
+		},
+		{
+			name:   "invalid option",
+			config: "git-host: github",
+			inputs: map[string][]byte{"istio/istio/istio.istio.master.yaml": valid},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "server path",
+			config: "output: /tmp",
+			inputs: map[string][]byte{"istio/istio/istio.istio.master.yaml": valid},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "input outside directory",
+			inputs: map[string][]byte{"../istio/istio/istio.istio.master.yaml": valid},
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	os.Args = []string{"genjobs", "serve"}
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = append(os.Args, fmt.Sprintf("--grpc-port=%d", port), "--ui-port=0", "--health-port=0")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		genjobs.Main()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, fmt.Sprintf("localhost:%d", port), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("failed connecting to the gRPC API: %v", err)
+	}
+	defer conn.Close()
+	client := api.NewGenJobsClient(conn)
+
+	// A failed request must not stop the server, so every case runs against the same one.
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transform := &api.Transform{Mapping: map[string]string{"istio": "istio-private"}, Modifier: "private", Config: test.config}
+
+			var files []*api.File
+			for p, content := range test.inputs {
+				files = append(files, &api.File{Path: p, Content: content})
+			}
+
+			var generated []*api.GeneratedFile
+			if test.verify {
+				_, err = client.VerifyJobs(ctx, &api.VerifyJobsRequest{Transform: transform, Inputs: files})
+			} else {
+				var stream api.GenJobs_GenerateJobsClient
+				if stream, err = client.GenerateJobs(ctx, &api.GenerateJobsRequest{Transform: transform, Inputs: files}); err == nil {
+					for {
+						var f *api.GeneratedFile
+						if f, err = stream.Recv(); err != nil {
+							break
+						}
+						generated = append(generated, f)
+					}
+					if err == io.EOF {
+						err = nil
+					}
+				}
+			}
+
+			if code := status.Code(err); code != test.code {
+				t.Fatalf("expected code %v, got %v: %v", test.code, code, err)
+			}
+			if !test.verify && test.code == codes.OK && (len(generated) != 1 || generated[0].GetJobs() != 2) {
+				t.Errorf("expected a single generated file with 2 jobs, got %v", generated)
+			}
+		})
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for the server to shut down")
+	}
+}
+
+func TestURLInput(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "url input",
+			args: []string{"--mapping=istio=istio-private", "--resolve"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+			presets := resolvePath(t, "_presets.yaml")
+			outE := resolvePath(t, "_out.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+			outA := filepath.Join(tmpDir, "out.yaml")
+
+			server := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir(in))))
+			defer server.Close()
+
+			os.Args = []string{"genjobs"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--input="+server.URL+"/"+filepath.Base(in), "--presets="+server.URL+"/"+filepath.Base(presets), "--output="+outA)
+			genjobs.Main()
+
+			compareGolden(t, outA, outE)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		file string
+	}{
+		{
+			name: "json format",
+			args: []string{"--mapping=istio=istio-private", "--format=json"},
+			file: "istio-private/istio/istio-private.istio.master.json",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.json")
+			outE := resolvePath(t, "_out.json")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			inDir := filepath.Join(tmpDir, "in")
+			inA := filepath.Join(inDir, "istio", "istio", "istio.istio.master.json")
+			if err := os.MkdirAll(filepath.Dir(inA), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			d, err := ioutil.ReadFile(in)
+			if err != nil {
+				t.Fatalf("failed reading input file %v: %v", in, err)
+			}
+			if err := ioutil.WriteFile(inA, d, 0644); err != nil {
+				t.Fatal(err)
+			}
+			outDir := filepath.Join(tmpDir, "out")
+
+			os.Args = []string{"genjobs"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--input="+inDir, "--output="+outDir)
+			genjobs.Main()
+
+			compareGolden(t, filepath.Join(outDir, test.file), outE)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		files []string
+	}{
+		{
+			name:  "split by type",
+			args:  []string{"--mapping=istio=istio-private", "--split-by-type"},
+			files: []string{"out.periodics.yaml", "out.postsubmits.yaml", "out.presubmits.yaml"},
+		},
+		{
+			name:  "split by job",
+			args:  []string{"--mapping=istio=istio-private", "--split-by-job"},
+			files: []string{"out/istio_periodic_private.yaml", "out/istio_postsubmit_private.yaml", "out/istio_presubmit_private.yaml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			os.Args = []string{"genjobs"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--input="+in, "--output="+filepath.Join(tmpDir, "out.yaml"))
+			genjobs.Main()
+
+			var files []string
+			if err := filepath.Walk(tmpDir, func(p string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(tmpDir, p)
+				files = append(files, filepath.ToSlash(rel))
+				return err
+			}); err != nil {
+				t.Fatalf("failed walking output directory %v: %v", tmpDir, err)
+			}
+
+			if diff := cmp.Diff(test.files, files); diff != "" {
+				t.Fatalf("output files differ (-want +got):\n%s", diff)
+			}
+
+			for _, file := range test.files {
+				compareGolden(t, filepath.Join(tmpDir, file), resolvePath(t, "_"+file))
+			}
+		})
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		files []string
+	}{
+		{
+			name:  "consolidate",
+			args:  []string{"--mapping=istio=istio-private", "--consolidate"},
+			files: []string{"istio-private/istio/istio-private.istio.private.yaml", "istio-private/proxy/istio-private.proxy.private.yaml"},
+		},
+		{
+			name:  "consolidate copy unmapped",
+			args:  []string{"--mapping=istio=istio-private", "--consolidate", "--copy-unmapped"},
+			files: []string{"envoyproxy/envoy/envoyproxy.envoy.private.yaml", "istio-private/istio/istio-private.istio.private.yaml", "istio-private/proxy/istio-private.proxy.private.yaml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			inDir := filepath.Join(tmpDir, "in")
+			inA := filepath.Join(inDir, "istio", "istio", "istio.istio.master.yaml")
+			if err := os.MkdirAll(filepath.Dir(inA), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			d, err := ioutil.ReadFile(in)
+			if err != nil {
+				t.Fatalf("failed reading input file %v: %v", in, err)
+			}
+			if err := ioutil.WriteFile(inA, d, 0644); err != nil {
+				t.Fatal(err)
+			}
+			outDir := filepath.Join(tmpDir, "out")
+
+			os.Args = []string{"genjobs"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--input="+inDir, "--output="+outDir)
+			genjobs.Main()
+
+			var files []string
+			if err := filepath.Walk(outDir, func(p string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(outDir, p)
+				files = append(files, filepath.ToSlash(rel))
+				return err
+			}); err != nil {
+				t.Fatalf("failed walking output directory %v: %v", outDir, err)
+			}
+
+			if diff := cmp.Diff(test.files, files); diff != "" {
+				t.Fatalf("output files differ (-want +got):\n%s", diff)
+			}
+
+			for _, file := range test.files {
+				compareGolden(t, filepath.Join(outDir, file), resolvePath(t, "_out/"+file))
+			}
+		})
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		file string
+	}{
+		{
+			name: "follow symlinks",
+			args: []string{"--mapping=istio=istio-private", "--follow-symlinks"},
+			file: "istio-private/istio/istio-private.istio.master.yaml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := resolvePath(t, "_in.yaml")
+			outE := resolvePath(t, "_out.yaml")
+
+			tmpDir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed creating temp file: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			// The input repo directory is a symlink to a shared directory that links back to itself.
+			sharedDir := filepath.Join(tmpDir, "shared")
+			if err := os.MkdirAll(sharedDir, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			d, err := ioutil.ReadFile(in)
+			if err != nil {
+				t.Fatalf("failed reading input file %v: %v", in, err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(sharedDir, "istio.istio.master.yaml"), d, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(sharedDir, filepath.Join(sharedDir, "loop")); err != nil {
+				t.Fatal(err)
+			}
+
+			inDir := filepath.Join(tmpDir, "in")
+			if err := os.MkdirAll(filepath.Join(inDir, "istio"), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(sharedDir, filepath.Join(inDir, "istio", "istio")); err != nil {
+				t.Fatal(err)
+			}
+			outDir := filepath.Join(tmpDir, "out")
+
+			os.Args = []string{"genjobs"}
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append(os.Args, test.args...)
+			os.Args = append(os.Args, "--input="+inDir, "--output="+outDir)
+			genjobs.Main()
+
+			compareGolden(t, filepath.Join(outDir, test.file), outE)
+		})
+	}
+}
+
+// writeBenchInput writes a synthetic job config tree with presubmits and postsubmits for each repo.
+func writeBenchInput(b *testing.B, dir string, repos, jobsPerRepo int) {
+	for r := 0; r < repos; r++ {
+		repo := fmt.Sprintf("repo%d", r)
+		orgrepo := "istio/" + repo
+
+		var buf bytes.Buffer
+
+		buf.WriteString("presets:\n")
+		buf.WriteString("- labels:\n    preset-bench: \"true\"\n  env:\n  - name: BENCH\n    value: \"true\"\n")
+
+		for _, jType := range []string{"presubmits", "postsubmits"} {
+			fmt.Fprintf(&buf, "%s:\n  %s:\n", jType, orgrepo)
+			for j := 0; j < jobsPerRepo/2; j++ {
+				fmt.Fprintf(&buf, "  - name: %s_%s_%d\n    branches:\n    - ^master$\n    decorate: true\n", repo, jType, j)
+				buf.WriteString("    labels:\n      preset-bench: \"true\"\n")
+				buf.WriteString("    spec:\n      containers:\n      - image: gcr.io/istio-testing/build-tools:master\n        command:\n        - \"true\"\n")
+			}
+		}
+
+		p := filepath.Join(dir, "istio", repo, "istio."+repo+".gen.yaml")
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			b.Fatalf("failed creating input directory %v: %v", filepath.Dir(p), err)
+		}
+		if err := ioutil.WriteFile(p, buf.Bytes(), 0644); err != nil {
+			b.Fatalf("failed writing input file %v: %v", p, err)
+		}
+	}
+}
+
+func BenchmarkGenjobs(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "simple transform",
+			args: []string{"--mapping=istio=istio-private", "--clean"},
+		},
+		{
+			name: "resolve presets",
+			args: []string{"--mapping=istio=istio-private", "--clean", "--resolve", "--sort=asc"},
+		},
+	}
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		b.Fatalf("failed creating temp file: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	in := filepath.Join(tmpDir, "in")
+	out := filepath.Join(tmpDir, "out")
+
+	writeBenchInput(b, in, benchRepos, benchJobsPerRepo)
+
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				os.Args = []string{"genjobs"}
+				pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+				os.Args = append(os.Args, bench.args...)
+				os.Args = append(os.Args, "--input="+in, "--output="+out)
+				genjobs.Main()
+			}
 		})
 	}
 }
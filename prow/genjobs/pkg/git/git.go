@@ -0,0 +1,212 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// credentialsRe matches the credentials of a remote URL.
+var credentialsRe = regexp.MustCompile(`://[^/@\s]+@`)
+
+// Repo is a local clone of a git repository.
+type Repo struct {
+	Dir string
+}
+
+// Clone clones the branch of a remote repository into dir.
+// If dir already contains a clone, the branch is fetched and the working tree is reset to it.
+func Clone(remote, branch, dir string) (*Repo, error) {
+	r := &Repo{Dir: dir}
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if _, err := run("", "clone", "--branch", branch, remote, dir); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if _, err := r.Run("remote", "set-url", "origin", remote); err != nil {
+		return nil, err
+	}
+	if _, err := r.Run("fetch", "origin", branch); err != nil {
+		return nil, err
+	}
+	if _, err := r.Run("checkout", "--force", "-B", branch, "FETCH_HEAD"); err != nil {
+		return nil, err
+	}
+	if _, err := r.Run("clean", "-fdx"); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Checkout fetches a ref (a branch, tag, or commit SHA) of a remote repository into a new dir and checks it out.
+func Checkout(remote, ref, dir string) (*Repo, error) {
+	r := &Repo{Dir: dir}
+
+	if _, err := run("", "init", "--quiet", dir); err != nil {
+		return nil, err
+	}
+	if _, err := r.Run("fetch", "--quiet", "--depth=1", remote, ref); err != nil {
+		return nil, err
+	}
+	if _, err := r.Run("checkout", "--quiet", "--force", "--detach", "FETCH_HEAD"); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Open opens the repository containing dir.
+func Open(dir string) (*Repo, error) {
+	root, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{Dir: root}, nil
+}
+
+// Run runs a git command in the repository and returns its trimmed output.
+func (r *Repo) Run(args ...string) (string, error) {
+	return run(r.Dir, args...)
+}
+
+// HeadSHA returns the commit SHA of HEAD.
+func (r *Repo) HeadSHA() (string, error) {
+	return r.Run("rev-parse", "HEAD")
+}
+
+// ChangedFiles returns the files changed between two revisions, limited to paths when provided.
+// The returned files are relative to the repository root.
+func (r *Repo) ChangedFiles(from, to string, paths ...string) ([]string, error) {
+	args := append([]string{"diff", "--name-only", from, to, "--"}, paths...)
+
+	out, err := r.Run(args...)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+
+	return strings.Split(out, "\n"), nil
+}
+
+// HasChanges checks if the working tree has uncommitted changes, including untracked files.
+// When paths are given, only changes to those paths are considered.
+func (r *Repo) HasChanges(paths ...string) (bool, error) {
+	out, err := r.Run(append([]string{"status", "--porcelain", "--"}, paths...)...)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// CommitSignature returns the signed payload and the armored signature of a commit.
+// An empty signature is returned for unsigned commits.
+func (r *Repo) CommitSignature(rev string) (string, string, error) {
+	raw, err := output(r.Dir, "cat-file", "commit", rev)
+	if err != nil {
+		return "", "", err
+	}
+
+	payload, signature := splitSignedCommit(raw)
+
+	return payload, signature, nil
+}
+
+// splitSignedCommit splits a raw commit object into the payload covered by its signature and the signature.
+// The signature is stored in the gpgsig header, whose continuation lines are indented by a single space.
+func splitSignedCommit(raw string) (string, string) {
+	var payload, signature strings.Builder
+
+	lines := strings.SplitAfter(raw, "\n")
+	inHeaders, inSig := true, false
+
+	for _, line := range lines {
+		switch {
+		case !inHeaders:
+			payload.WriteString(line)
+		case inSig && strings.HasPrefix(line, " "):
+			signature.WriteString(strings.TrimPrefix(line, " "))
+		case strings.HasPrefix(line, "gpgsig "):
+			inSig = true
+			signature.WriteString(strings.TrimPrefix(line, "gpgsig "))
+		default:
+			inSig = false
+			if line == "\n" {
+				inHeaders = false
+			}
+			payload.WriteString(line)
+		}
+	}
+
+	return payload.String(), signature.String()
+}
+
+// CommitAll commits all changes in the working tree to a new branch.
+func (r *Repo) CommitAll(branch, author, email, message string) error {
+	if _, err := r.Run("checkout", "-B", branch); err != nil {
+		return err
+	}
+	if _, err := r.Run("add", "--all"); err != nil {
+		return err
+	}
+	_, err := r.Run("-c", "user.name="+author, "-c", "user.email="+email, "commit", "--message", message)
+	return err
+}
+
+// ForcePush force pushes a local branch to the origin remote.
+func (r *Repo) ForcePush(branch string) error {
+	_, err := r.Run("push", "--force", "origin", branch)
+	return err
+}
+
+// run runs a git command in a directory.
+func run(dir string, args ...string) (string, error) {
+	out, err := output(dir, args...)
+
+	return strings.TrimSpace(out), err
+}
+
+// output runs a git command in dir and returns its untrimmed output.
+func output(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", args[0], err, redactCredentials(strings.TrimSpace(stderr.String())))
+	}
+
+	return stdout.String(), nil
+}
+
+// redactCredentials removes the credentials of any remote URL from a message.
+func redactCredentials(msg string) string {
+	return credentialsRe.ReplaceAllString(msg, "://")
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+// Client is a GitHub API client for a single repository.
+type Client struct {
+	client *github.Client
+	org    string
+	repo   string
+}
+
+// NewClient creates a GitHub API client for an org/repo authenticated with the token stored in a file.
+// The client uses the API endpoint when set (e.g. of GitHub Enterprise) instead of the public GitHub API.
+func NewClient(tokenPath, endpoint, org, repo string) (*Client, error) {
+	token, err := ReadToken(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	client := github.NewClient(oauth2.NewClient(context.Background(), ts))
+
+	if endpoint != "" {
+		// The base URL must have a trailing slash for the API paths to be resolved against it.
+		if client.BaseURL, err = url.Parse(strings.TrimSuffix(endpoint, "/") + "/"); err != nil {
+			return nil, fmt.Errorf("invalid GitHub endpoint %v: %v", endpoint, err)
+		}
+	}
+
+	return &Client{
+		client: client,
+		org:    org,
+		repo:   repo,
+	}, nil
+}
+
+// ReadToken reads a GitHub token from a file.
+func ReadToken(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read GitHub token %v: %v", path, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// EnsurePullRequest opens a pull request from head into base, unless one is already open.
+// The number of the new or existing pull request is returned.
+func (c *Client) EnsurePullRequest(head, base, title, body string) (int, error) {
+	ctx := context.Background()
+
+	prs, _, err := c.client.PullRequests.List(ctx, c.org, c.repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  c.org + ":" + head,
+		Base:  base,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to list pull requests for %s/%s: %v", c.org, c.repo, err)
+	}
+	if len(prs) > 0 {
+		return prs[0].GetNumber(), nil
+	}
+
+	pr, _, err := c.client.PullRequests.Create(ctx, c.org, c.repo, &github.NewPullRequest{
+		Title: &title,
+		Head:  &head,
+		Base:  &base,
+		Body:  &body,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to create pull request for %s/%s: %v", c.org, c.repo, err)
+	}
+
+	return pr.GetNumber(), nil
+}
+
+// RepositoryExists returns whether the repository exists and is accessible with the token.
+func (c *Client) RepositoryExists() (bool, error) {
+	_, resp, err := c.client.Repositories.Get(context.Background(), c.org, c.repo)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to get repository %s/%s: %v", c.org, c.repo, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRegistry = "registry-1.docker.io"
+	defaultTimeout  = 30 * time.Second
+	// tokenUsername is the username used with access tokens by registries such as GCR.
+	tokenUsername = "oauth2accesstoken"
+	manifestTypes = "application/vnd.docker.distribution.manifest.v2+json, " +
+		"application/vnd.docker.distribution.manifest.list.v2+json, " +
+		"application/vnd.oci.image.manifest.v1+json, " +
+		"application/vnd.oci.image.index.v1+json"
+)
+
+// challengeParamRe matches the parameters of a WWW-Authenticate challenge.
+var challengeParamRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Reference is a parsed container image reference.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses a container image reference (e.g. gcr.io/istio-testing/build-tools:master).
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+
+	if name == "" {
+		return Reference{}, fmt.Errorf("invalid image reference: %q", s)
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry, ref.Repository = parts[0], parts[1]
+	} else {
+		ref.Registry, ref.Repository = defaultRegistry, name
+		if !strings.Contains(name, "/") {
+			ref.Repository = "library/" + name
+		}
+	}
+
+	return ref, nil
+}
+
+// Name returns the reference without its tag or digest.
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// Client is a Docker Registry HTTP API V2 client.
+type Client struct {
+	client *http.Client
+	scheme string
+	token  string
+}
+
+// NewClient creates a registry client, authenticated with the access token stored in a file when provided.
+func NewClient(tokenPath string) (*Client, error) {
+	c := &Client{
+		client: &http.Client{Timeout: defaultTimeout},
+		scheme: "https",
+	}
+
+	if tokenPath != "" {
+		b, err := ioutil.ReadFile(tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read registry token %v: %v", tokenPath, err)
+		}
+		c.token = strings.TrimSpace(string(b))
+	}
+
+	return c, nil
+}
+
+// Tags lists the tags of an image repository.
+func (c *Client) Tags(ref Reference) ([]string, error) {
+	resp, err := c.do(http.MethodGet, ref, "tags/list", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("unable to decode tags of %v: %v", ref.Name(), err)
+	}
+
+	return tags.Tags, nil
+}
+
+// Digest returns the manifest digest of an image tag.
+func (c *Client) Digest(ref Reference, tag string) (string, error) {
+	resp, err := c.do(http.MethodHead, ref, "manifests/"+tag, manifestTypes)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("no digest returned for %v:%v", ref.Name(), tag)
+	}
+
+	return digest, nil
+}
+
+// do sends a request to the registry API of an image repository, answering any authentication challenge.
+func (c *Client) do(method string, ref Reference, path, accept string) (*http.Response, error) {
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", c.scheme, ref.Registry, ref.Repository, path)
+
+	send := func(auth string) (*http.Response, error) {
+		req, err := http.NewRequest(method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return c.client.Do(req)
+	}
+
+	resp, err := send("")
+	if err != nil {
+		return nil, fmt.Errorf("unable to request %v: %v", u, err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		auth, err := c.authorize(challenge, ref)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = send(auth); err != nil {
+			return nil, fmt.Errorf("unable to request %v: %v", u, err)
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unable to request %v: %v", u, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// authorize returns the Authorization header answering a WWW-Authenticate challenge.
+func (c *Client) authorize(challenge string, ref Reference) (string, error) {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+
+	switch scheme {
+	case "basic":
+		if c.token == "" {
+			return "", fmt.Errorf("registry %v requires credentials", ref.Registry)
+		}
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(tokenUsername, c.token)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		params := map[string]string{}
+		for _, m := range challengeParamRe.FindAllStringSubmatch(challenge, -1) {
+			params[strings.ToLower(m[1])] = m[2]
+		}
+
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("invalid authentication challenge from %v: %q", ref.Registry, challenge)
+		}
+
+		scope := params["scope"]
+		if scope == "" {
+			scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+		}
+
+		q := realm.Query()
+		if params["service"] != "" {
+			q.Set("service", params["service"])
+		}
+		q.Set("scope", scope)
+		realm.RawQuery = q.Encode()
+
+		token, err := c.fetchToken(realm.String())
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge from %v: %q", ref.Registry, challenge)
+	}
+}
+
+// fetchToken requests a bearer token from an authorization service.
+func (c *Client) fetchToken(realm string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, realm, nil)
+	if err != nil {
+		return "", err
+	}
+	if c.token != "" {
+		req.SetBasicAuth(tokenUsername, c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to request token from %v: %v", realm, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to request token from %v: %v", realm, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unable to decode token from %v: %v", realm, err)
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Reference
+	}{
+		{
+			name:     "tag",
+			input:    "gcr.io/istio-testing/build-tools:master-2019-11-14T12-01-13",
+			expected: Reference{Registry: "gcr.io", Repository: "istio-testing/build-tools", Tag: "master-2019-11-14T12-01-13"},
+		},
+		{
+			name:     "digest",
+			input:    "gcr.io/istio-testing/build-tools@sha256:abc",
+			expected: Reference{Registry: "gcr.io", Repository: "istio-testing/build-tools", Digest: "sha256:abc"},
+		},
+		{
+			name:     "tag and digest",
+			input:    "gcr.io/istio-testing/build-tools:master@sha256:abc",
+			expected: Reference{Registry: "gcr.io", Repository: "istio-testing/build-tools", Tag: "master", Digest: "sha256:abc"},
+		},
+		{
+			name:     "registry with port",
+			input:    "localhost:5000/build-tools",
+			expected: Reference{Registry: "localhost:5000", Repository: "build-tools"},
+		},
+		{
+			name:     "docker hub",
+			input:    "ubuntu:18.04",
+			expected: Reference{Registry: defaultRegistry, Repository: "library/ubuntu", Tag: "18.04"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ParseReference(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Error("TestParseReference (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:istio-private/build-tools:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token":"secret"}`)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/istio-private/build-tools/tags/list":
+			fmt.Fprint(w, `{"name":"istio-private/build-tools","tags":["master-2020-01-01T00-00-00","master-2020-02-01T00-00-00"]}`)
+		case r.URL.Path == "/v2/istio-private/build-tools/manifests/master-2020-02-01T00-00-00" && r.Method == http.MethodHead:
+			w.Header().Set("Docker-Content-Digest", "sha256:def")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.scheme = "http"
+
+	ref, err := ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/istio-private/build-tools:master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := c.Tags(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"master-2020-01-01T00-00-00", "master-2020-02-01T00-00-00"}, tags); diff != "" {
+		t.Error("TestClient tags (-want, +got):", diff)
+	}
+
+	digest, err := c.Digest(ref, "master-2020-02-01T00-00-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "sha256:def" {
+		t.Errorf("TestClient digest: expected %q, got %q", "sha256:def", digest)
+	}
+
+	if _, err := c.Digest(ref, "missing"); err == nil {
+		t.Error("TestClient digest: expected error for missing tag")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldDiff is a difference of a single field between two objects.
+type FieldDiff struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// DiffFields compares two objects field by field and returns the differences sorted by field path.
+// Objects are compared using their JSON representation and field paths use dot and index notation (e.g. spec.containers[0].image).
+func DiffFields(before, after interface{}) ([]FieldDiff, error) {
+	b, err := flattenObject(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := flattenObject(after)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]bool{}
+	for p := range b {
+		paths[p] = true
+	}
+	for p := range a {
+		paths[p] = true
+	}
+
+	var diffs []FieldDiff
+	for p := range paths {
+		if !reflect.DeepEqual(b[p], a[p]) {
+			diffs = append(diffs, FieldDiff{Path: p, Before: b[p], After: a[p]})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+
+	return diffs, nil
+}
+
+// flattenObject flattens the JSON representation of an object into leaf values keyed by field path.
+func flattenObject(obj interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+
+	flat := map[string]interface{}{}
+	flatten("", generic, flat)
+
+	return flat, nil
+}
+
+// flatten recursively adds the leaf values of a generic JSON value to flat.
+func flatten(prefix string, v interface{}, flat map[string]interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 && prefix != "" {
+			flat[prefix] = t
+		}
+		for k, child := range t {
+			flatten(strings.TrimPrefix(prefix+"."+k, "."), child, flat)
+		}
+	case []interface{}:
+		if len(t) == 0 && prefix != "" {
+			flat[prefix] = t
+		}
+		for i, child := range t {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, flat)
+		}
+	default:
+		flat[prefix] = t
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   interface{}
+		after    interface{}
+		expected []FieldDiff
+	}{
+		{
+			name:     "identical",
+			before:   map[string]interface{}{"name": "job"},
+			after:    map[string]interface{}{"name": "job"},
+			expected: nil,
+		},
+		{
+			name:   "changed, added, and removed fields",
+			before: map[string]interface{}{"name": "job", "cluster": "default"},
+			after:  map[string]interface{}{"name": "job_private", "labels": map[string]string{"a": "b"}},
+			expected: []FieldDiff{
+				{Path: "cluster", Before: "default"},
+				{Path: "labels.a", After: "b"},
+				{Path: "name", Before: "job", After: "job_private"},
+			},
+		},
+		{
+			name:   "nested lists",
+			before: map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]string{"image": "a"}}}},
+			after:  map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]string{"image": "b"}}}},
+			expected: []FieldDiff{
+				{Path: "spec.containers[0].image", Before: "a", After: "b"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := DiffFields(test.before, test.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Error("TestDiffFields (-want, +got):", diff)
+			}
+		})
+	}
+}
@@ -19,18 +19,26 @@ package util
 import (
 	"os"
 	"path/filepath"
-	"regexp"
+	"strings"
 )
 
 // RenameFile renames a file based on a specified regular expression pattern.
 func RenameFile(pat string, src string, repl string) string {
-	s := regexp.MustCompile(pat).ReplaceAllString(src, repl)
-	return regexp.MustCompile(`^[^\w\d]`).ReplaceAllString(s, "")
+	s := MustCompile(pat).ReplaceAllString(src, repl)
+	return MustCompile(`^[^\w\d]`).ReplaceAllString(s, "")
 }
 
 // HasExtension checks if a file's extension matches a pattern.
 func HasExtension(path string, pat string) bool {
-	return regexp.MustCompile(pat).MatchString(filepath.Ext(path))
+	return MustCompile(pat).MatchString(filepath.Ext(path))
+}
+
+// SplitPath splits a path into its non-empty segments.
+// Both the OS-specific path separator and the forward slash are treated as separators.
+func SplitPath(path string) []string {
+	return strings.FieldsFunc(path, func(c rune) bool {
+		return c == filepath.Separator || c == '/'
+	})
 }
 
 // Exists checks if a path exists.
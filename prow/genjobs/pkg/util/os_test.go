@@ -0,0 +1,63 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "relative path",
+			input:    filepath.Join("istio", "istio", "istio.istio.master.yaml"),
+			expected: []string{"istio", "istio", "istio.istio.master.yaml"},
+		},
+		{
+			name:     "forward slashes",
+			input:    "istio/istio/istio.istio.master.yaml",
+			expected: []string{"istio", "istio", "istio.istio.master.yaml"},
+		},
+		{
+			name:     "leading and repeated separators",
+			input:    string(filepath.Separator) + "istio" + string(filepath.Separator) + string(filepath.Separator) + "file.yaml",
+			expected: []string{"istio", "file.yaml"},
+		},
+		{
+			name:     "empty path",
+			input:    "",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := SplitPath(test.input)
+
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Error("TestSplitPath (-want, +got):", diff)
+			}
+		})
+	}
+}
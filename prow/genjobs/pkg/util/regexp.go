@@ -0,0 +1,70 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// regexpCache holds compiled regular expressions keyed by pattern.
+var regexpCache sync.Map
+
+// MustCompile compiles a regular expression pattern, reusing a previously compiled expression when available.
+// It is safe for concurrent use.
+func MustCompile(pat string) *regexp.Regexp {
+	if re, ok := regexpCache.Load(pat); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	re, _ := regexpCache.LoadOrStore(pat, regexp.MustCompile(pat))
+
+	return re.(*regexp.Regexp)
+}
+
+// GlobPattern converts a path glob into an anchored regular expression pattern. A * or ? does not match a path
+// separator, while ** matches any number of path segments (e.g. **/experimental/** matches a/experimental/b.yaml).
+func GlobPattern(glob string) string {
+	var b strings.Builder
+
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return b.String()
+}
+
+// IsGlob checks if a path contains glob wildcards.
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?")
+}
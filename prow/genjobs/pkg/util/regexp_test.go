@@ -0,0 +1,57 @@
+/*
+Copyright 2019 Istio Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+)
+
+func TestMustCompile(t *testing.T) {
+	first := MustCompile(`^release-\d+\.\d+$`)
+	second := MustCompile(`^release-\d+\.\d+$`)
+
+	if first != second {
+		t.Error("TestMustCompile: expected cached regular expression to be reused")
+	}
+
+	if !first.MatchString("release-1.6") {
+		t.Error("TestMustCompile: expected compiled regular expression to match")
+	}
+}
+
+func TestGlobPattern(t *testing.T) {
+	tests := []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{glob: "**/experimental/**", path: "istio/experimental/istio.yaml", matches: true},
+		{glob: "**/experimental/**", path: "experimental/istio.yaml", matches: true},
+		{glob: "**/experimental/**", path: "istio/istio/istio.yaml", matches: false},
+		{glob: "jobs/*/istio.yaml", path: "jobs/istio/istio.yaml", matches: true},
+		{glob: "jobs/*/istio.yaml", path: "jobs/istio/istio/istio.yaml", matches: false},
+		{glob: "jobs/**.yaml", path: "jobs/istio/istio/istio.yaml", matches: true},
+		{glob: "istio.?.yaml", path: "istio.1.yaml", matches: true},
+		{glob: "istio.?.yaml", path: "istio-1.yaml", matches: false},
+	}
+
+	for _, test := range tests {
+		if actual := MustCompile(GlobPattern(test.glob)).MatchString(test.path); actual != test.matches {
+			t.Errorf("TestGlobPattern: %v matching %v: expected %v, got %v", test.glob, test.path, test.matches, actual)
+		}
+	}
+}
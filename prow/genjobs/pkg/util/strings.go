@@ -18,14 +18,13 @@ package util
 
 import (
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
 )
 
 // GetTopLevelOrg escapes and returns the top-level org from an org string.
 func GetTopLevelOrg(s string) string {
-	m := regexp.MustCompile(`^http(?:s)://(.+?)(?:/(.+))?$`).FindStringSubmatch(s)
+	m := MustCompile(`^http(?:s)://(.+?)(?:/(.+))?$`).FindStringSubmatch(s)
 
 	if len(m) == 2 {
 		return strings.Replace(m[1], "/", "-", -1)
@@ -39,14 +38,14 @@ func GetTopLevelOrg(s string) string {
 
 // SplitOrgRepo splits and org/repo string into into two separate strings.
 func SplitOrgRepo(s string) (string, string) {
-	m := regexp.MustCompile(`^((?:http(?:s)://)?.+)/(.+)$`).FindStringSubmatch(s)
+	m := MustCompile(`^((?:http(?:s)://)?.+)/(.+)$`).FindStringSubmatch(s)
 
 	return m[1], m[2]
 }
 
 // RemoveHost removes a host prefix from a string.
 func RemoveHost(s string) string {
-	return regexp.MustCompile("^https?://.*?(?:/(.*)|$)").ReplaceAllString(s, "$1")
+	return MustCompile("^https?://.*?(?:/(.*)|$)").ReplaceAllString(s, "$1")
 }
 
 // NormalizeOrg removes a host prefix from a string.